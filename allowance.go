@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// untested sections all files matching a glob may have together, from --allowance=internal/legacy/**=40 or the
+// allowance list of the config, so legacy trees do not need a budget comment in every file
+type pathAllowance struct {
+	glob  string
+	count int
+}
+
+// "internal/legacy/**=40" or "internal/legacy/**=40 untested sections" like the config reads => allowance
+func parsePathAllowance(value string) (allowance pathAllowance, err error) {
+	separator := strings.LastIndex(value, "=")
+	if separator <= 0 {
+		return allowance, fmt.Errorf("invalid allowance %v, expected glob=count", value)
+	}
+	count, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value[separator+1:]), " untested sections"))
+	if err != nil || count < 0 {
+		return allowance, fmt.Errorf("invalid allowance %v, expected glob=count", value)
+	}
+	return pathAllowance{glob: strings.TrimSpace(value[:separator]), count: count}, nil
+}
+
+// budget of the first allowance matching the path, pooled by its glob like a package budget is pooled by directory
+func findPathAllowance(path string, allowances []pathAllowance) (budget packageBudget, pool string) {
+	for _, allowance := range allowances {
+		if matchesPathGlob(path, allowance.glob) {
+			return packageBudget{count: allowance.count, glob: allowance.glob, found: true}, "allowance:" + allowance.glob
+		}
+	}
+	return
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// azure pipelines logging commands, so findings show up as build issues and the task result follows the check
+// https://learn.microsoft.com/en-us/azure/devops/pipelines/scripts/logging-commands
+func formatAzure(result runResult) string {
+	content := ""
+	warnings := false
+	for _, finding := range result.findings {
+		properties := "type=" + finding.Severity + ";"
+		if finding.Path != "" {
+			properties += fmt.Sprintf("sourcepath=%v;linenumber=%v;", escapeAzureProperty(finding.Path), finding.Line)
+			if finding.Column != 0 {
+				properties += fmt.Sprintf("columnnumber=%v;", finding.Column)
+			}
+		}
+		properties += "code=" + finding.Code + ";"
+		content += fmt.Sprintf("##vso[task.logissue %v]%v\n", properties, escapeAzureData(finding.Message))
+		warnings = warnings || finding.Severity == "warning"
+	}
+
+	status := "Succeeded"
+	if result.exitCode != 0 {
+		status = "Failed"
+	} else if warnings {
+		status = "SucceededWithIssues"
+	}
+	return content + fmt.Sprintf("##vso[task.complete result=%v;]%v\n", status, escapeAzureData(summaryLine(result, result.precision)))
+}
+
+func escapeAzureData(data string) string {
+	return strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A").Replace(data)
+}
+
+func escapeAzureProperty(property string) string {
+	return strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A", "]", "%5D", ";", "%3B").Replace(property)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// markers around the region of the readme that `badge --update-readme` rewrites
+var badgeStartMarker = "<!-- go-testcov badge -->"
+var badgeEndMarker = "<!-- /go-testcov badge -->"
+
+// readme names in order of preference
+var readmeNames = []string{"README.md", "Readme.md", "readme.md"}
+
+// run the tests and print a coverage badge, or write it into the readme so a scheduled ci job can commit it,
+// --per-package=DIR also writes a badge of each package for readme tables of monorepos
+func runBadge(argv []string) (exitCode int) {
+	updateReadme := containsString(argv, "--update-readme")
+	argv = removeString(argv, "--update-readme")
+	perPackage := ""
+	for _, arg := range argv {
+		if strings.HasPrefix(arg, "--per-package=") {
+			perPackage = strings.TrimPrefix(arg, "--per-package=")
+			argv = removeString(argv, arg)
+			break
+		}
+	}
+	opts, argv, err := parseOptions(argv)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer useOptions(opts)()
+	if updateReadme && opts.readOnly {
+		_, _ = fmt.Fprintln(os.Stderr, "--update-readme writes the readme, which --read-only forbids")
+		return 2
+	}
+	if perPackage != "" && opts.readOnly {
+		_, _ = fmt.Fprintln(os.Stderr, "--per-package writes badges, which --read-only forbids")
+		return 2
+	}
+
+	coverageFile, err := ioutil.TempFile("", "go-testcov-coverage")
+	check(err)
+	coveragePath := coverageFile.Name()
+	check(coverageFile.Close())
+	defer os.Remove(coveragePath)
+
+	argv = append(append([]string{"test"}, argv...), "-coverprofile", coveragePath)
+	if exitCode = runCommand("go", argv...); exitCode != 0 {
+		return exitCode
+	}
+	badge := coverageBadge(coveragePercent(statementCoverage(coveragePath, opts)), opts.precision)
+
+	if perPackage != "" {
+		writePackageBadges(perPackage, coveragePath, opts)
+	}
+	if updateReadme {
+		return updateReadmeBadge(badge)
+	}
+	if perPackage == "" {
+		fmt.Println(badge)
+	}
+	return 0
+}
+
+// write NAME.svg and NAME.json (a shields.io endpoint) for each package into the directory,
+// NAME is the package directory in the module with "-" instead of "/", or "root" for the package at the module root
+func writePackageBadges(directory string, coveragePath string, opts options) {
+	packages := map[string]fileCoverage{}
+	for file, coverage := range statementCoverageByPath(coveragePath, opts) {
+		name := packageBadgeName(path.Dir(file))
+		total := packages[name]
+		total.statements += coverage.statements
+		total.covered += coverage.covered
+		packages[name] = total
+	}
+
+	check(os.MkdirAll(directory, 0755))
+	for name, coverage := range packages {
+		result := runResult{coverage: coveragePercent(coverage.statements, coverage.covered), precision: opts.precision}
+		check(ioutil.WriteFile(filepath.Join(directory, name+".svg"), []byte(formatSvgBadge(result)), 0644))
+		check(ioutil.WriteFile(filepath.Join(directory, name+".json"), []byte(formatShields(result)), 0644))
+	}
+}
+
+// "github.com/user/lib/pkg/api" => "pkg-api" inside of the github.com/user/lib module
+func packageBadgeName(importPath string) string {
+	wd, err := os.Getwd()
+	check(err)
+	if module := findGoModule(wd); module != nil {
+		if importPath == module.path {
+			importPath = "."
+		}
+		importPath = strings.TrimPrefix(importPath, module.path+"/")
+	}
+	if importPath == "." {
+		return "root"
+	}
+	return strings.ReplaceAll(importPath, "/", "-")
+}
+
+// markdown image of a shields.io badge, colored like the common coverage badges
+func coverageBadge(percent float64, precision int) string {
+	return fmt.Sprintf("![coverage](https://img.shields.io/badge/coverage-%v-%v)", url.PathEscape(formatPercent(percent, precision)), badgeColor(percent))
+}
+
+func badgeColor(percent float64) string {
+	switch {
+	case percent >= 90:
+		return "brightgreen"
+	case percent >= 75:
+		return "green"
+	case percent >= 60:
+		return "yellow"
+	case percent >= 40:
+		return "orange"
+	}
+	return "red"
+}
+
+// replace everything between the badge markers in the readme
+func updateReadmeBadge(badge string) (exitCode int) {
+	path := ""
+	for _, name := range readmeNames {
+		if _, err := os.Stat(name); err == nil {
+			path = name
+			break
+		}
+	}
+	if path == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "Could not find a readme, looked for %v\n", strings.Join(readmeNames, ", "))
+		return 2
+	}
+
+	content := readFile(path)
+	start := strings.Index(content, badgeStartMarker)
+	end := strings.Index(content, badgeEndMarker)
+	if start == -1 || end < start {
+		_, _ = fmt.Fprintf(os.Stderr, "Could not find %v ... %v in %v, add them where the badge should go\n", badgeStartMarker, badgeEndMarker, path)
+		return 2
+	}
+
+	updated := content[:start+len(badgeStartMarker)] + badge + content[end:]
+	if updated != content {
+		check(ioutil.WriteFile(path, []byte(updated), 0644))
+	}
+	return 0
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// how many ancestors of the diff revision are tried when the baseline has no coverage for the revision itself,
+// busy repos merge faster than CI uploads coverage
+var baselineAncestorLimit = 50
+
+// find the baseline coverage file, which can be a path or an http, s3 or gs url with {branch} and {sha} placeholders
+// like https://artifacts.example.com/coverage/{branch}/{sha}.out
+// placeholders are filled with the --diff revision and its ancestors, the first one that has coverage is used
+// returns where the coverage was stored locally and a cleanup function, or found=false when no ancestor had coverage
+// and an error when the store could not be read, like missing credentials
+func resolveBaseline(pattern string, revision string, vcs versionControl) (path string, cleanup func(), found bool, err error) {
+	cleanup = func() {}
+	if !strings.Contains(pattern, "{sha}") && !strings.Contains(pattern, "{branch}") && findBaselineStore(pattern) == nil {
+		return pattern, cleanup, true, nil
+	}
+
+	candidates := []string{revision}
+	if strings.Contains(pattern, "{sha}") {
+		candidates = vcs.ancestors(revision, baselineAncestorLimit)
+	}
+
+	for _, sha := range candidates {
+		location := strings.NewReplacer("{branch}", revision, "{sha}", sha).Replace(pattern)
+		store := findBaselineStore(location)
+		if store == nil {
+			if _, err := os.Stat(location); err == nil {
+				return location, cleanup, true, nil
+			}
+			continue
+		}
+
+		// coverage of a sha never changes, so it is kept in the cache to not download it again
+		if strings.Contains(pattern, "{sha}") {
+			path = cacheFile("baselines", location)
+			if _, err := os.Stat(path); err == nil {
+				return path, cleanup, true, nil
+			}
+			if found, err = downloadBaseline(store, location, path); found || err != nil {
+				return path, cleanup, found, err
+			}
+			continue
+		}
+
+		file, err := ioutil.TempFile("", "go-testcov-baseline")
+		check(err)
+		check(file.Close())
+		if found, err = downloadBaseline(store, location, file.Name()); found {
+			return file.Name(), func() { _ = os.Remove(file.Name()) }, true, nil
+		}
+		_ = os.Remove(file.Name())
+		if err != nil {
+			return "", cleanup, false, err
+		}
+	}
+	return "", cleanup, false, nil
+}
+
+// upload a coverage file to a location with publish-baseline, so later runs with --baseline can compare against it
+func publishBaseline(path string, location string) error {
+	store := findBaselineStore(location)
+	if store == nil {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(filepath.Dir(location), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(location, content, 0644)
+	}
+	return store.upload(path, location)
+}
+
+// `go-testcov baseline publish coverage.out s3://bucket/coverage/$SHA.out` uploads the coverage of a run,
+// so ci runners share the baseline of each commit without committing it
+func runBaseline(argv []string) (exitCode int) {
+	if len(argv) != 3 || argv[0] != "publish" {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: go-testcov baseline publish COVERAGE_FILE LOCATION")
+		return 2
+	}
+	if err := publishBaseline(argv[1], argv[2]); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "published %v to %v\n", argv[1], argv[2])
+	return 0
+}
+
+// where baselines are downloaded from and published to, so teams can share coverage through the artifact store they already have
+type baselineStore interface {
+	// download the coverage at the location to the path, found is false when there is no coverage at the location
+	download(location string, path string) (found bool, err error)
+
+	// upload the coverage file at the path to the location
+	upload(path string, location string) error
+}
+
+// stores by the url prefix of the locations they handle, locations without a store are local files
+var baselineStores = map[string]baselineStore{
+	"http://":  httpStore{},
+	"https://": httpStore{},
+	"s3://":    commandStore{name: "aws", args: []string{"s3", "cp"}, missing: []string{"(404)", "NoSuchKey"}},
+	"gs://":    commandStore{name: "gsutil", args: []string{"cp"}, missing: []string{"No URLs matched"}},
+}
+
+func findBaselineStore(location string) baselineStore {
+	for prefix, store := range baselineStores {
+		if strings.HasPrefix(location, prefix) {
+			return store
+		}
+	}
+	return nil
+}
+
+// download coverage to the path, missing coverage is expected since not every commit has it
+// downloads go next to the path and are then moved, so interrupted downloads do not leave partial coverage behind
+func downloadBaseline(store baselineStore, location string, path string) (found bool, err error) {
+	check(os.MkdirAll(filepath.Dir(path), 0700))
+	file, err := ioutil.TempFile(filepath.Dir(path), "download")
+	check(err)
+	check(file.Close())
+	if found, err = store.download(location, file.Name()); !found || err != nil {
+		_ = os.Remove(file.Name())
+		return false, err
+	}
+	check(os.Rename(file.Name(), path))
+	return true, nil
+}
+
+// how long downloading or uploading a baseline over http may take, so a hanging artifact store fails the build instead of blocking it
+var baselineDownloadTimeout = 2 * time.Minute
+
+// downloads coverage from artifact stores that serve it over http and uploads it with PUT
+type httpStore struct{}
+
+func (httpStore) download(url string, path string) (found bool, err error) {
+	client := http.Client{Timeout: baselineDownloadTimeout}
+	response, err := client.Get(url)
+	if err != nil {
+		return false, fmt.Errorf("downloading baseline %v failed: %v", url, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return false, fmt.Errorf("downloading baseline %v failed with status %v", url, response.Status)
+	}
+
+	file, err := os.Create(path)
+	check(err)
+	defer file.Close()
+	if _, err = io.Copy(file, response.Body); err != nil {
+		return false, fmt.Errorf("downloading baseline %v failed: %v", url, err)
+	}
+	return true, nil
+}
+
+func (httpStore) upload(path string, url string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	request, err := http.NewRequest(http.MethodPut, url, file)
+	check(err)
+	client := http.Client{Timeout: baselineDownloadTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("uploading baseline %v failed: %v", url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("uploading baseline %v failed with status %v", url, response.Status)
+	}
+	return nil
+}
+
+// copies coverage with the cli of a cloud storage, like `aws s3 cp` or `gsutil cp`, so their credentials just work
+type commandStore struct {
+	name    string
+	args    []string // followed by the source and the destination
+	missing []string // output that means the location does not exist
+}
+
+func (s commandStore) download(location string, path string) (found bool, err error) {
+	exitCode, output := runCommandCapturingOutput(s.name, append(append([]string{}, s.args...), location, path)...)
+	if exitCode == 0 {
+		return true, nil
+	}
+	for _, missing := range s.missing {
+		if strings.Contains(output, missing) {
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("downloading baseline %v with %v failed with exit code %v:\n%v", location, s.name, exitCode, output)
+}
+
+func (s commandStore) upload(path string, location string) error {
+	exitCode, output := runCommandCapturingOutput(s.name, append(append([]string{}, s.args...), path, location)...)
+	if exitCode != 0 {
+		return fmt.Errorf("uploading baseline %v with %v failed with exit code %v:\n%v", location, s.name, exitCode, output)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// run go test and check coverage in each of the given directories, like the modules of a monorepo, with the
+// .go-testcov.yml of each directory, one after the other so their output does not interleave, or with --parallel
+// at the same time with each line of output prefixed by its directory
+// returns the worst exit code and writes one report with the findings and coverage of all directories
+func runBatch(argv []string) (exitCode int) {
+	parallel := len(argv) > 0 && argv[0] == "--parallel"
+	if parallel {
+		argv = argv[1:]
+	}
+	directories := []string{}
+	for len(argv) > 0 && !strings.HasPrefix(argv[0], "-") && isDirectory(argv[0]) {
+		directories = append(directories, argv[0])
+		argv = argv[1:]
+	}
+	if len(directories) == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: go-testcov run [--parallel] DIRECTORY... [go test arguments]")
+		return 2
+	}
+
+	// reports and verbosity come from where go-testcov runs, everything else from each directory
+	opts, _, err := parseOptions(argv)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	reports, err := ioutil.TempDir("", "go-testcov-batch")
+	check(err)
+	defer os.RemoveAll(reports)
+	lines := needsLines(opts.reports)
+
+	// both modes check each directory with the same arguments and read its result back from the reports it wrote,
+	// so they produce the same result
+	var exitCodes []int
+	if parallel {
+		exitCodes = runDirectoriesConcurrently(directories, argv, reports, lines)
+	} else {
+		for i, directory := range directories {
+			_, _ = fmt.Fprintf(os.Stderr, "go-testcov in %v\n", directory)
+			exitCodes = append(exitCodes, runDirectory(directory, directoryArguments(argv, reports, i, lines)))
+		}
+	}
+	results := []runResult{}
+	for i, exitCode := range exitCodes {
+		results = append(results, readDirectoryResult(exitCode, reports, i, lines))
+	}
+
+	summaries := []string{}
+	all := runResult{findings: []finding{}, files: []fileResult{}, skipped: []skippedFile{}, phases: timings{}, lines: map[string]map[int]int{}, precision: opts.precision}
+	coverageKnown := true
+	for i, result := range results {
+		directory := directories[i]
+		for _, phase := range result.phases {
+			all.phases = append(all.phases, timing{Phase: directory + " " + phase.Phase, Seconds: phase.Seconds})
+		}
+
+		for _, finding := range result.findings {
+			if !filepath.IsAbs(finding.Path) && finding.Path != "" {
+				finding.Path = filepath.Join(directory, finding.Path)
+			}
+			all.findings = append(all.findings, finding)
+		}
+		for _, file := range result.files {
+			if !filepath.IsAbs(file.Path) {
+				file.Path = filepath.Join(directory, file.Path)
+			}
+			all.files = append(all.files, file)
+		}
+		for _, file := range result.skipped {
+			if !filepath.IsAbs(file.Path) {
+				file.Path = filepath.Join(directory, file.Path)
+			}
+			all.skipped = append(all.skipped, file)
+		}
+		for path, lines := range result.lines {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(directory, path)
+			}
+			all.lines[path] = lines
+		}
+		all.statements += result.statements
+		all.covered += result.covered
+		if result.coverage < 0 {
+			coverageKnown = false
+		}
+		if result.exitCode == 0 {
+			summaries = append(summaries, directory+" ok")
+		} else {
+			summaries = append(summaries, fmt.Sprintf("%v failed with exit code %v", directory, result.exitCode))
+		}
+		if result.exitCode > exitCode {
+			exitCode = result.exitCode
+		}
+	}
+
+	_, _ = fmt.Fprintln(os.Stderr, strings.Join(summaries, "\n"))
+	if opts.verbose {
+		printTimings(all.phases)
+	}
+	all.exitCode = exitCode
+	all.coverage = -1 // total coverage is unknown when go test failed in any directory
+	if coverageKnown {
+		all.coverage = coveragePercent(all.statements, all.covered)
+	}
+	writeReports(all, opts)
+	return exitCode
+}
+
+// check a directory with its own config, write its reports and print its summary line
+func runDirectory(directory string, argv []string) (exitCode int) {
+	inDirectory(directory, func() {
+		opts, goTestArgs, err := parseOptions(argv)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			exitCode = 2
+			return
+		}
+		defer useOptions(opts)()
+		result := goTestAndCheckCoverage(goTestArgs, opts)
+		writeReports(result, opts)
+		_, _ = fmt.Fprintln(os.Stderr, summaryLine(result, opts.precision))
+		exitCode = result.exitCode
+	})
+	return exitCode
+}
+
+// path of the running go-testcov, to check directories in processes of their own
+var selfExecutable = func() string {
+	path, err := os.Executable()
+	check(err)
+	return path
+}
+
+// run go-testcov in each directory at the same time with each line of output prefixed by its directory
+func runDirectoriesConcurrently(directories []string, argv []string, reports string, lines bool) []int {
+	commands := []command{}
+	for i, directory := range directories {
+		commands = append(commands, command{
+			prefix: directory + ": ", directory: directory, name: selfExecutable(),
+			args: directoryArguments(argv, reports, i, lines),
+		})
+	}
+	return runCommandsConcurrently(commands)
+}
+
+// arguments to check the directory with the given index: reports of the whole batch are written by the batch,
+// each directory writes what the batch needs into the reports directory instead, and the reports of its own config
+func directoryArguments(argv []string, reports string, index int, lines bool) []string {
+	args := []string{"--format=json:" + filepath.Join(reports, fmt.Sprintf("%v.json", index))}
+	if lines {
+		args = append(args, "--format=lcov:"+filepath.Join(reports, fmt.Sprintf("%v.lcov", index)))
+	}
+	for _, arg := range argv {
+		if option, value, found := findOption(arg); found {
+			var probe options
+			if option.apply(&probe, value) == nil && len(probe.reports) > 0 {
+				continue
+			}
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+// result of the directory with the given index from the reports it wrote, nothing is known when it wrote none
+func readDirectoryResult(exitCode int, reports string, index int, lines bool) runResult {
+	result := runResult{exitCode: exitCode, coverage: -1}
+	content, err := ioutil.ReadFile(filepath.Join(reports, fmt.Sprintf("%v.json", index)))
+	if err != nil {
+		return result
+	}
+	var report ideReport
+	check(json.Unmarshal(content, &report))
+	result.findings, result.files, result.skipped, result.phases = report.Findings, report.Files, report.Skipped, report.Timings
+	result.coverage, result.statements, result.covered = report.Coverage, report.Statements, report.CoveredStatements
+	if lines {
+		content, err = ioutil.ReadFile(filepath.Join(reports, fmt.Sprintf("%v.lcov", index)))
+		check(err)
+		result.lines = parseLcov(string(content))
+	}
+	return result
+}
+
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// run a function in a directory and go back to the current directory afterwards
+func inDirectory(directory string, fn func()) {
+	wd, err := os.Getwd()
+	check(err)
+	check(os.Chdir(directory))
+	defer func() { check(os.Chdir(wd)) }()
+	fn()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// budget for the untested sections of one function, configured in a --budgets file,
+// so exemptions do not need to be renumbered when other code of the file moves
+type functionBudget struct {
+	path     string // source file as go-testcov shows it, like "pkg/x/y.go"
+	function string // "Shutdown", "Server.Shutdown" or "(*Server).Shutdown"
+	count    int
+	file     string // budgets file and line the budget is configured on
+	line     int
+}
+
+// parse a budgets file with one "path#function: count" per line, for example:
+//
+//	# comments and empty lines are ignored
+//	pkg/x/y.go#(*Server).Shutdown: 2
+//	pkg/x/y.go#parse: 1
+func parseFunctionBudgets(path string) (budgets []functionBudget, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for index, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		invalid := fmt.Errorf("%v:%v: expected \"path#function: count\" but got %q", path, index+1, line)
+		colon := strings.LastIndex(line, ":")
+		hash := strings.Index(line, "#")
+		if colon == -1 || hash == -1 || hash > colon {
+			return nil, invalid
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(line[colon+1:]))
+		if err != nil || count < 0 {
+			return nil, invalid
+		}
+		budgets = append(budgets, functionBudget{
+			path:     filepath.Clean(strings.TrimSpace(line[:hash])),
+			function: strings.TrimSpace(line[hash+1 : colon]),
+			count:    count,
+			file:     path,
+			line:     index + 1,
+		})
+	}
+	return budgets, nil
+}
+
+// remove the sections of functions that are within their budget, functions above their budget keep all their sections
+// so they count against the file, stale are the budgets that are larger than needed with their current untested sections
+// budgets of functions that no longer exist are stale too, since they were probably renamed
+func applyFunctionBudgets(sections []Section, source sourceFile, displayPath string, budgets []functionBudget) (remaining []Section, stale map[functionBudget]int) {
+	stale = map[functionBudget]int{}
+	budgeted := map[Section]bool{}
+	for _, budget := range budgets {
+		if budget.path != filepath.Clean(displayPath) {
+			continue
+		}
+		inFunction := []Section{}
+		if function, ok := source.functionNames[budget.function]; ok {
+			for _, section := range sections {
+				if function[0] <= section.Line && section.EndLine <= function[1] {
+					inFunction = append(inFunction, section)
+				}
+			}
+		}
+		if len(inFunction) > budget.count {
+			continue
+		}
+		if len(inFunction) < budget.count {
+			stale[budget] = len(inFunction)
+		}
+		for _, section := range inFunction {
+			budgeted[section] = true
+		}
+	}
+
+	remaining = []Section{}
+	for _, section := range sections {
+		if !budgeted[section] {
+			remaining = append(remaining, section)
+		}
+	}
+	return remaining, stale
+}
+
+// budgets in the order they are configured, so output is stable
+func sortedFunctionBudgets(budgets map[functionBudget]int) (sorted []functionBudget) {
+	for budget := range budgets {
+		sorted = append(sorted, budget)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].line < sorted[j].line })
+	return
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// directory for state go-testcov keeps between runs like build caches and downloaded baselines,
+// ~/.cache/go-testcov or $XDG_CACHE_HOME/go-testcov on linux, so nothing is stored in repos
+func cacheDirectory(parts ...string) string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir() // untested section
+	}
+	return filepath.Join(append([]string{cacheDir, "go-testcov"}, parts...)...)
+}
+
+// path in the cache for something identified by a key like a url
+func cacheFile(kind string, key string) string {
+	return cacheDirectory(kind, fmt.Sprintf("%x", sha256.Sum256([]byte(key))))
+}
+
+// manage the cache directory
+func runCache(argv []string) (exitCode int) {
+	if len(argv) != 1 || (argv[0] != "clean" && argv[0] != "dir") {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: go-testcov cache clean|dir")
+		return 2
+	}
+	directory := cacheDirectory()
+	if argv[0] == "dir" {
+		fmt.Println(directory)
+		return 0
+	}
+
+	// go makes its build cache read-only, so make it writable again before removing it
+	_ = filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			_ = os.Chmod(path, 0700)
+		}
+		return nil
+	})
+	check(os.RemoveAll(directory))
+	_, _ = fmt.Fprintf(os.Stderr, "removed %v\n", directory)
+	return 0
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/xml"
+)
+
+// checkstyle xml report, which reviewdog, jenkins warnings and many editors already show inline
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr,omitempty"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// findings with a location grouped by file, in the order they were sorted in
+func formatCheckstyle(result runResult) string {
+	report := checkstyleReport{Version: "4.3", Files: []checkstyleFile{}}
+	for _, finding := range result.findings {
+		if finding.Path == "" {
+			continue // checkstyle only knows findings in files
+		}
+		if len(report.Files) == 0 || report.Files[len(report.Files)-1].Name != finding.Path {
+			report.Files = append(report.Files, checkstyleFile{Name: finding.Path})
+		}
+		file := &report.Files[len(report.Files)-1]
+		file.Errors = append(file.Errors, checkstyleError{
+			Line: finding.Line, Column: finding.Column, Severity: finding.Severity, Message: finding.Message, Source: "go-testcov." + finding.Code,
+		})
+	}
+	content, err := xml.MarshalIndent(report, "", "  ")
+	check(err)
+	return xml.Header + string(content) + "\n"
+}
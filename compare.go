@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// coverage of a ref, keyed by path relative to the current directory
+type refCoverage struct {
+	files    map[string]fileCoverage
+	untested map[string][]Section
+	code     map[Section]string // source of each untested section, to find it again when lines moved
+}
+
+// run the tests of two git refs in temporary worktrees and compare their coverage,
+// so users do not have to script worktree management to see what a branch changed
+func runCompareRefs(argv []string) (exitCode int) {
+	opts, argv, err := parseOptions(argv)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer useOptions(opts)()
+	if len(argv) < 2 {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: go-testcov compare-refs BASE HEAD [go test arguments]")
+		return 2
+	}
+	base, head, goTestArgs := argv[0], argv[1], argv[2:]
+
+	// run in the same sub-directory of the worktree as the user is in
+	prefix := strings.TrimSpace(commandOutput("git", "rev-parse", "--show-prefix"))
+
+	baseCoverage, exitCode := coverageOfRef(base, "base", prefix, goTestArgs, opts)
+	if exitCode != 0 {
+		return exitCode
+	}
+	headCoverage, exitCode := coverageOfRef(head, "head", prefix, goTestArgs, opts)
+	if exitCode != 0 {
+		return exitCode
+	}
+
+	printRefComparison(base, head, baseCoverage, headCoverage, opts.precision)
+	return 0
+}
+
+// check out the ref into a temporary worktree and run the tests there
+func coverageOfRef(ref string, slot string, prefix string, goTestArgs []string, opts options) (result refCoverage, exitCode int) {
+	tree, remove := addWorktree(ref, slot)
+	defer remove()
+
+	coverageFile, err := ioutil.TempFile("", "go-testcov-coverage")
+	check(err)
+	coveragePath := coverageFile.Name()
+	check(coverageFile.Close())
+	defer os.Remove(coveragePath)
+
+	directory := filepath.Join(tree.path, prefix)
+	argv := append(append([]string{"test"}, goTestArgs...), "-coverprofile", coveragePath)
+	if exitCode = runCommandIn(directory, tree.environment, "go", argv...); exitCode != 0 {
+		return
+	}
+
+	result = refCoverage{files: map[string]fileCoverage{}, untested: map[string][]Section{}, code: map[Section]string{}}
+	for path, file := range statementCoverageByPath(coveragePath, opts) {
+		result.files[findFileInDirectory(directory, path)] = file
+	}
+	for path, sections := range groupSectionsByPath(sectionsOf(coveragePath)) {
+		if skipReason(path, opts) != "" {
+			continue
+		}
+		displayPath := findFileInDirectory(directory, path)
+		source := parseSourceFile(displayPath, readFile(filepath.Join(directory, displayPath)))
+		sections = removeSectionsMarkedWithInlineComment(sections, source)
+		sortSections(sections)
+		result.untested[displayPath] = sections
+		for _, section := range sections {
+			result.code[section] = sectionCode(section, source)
+		}
+	}
+	return
+}
+
+// source of the lines of a section without indentation, so it can be found after it moved
+func sectionCode(section Section, source sourceFile) string {
+	code := []string{}
+	for lineNumber := section.Line; lineNumber <= section.EndLine && lineNumber <= len(source.lines); lineNumber++ {
+		code = append(code, strings.TrimSpace(source.lines[lineNumber-1]))
+	}
+	return strings.Join(code, "\n")
+}
+
+// print total and per file coverage changes and the untested sections head introduced
+func printRefComparison(base string, head string, baseCoverage refCoverage, headCoverage refCoverage, precision int) {
+	total := func(files map[string]fileCoverage) float64 {
+		statements, covered := 0, 0
+		for _, file := range files {
+			statements += file.statements
+			covered += file.covered
+		}
+		return coveragePercent(statements, covered)
+	}
+	before, after := total(baseCoverage.files), total(headCoverage.files)
+	_, _ = fmt.Fprintf(os.Stderr, "coverage %v -> %v: %v -> %v (%v)\n", base, head, formatPercent(before, precision), formatPercent(after, precision), formatPercentChange(after-before, precision))
+
+	paths := []string{}
+	for path := range baseCoverage.files {
+		paths = append(paths, path)
+	}
+	for path := range headCoverage.files {
+		if _, ok := baseCoverage.files[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		baseFile, inBase := baseCoverage.files[path]
+		headFile, inHead := headCoverage.files[path]
+		beforeFile := coveragePercent(baseFile.statements, baseFile.covered)
+		afterFile := coveragePercent(headFile.statements, headFile.covered)
+		switch {
+		case !inBase:
+			_, _ = fmt.Fprintf(os.Stderr, "%v new -> %v\n", path, formatPercent(afterFile, precision))
+		case !inHead:
+			_, _ = fmt.Fprintf(os.Stderr, "%v %v -> removed\n", path, formatPercent(beforeFile, precision))
+		case beforeFile != afterFile:
+			_, _ = fmt.Fprintf(os.Stderr, "%v %v -> %v (%v)\n", path, formatPercent(beforeFile, precision), formatPercent(afterFile, precision), formatPercentChange(afterFile-beforeFile, precision))
+		}
+	}
+
+	introduced := []string{}
+	untestedPaths := []string{}
+	for path := range headCoverage.untested {
+		untestedPaths = append(untestedPaths, path)
+	}
+	sort.Strings(untestedPaths)
+	for _, path := range untestedPaths {
+		// count the code of untested base sections, so duplicated code is matched once per section
+		known := map[string]int{}
+		for _, section := range baseCoverage.untested[path] {
+			known[baseCoverage.code[section]]++
+		}
+		for _, section := range headCoverage.untested[path] {
+			code := headCoverage.code[section]
+			if known[code] > 0 {
+				known[code]--
+			} else {
+				introduced = append(introduced, path+":"+section.Location())
+			}
+		}
+	}
+	if len(introduced) > 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "new untested sections in %v:\n%v\n", head, strings.Join(introduced, "\n"))
+	}
+}
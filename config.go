@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// config files looked for at the module root, in order of preference
+var configNames = []string{".go-testcov.yml", ".go-testcov.yaml"}
+
+// options from the config file at the module root (or the current directory outside of modules), so teams keep their
+// policy in the repo instead of makefiles, it uses the option names without "--" and a small subset of yaml:
+//
+//	# comments and empty lines are ignored
+//	min-coverage: 85
+//	split-lines: true
+//	format:
+//	  - json:testcov.json
+//	  - sarif:testcov.sarif
+//	force-check: [pkg/generated.go, api/*_generated.go]
+//	args: [./...]
+//
+// lists repeat the option, flags take true or false and args are the go test arguments used when none are given,
+// command line options come after the config so they win, path is where the config was found
+func loadConfig() (args []string, goTestArgs []string, path string, err error) {
+	wd, err := os.Getwd()
+	check(err)
+	root := wd
+	if module := findGoModule(wd); module != nil {
+		root = module.root
+	}
+	for _, name := range configNames {
+		content, err := ioutil.ReadFile(filepath.Join(root, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		check(err)
+		path = displayConfigPath(filepath.Join(root, name), wd)
+		args, goTestArgs, err = parseConfig(path, string(content))
+		return args, goTestArgs, path, err
+	}
+	return nil, nil, "", nil
+}
+
+func parseConfig(path string, content string) (args []string, goTestArgs []string, err error) {
+	listKey := "" // key of the list the following "- item" lines belong to
+	for index, line := range strings.Split(content, "\n") {
+		if comment := indexOutsideQuotes(line, " #", false); comment != -1 {
+			line = line[:comment]
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		values := []string{}
+		key := listKey
+		indented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+		if strings.HasPrefix(trimmed, "- ") && listKey != "" {
+			values = append(values, unquoteConfigValue(trimmed[2:]))
+		} else if parts := strings.SplitN(trimmed, ": ", 2); indented && listKey != "" && len(parts) == 2 {
+			values = append(values, unquoteConfigValue(parts[0])+"="+unquoteConfigValue(parts[1])) // "a: 1" in a map is "a=1"
+		} else {
+			parts := strings.SplitN(trimmed, ":", 2)
+			if len(parts) != 2 || indented {
+				return nil, nil, fmt.Errorf("%v:%v: expected \"option: value\" but got %q", path, index+1, trimmed)
+			}
+			key, listKey = strings.TrimSpace(parts[0]), ""
+			value := strings.TrimSpace(parts[1])
+			switch {
+			case value == "":
+				listKey = key
+			case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+				for _, item := range splitOutsideQuotes(value[1:len(value)-1], ",") {
+					if item = unquoteConfigValue(item); item != "" {
+						values = append(values, item)
+					}
+				}
+			default:
+				values = append(values, unquoteConfigValue(value))
+			}
+		}
+
+		for _, value := range values {
+			if key == "args" {
+				goTestArgs = append(goTestArgs, value)
+				continue
+			}
+			arg, err := configArg(key, value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%v:%v: %v", path, index+1, err)
+			}
+			if arg != "" {
+				args = append(args, arg)
+			}
+		}
+	}
+	return args, goTestArgs, nil
+}
+
+// "min-coverage", "85" => "--min-coverage=85", flags are only added when true
+func configArg(key string, value string) (arg string, err error) {
+	for _, option := range availableOptions {
+		if option.name != key {
+			continue
+		}
+		if !option.flag {
+			return "--" + key + "=" + value, nil
+		}
+		switch value {
+		case "true":
+			return "--" + key, nil
+		case "false":
+			return "", nil
+		}
+		return "", fmt.Errorf("%v is a flag, expected true or false but got %v", key, value)
+	}
+	return "", fmt.Errorf("unknown option %v", key)
+}
+
+func unquoteConfigValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// splits on sep outside of quotes and nested {} or [], so "a{1,2}, 'b, c'" is "a{1,2}" and "'b, c'"
+func splitOutsideQuotes(value string, sep string) (parts []string) {
+	for {
+		index := indexOutsideQuotes(value, sep, true)
+		if index == -1 {
+			return append(parts, value)
+		}
+		parts = append(parts, value[:index])
+		value = value[index+len(sep):]
+	}
+}
+
+// index of sep outside of quoted values (and nested {} or [] when nested is true), -1 when there is none,
+// quotes only start a value after a separator so "it's" is not quoted
+func indexOutsideQuotes(value string, sep string, nested bool) int {
+	quote := byte(0)
+	depth := 0
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.ContainsRune(" \t[{,:", rune(value[i-1]))):
+			quote = c
+		case nested && (c == '[' || c == '{'):
+			depth++
+		case nested && (c == ']' || c == '}') && depth > 0:
+			depth--
+		case depth == 0 && strings.HasPrefix(value[i:], sep):
+			return i
+		}
+	}
+	return -1
+}
+
+// config path relative to the current directory for error messages, when possible
+func displayConfigPath(path string, wd string) string {
+	if relative, err := filepath.Rel(wd, path); err == nil {
+		return relative
+	}
+	return path
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// statements of a file and how many of them are covered
+type fileCoverage struct {
+	statements int
+	covered    int
+}
+
+// "path:startLine.startColumn,endLine.endColumn statements hits", statements are missing in old profiles
+var profileLinePattern = regexp.MustCompile(`^.+:\d+\.\d+,\d+\.\d+ (\d+ )?\d+$`)
+
+// call fn with each block line of a coverage profile, without the leading mode line
+// profiles are read line by line since merged -coverpkg profiles of big repos can be larger than the memory of ci runners
+func eachProfileLine(coverageFilePath string, fn func(line string)) {
+	file, err := os.Open(coverageFilePath)
+	check(err)
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // long paths of generated code can exceed the default line limit
+	seenMode := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		if !seenMode {
+			seenMode = true
+			continue
+		}
+		fn(line)
+	}
+	check(scanner.Err())
+}
+
+// count statements and covered statements per path of a coverage file, skipping generated files like the section check does
+// the same block can be listed multiple times when packages cover each other, it counts as covered if any run covered it
+func statementCoverageByPath(coverageFilePath string, opts options) (byPath map[string]fileCoverage) {
+	byPath = map[string]fileCoverage{}
+
+	blocks := map[string][2]int{} // "path:location" => statements, hits
+	eachProfileLine(coverageFilePath, func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || !profileLinePattern.MatchString(line) || skipReason(strings.SplitN(fields[0], ":", 2)[0], opts) != "" {
+			return
+		}
+		block := blocks[fields[0]]
+		blocks[fields[0]] = [2]int{stringToInt(fields[1]), block[1] + stringToInt(fields[2])}
+	})
+
+	for location, block := range blocks {
+		path := strings.SplitN(location, ":", 2)[0]
+		file := byPath[path]
+		file.statements += block[0]
+		if block[1] > 0 {
+			file.covered += block[0]
+		}
+		byPath[path] = file
+	}
+	return
+}
+
+// count statements and covered statements of all paths in a coverage file
+func statementCoverage(coverageFilePath string, opts options) (statements int, covered int) {
+	for _, file := range statementCoverageByPath(coverageFilePath, opts) {
+		statements += file.statements
+		covered += file.covered
+	}
+	return
+}
+
+// percentage of covered statements, nothing to cover counts as fully covered
+func coveragePercent(statements int, covered int) float64 {
+	if statements == 0 {
+		return 100
+	}
+	return float64(covered) * 100 / float64(statements)
+}
+
+// round to the given number of decimals
+func roundPercent(percent float64, precision int) float64 {
+	factor := math.Pow(10, float64(precision))
+	return math.Round(percent*factor) / factor
+}
+
+// 81.4 => "81.4%", fmt ignores the locale so output is the same everywhere
+func formatPercent(percent float64, precision int) string {
+	return fmt.Sprintf("%.*f%%", precision, percent)
+}
+
+// change in percentage points, 1.5 => "+1.5"
+func formatPercentChange(change float64, precision int) string {
+	return fmt.Sprintf("%+.*f", precision, change)
+}
+
+// percentage gate that is checked independently of the untested sections, returns the problem or "" when ok
+func checkMinCoverage(coverageFilePath string, opts options) (problem string) {
+	// compare what users see, so 74.96% shown as 75.0% does not fail a 75% threshold
+	percent := roundPercent(coveragePercent(statementCoverage(coverageFilePath, opts)), opts.precision)
+	required, source := opts.minCoverage, "--min-coverage"
+	if opts.target != nil {
+		required = roundPercent(scheduledMinCoverage(*opts.target, opts.minCoverage, timeNow()), opts.precision)
+		source = fmt.Sprintf("--target %v", opts.target)
+	}
+	if percent >= required {
+		return ""
+	}
+	problem = fmt.Sprintf("total coverage %v is below the required %v", formatPercent(percent, opts.precision), formatPercent(required, opts.precision))
+	_, _ = fmt.Fprintf(os.Stderr, "%v (%v)\n", problem, source)
+	return problem
+}
+
+// coverage files matching a glob need instead of --min-file-coverage, from --min-file-coverage=legacy/*.go=20
+type fileCoverageThreshold struct {
+	glob    string
+	percent float64
+}
+
+// percentage gate for each file, so a single file can not drop to 10% while staying within its untested sections,
+// in diff mode only changed files are checked
+func checkFileCoverage(coverageFilePath string, changed map[string]bool, workingDirectory string, opts options) (findings []finding) {
+	byPath := statementCoverageByPath(coverageFilePath, opts)
+	paths := []string{}
+	for path := range byPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		displayPath, readPath := normalizeCoveredPath(path, workingDirectory)
+		if changed != nil && !changed[absolutePath(readPath, workingDirectory)] {
+			continue
+		}
+		required := opts.minFileCoverage
+		for _, threshold := range opts.fileCoverageThresholds {
+			if matchesPathGlob(displayPath, threshold.glob) {
+				required = threshold.percent
+				break
+			}
+		}
+		percent := roundPercent(coveragePercent(byPath[path].statements, byPath[path].covered), opts.precision)
+		if percent >= required {
+			continue
+		}
+		problem := fmt.Sprintf("%v has %v coverage, below the required %v", displayPath, formatPercent(percent, opts.precision), formatPercent(required, opts.precision))
+		_, _ = fmt.Fprintf(os.Stderr, "%v (--min-file-coverage)\n", problem)
+		findings = append(findings, finding{Section: Section{Path: readPath, Line: 1, Column: 1}, Severity: "error", Code: codeLowFileCoverage, Message: problem})
+	}
+	return findings
+}
+
+// show how the coverage percentage of each changed file moved compared to the baseline, since reviewers think in percentages
+func printCoverageDelta(coverageFilePath string, changed map[string]bool, workingDirectory string, opts options) {
+	baseline := statementCoverageByPath(opts.baseline, opts)
+	current := statementCoverageByPath(coverageFilePath, opts)
+
+	paths := []string{}
+	for path := range current {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	deltas := []string{}
+	for _, path := range paths {
+		displayPath, readPath := normalizeCoveredPath(path, workingDirectory)
+		if !changed[absolutePath(readPath, workingDirectory)] {
+			continue
+		}
+		after := coveragePercent(current[path].statements, current[path].covered)
+		before, existed := baseline[path]
+		if existed {
+			beforePercent := coveragePercent(before.statements, before.covered)
+			deltas = append(deltas, fmt.Sprintf("%v %v -> %v (%v)", displayPath, formatPercent(beforePercent, opts.precision), formatPercent(after, opts.precision), formatPercentChange(after-beforePercent, opts.precision)))
+		} else {
+			deltas = append(deltas, fmt.Sprintf("%v new -> %v", displayPath, formatPercent(after, opts.precision)))
+		}
+	}
+
+	if len(deltas) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintln(os.Stderr, "coverage of changed files (baseline -> current):")
+	for _, delta := range deltas {
+		_, _ = fmt.Fprintln(os.Stderr, delta)
+	}
+}
+
+// list the sections of files with less untested sections than configured that were untested in the baseline,
+// so cleanup PRs show what they fixed, files are display paths by covered path
+func printResolvedSections(baselinePath string, current map[string][]Section, files map[string]string) {
+	baseline := groupSectionsByPath(sectionsOf(baselinePath))
+	iterateBySortedKey(baseline, func(path string, sections []Section) {
+		displayPath, ok := files[path]
+		if !ok {
+			return
+		}
+		untested := map[string]bool{}
+		for _, section := range current[path] {
+			untested[section.Location()] = true
+		}
+		resolved := []Section{}
+		for _, section := range sections {
+			if !untested[section.Location()] {
+				resolved = append(resolved, section)
+			}
+		}
+		if len(resolved) == 0 {
+			return
+		}
+		sortSections(resolved)
+		_, _ = fmt.Fprintf(os.Stderr, "%v resolved untested sections since the baseline\n", displayPath)
+		for _, section := range resolved {
+			_, _ = fmt.Fprintln(os.Stderr, displayPath+":"+section.Location())
+		}
+	})
+}
+
+// a file with more untested sections than allowed and no budget comment
+type unbudgetedFile struct {
+	displayPath string
+	readPath    string
+	untested    int
+	severity    string // of its untested sections, warning when it is experimental
+}
+
+// report unbudgeted files the baseline does not have, so new files without tests stand out from churn in existing files
+// files are by covered path
+func newFilesWithoutTests(baselinePath string, files map[string]unbudgetedFile, opts options) (findings []finding) {
+	findings = []finding{}
+	inBaseline := statementCoverageByPath(baselinePath, opts)
+	paths := []string{}
+	for path := range files {
+		if _, found := inBaseline[path]; !found {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		file := files[path]
+		message := fmt.Sprintf("new file without tests (%v untested sections and no budget)", file.untested)
+		_, _ = fmt.Fprintf(os.Stderr, "%v is a %v, add tests for it\n", file.displayPath, message)
+		findings = append(findings, finding{Section: Section{Path: file.readPath, Line: 1, Column: 1}, Severity: file.severity, Code: codeNewFileWithoutTests, Message: message})
+	}
+	return
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strings"
+)
+
+// coverage every package in the dependency tree of a package needs, so critical binaries are gated as a unit
+type dependencyGate struct {
+	pattern string // package as go list understands it, like "./cmd/server"
+	percent float64
+}
+
+// the gate's package and the packages it imports that have coverage below the gate, dependencies come from
+// `go list -deps`, standard library and packages without coverage in the profile (like other modules) are not checked
+func checkDependencyCoverage(coverageFilePath string, gate dependencyGate, opts options) (findings []finding) {
+	statements := map[string]fileCoverage{} // by import path
+	for path, file := range statementCoverageByPath(coverageFilePath, opts) {
+		pkg := statements[pathpkg.Dir(path)]
+		pkg.statements += file.statements
+		pkg.covered += file.covered
+		statements[pathpkg.Dir(path)] = pkg
+	}
+
+	// "example.com/m/lib true", DepOnly is false for the gate's own package
+	dependencies := splitWithoutEmpty(commandOutput("go", "list", "-deps", "-f", "{{if not .Standard}}{{.ImportPath}} {{.DepOnly}}{{end}}", gate.pattern), '\n')
+	sort.Strings(dependencies)
+	for _, line := range dependencies {
+		fields := strings.Fields(line)
+		dependency := fields[0]
+		pkg, found := statements[dependency]
+		if !found {
+			continue
+		}
+		percent := roundPercent(coveragePercent(pkg.statements, pkg.covered), opts.precision)
+		if percent >= gate.percent {
+			continue
+		}
+		code, importedBy := codeLowDependencyCoverage, " imported by "+gate.pattern
+		if len(fields) > 1 && fields[1] == "false" {
+			code, importedBy = codeLowPackageCoverage, ""
+		}
+		problem := fmt.Sprintf(
+			"package %v%v has %v coverage, below the required %v",
+			dependency, importedBy, formatPercent(percent, opts.precision), formatPercent(gate.percent, opts.precision))
+		_, _ = fmt.Fprintf(os.Stderr, "%v (--deps-min-coverage)\n", problem)
+		findings = append(findings, finding{Severity: "error", Code: code, Message: problem})
+	}
+	return
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Directives are found in `//` comments and follow this grammar:
+//
+//	directive = budget | ignore
+//	budget    = "untested sections" [ "(package)" ] ":" count
+//	ignore    = "untested section" [ ":" scope ] [ ("," | space) text ]
+//	scope     = "function" | "next" count ( "block" | "blocks" ) | "begin" | "end" | keyword
+//	keyword   = "if" | "else" | "for" | "range" | "switch" | "case" | "default" | "select" | "func" | "go" | "defer"
+//	count     = digit { digit }
+//
+// "//testcov:critical" requires the file to be fully tested, ignores and budgets in it have no effect,
+// "//testcov:critical package" does the same for all files of its package
+//
+// "//testcov:experimental" turns failures of the file into warnings until --experimental-days passed since it was added
+//
+// "//nolint:testcov" and "//nolint:gocov" are ignores without scope for teams that use nolint comments for all their tools,
+// the names it reacts to are configured with --nolint
+//
+// comments starting with an --ignore-marker, "// coverage:ignore" by default, are ignores without scope too, so teams
+// with their own convention or annotations for other coverage tools can adopt go-testcov without touching every file
+//
+// comments matching --budget-pattern are budgets too, so repos migrating from other tools can keep comments like
+// "// coverage-allowance: 3", the pattern captures the count
+//
+// budgets configure how many untested sections a file has, "(package)" budgets can be in any file of a package
+// and are shared by all of its files that have no budget of their own, ignores mark sections as untested on purpose
+// text before the directive is allowed, so "// TODO: untested section" works
+type directive struct {
+	budget        bool   // "untested sections: N" budget for the whole file
+	packageBudget bool   // "untested sections (package): N" budget for the whole package
+	scope         string // what an ignore applies to: "" for its section, "function", "next", "begin", "end" or a keyword like "else"
+	count         int    // untested sections of a budget or number of blocks for "next"
+	ownLine       bool   // comment is the only thing on its line, so it applies to the code below it
+}
+
+var directiveMarker = "untested section"
+
+var criticalMarker = "//testcov:critical"
+var experimentalMarker = "//testcov:experimental"
+
+// linter names in nolint comments that are ignores
+var nolintNames = []string{"testcov", "gocov"}
+
+// additional ignore comment texts
+var ignoreMarkers = []string{"coverage:ignore"}
+
+// additional budget syntax with one group that captures the count, nil when not configured
+var budgetPattern *regexp.Regexp
+
+var directiveKeywords = []string{"if", "else", "for", "range", "switch", "case", "default", "select", "func", "go", "defer"}
+
+// find the directive in a line of code
+// found is false when the comment does not contain a directive, err is set when it contains a malformed one
+func parseDirective(line string) (found directive, ok bool, err error) {
+	commentStart := strings.Index(line, "//")
+	if commentStart == -1 {
+		return
+	}
+	comment := line[commentStart+2:]
+	if isNolintDirective(comment) || isIgnoreMarker(comment) {
+		found.ownLine = strings.TrimSpace(line[:commentStart]) == ""
+		return found, true, nil
+	}
+	if budgetPattern != nil {
+		if match := budgetPattern.FindStringSubmatch(comment); match != nil {
+			if match[1] == "" || leadingDigits(match[1]) != match[1] {
+				return found, false, fmt.Errorf("expected --budget-pattern to capture a number but got %q", match[1])
+			}
+			found.ownLine = strings.TrimSpace(line[:commentStart]) == ""
+			found.budget, found.count = true, stringToInt(match[1])
+			return found, true, nil
+		}
+	}
+	markerStart := strings.Index(comment, directiveMarker)
+	if markerStart == -1 {
+		return
+	}
+	rest := comment[markerStart+len(directiveMarker):]
+	found.ownLine = strings.TrimSpace(line[:commentStart]) == ""
+
+	// budget
+	if strings.HasPrefix(rest, "s") {
+		rest = strings.TrimLeftFunc(rest[1:], unicode.IsSpace)
+		if strings.HasPrefix(rest, "(package)") {
+			found.packageBudget = true
+			rest = strings.TrimLeftFunc(rest[len("(package)"):], unicode.IsSpace)
+		}
+		if !strings.HasPrefix(rest, ":") {
+			return // prose like "// no untested sections here"
+		}
+		count := leadingDigits(strings.TrimLeftFunc(rest[1:], unicode.IsSpace))
+		if count == "" {
+			if found.packageBudget {
+				return found, false, fmt.Errorf("expected a number after \"untested sections (package):\"")
+			}
+			return found, false, fmt.Errorf("expected a number after \"untested sections:\"")
+		}
+		found.budget, found.count = true, stringToInt(count)
+		return found, true, nil
+	}
+
+	// ignore without scope
+	if rest == "" || strings.HasPrefix(rest, ",") || unicode.IsSpace(rune(rest[0])) {
+		return found, true, nil
+	}
+	if !strings.HasPrefix(rest, ":") {
+		return // prose like "// untested sectionless"
+	}
+
+	// ignore with scope, which ends at a "," to allow explanations
+	scope := strings.Fields(strings.SplitN(rest[1:], ",", 2)[0])
+	found.scope, err = parseDirectiveScope(scope)
+	if err != nil {
+		return found, false, err
+	}
+	if found.scope == "next" {
+		found.count = stringToInt(scope[1])
+	}
+	return found, true, nil
+}
+
+// "//testcov:critical package" => "package", "//testcov:critical" => "file", anything else => ""
+func parseCriticalDirective(line string) (scope string) {
+	fields := strings.Fields(strings.TrimSpace(line))
+	if len(fields) == 0 || fields[0] != criticalMarker {
+		return ""
+	}
+	if len(fields) > 1 && fields[1] == "package" {
+		return "package"
+	}
+	return "file"
+}
+
+// "nolint:errcheck,testcov // reason" => true
+func isNolintDirective(comment string) bool {
+	if !strings.HasPrefix(comment, "nolint:") {
+		return false
+	}
+	names := strings.Fields(comment[len("nolint:"):])
+	if len(names) == 0 {
+		return false
+	}
+	for _, name := range strings.Split(names[0], ",") {
+		if containsString(nolintNames, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// " coverage:ignore, reason" => true when "coverage:ignore" is an --ignore-marker
+func isIgnoreMarker(comment string) bool {
+	comment = strings.TrimLeftFunc(comment, unicode.IsSpace)
+	for _, marker := range ignoreMarkers {
+		if !strings.HasPrefix(comment, marker) {
+			continue
+		}
+		rest := comment[len(marker):]
+		if rest == "" || strings.HasPrefix(rest, ",") || unicode.IsSpace(rune(rest[0])) {
+			return true
+		}
+	}
+	return false
+}
+
+// ["next", "3", "blocks"] => "next"
+func parseDirectiveScope(words []string) (scope string, err error) {
+	expected := fmt.Errorf(
+		"expected \"untested section:\" to be followed by function, next N blocks, begin, end or one of %v",
+		strings.Join(directiveKeywords, ", "))
+	switch {
+	case len(words) == 0:
+		return "", expected
+	case (words[0] == "function" || words[0] == "begin" || words[0] == "end") && len(words) == 1:
+		return words[0], nil
+	case words[0] == "next":
+		if len(words) != 3 || leadingDigits(words[1]) != words[1] || (words[2] != "block" && words[2] != "blocks") {
+			return "", fmt.Errorf("expected \"next N blocks\" but got %q", strings.Join(words, " "))
+		}
+		return "next", nil
+	case len(words) == 1 && containsString(directiveKeywords, words[0]):
+		return words[0], nil
+	default:
+		return "", expected
+	}
+}
+
+// print malformed directives so users know why they have no effect, returns true if there were any
+func printDirectiveErrors(source sourceFile, displayPath string) bool {
+	lineNumbers := []int{}
+	for lineNumber := range source.directiveErrors {
+		lineNumbers = append(lineNumbers, lineNumber)
+	}
+	sort.Ints(lineNumbers)
+	for _, lineNumber := range lineNumbers {
+		_, _ = fmt.Fprintf(os.Stderr, "%v:%v: invalid directive: %v\n", displayPath, lineNumber, source.directiveErrors[lineNumber])
+	}
+	return len(lineNumbers) > 0
+}
+
+// "12 foo" => "12"
+func leadingDigits(text string) string {
+	end := 0
+	for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+		end++
+	}
+	return text[:end]
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// "${NAME}" or "${NAME:-default}", plain "$NAME" is left alone so regular expressions and shell snippets keep working
+var envReferenceRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// replace environment variable references in an option value, so one command line or config can be strict in ci
+// and lenient locally, like --min-coverage=${TESTCOV_MIN_COVERAGE:-60}, unset variables without default are an error
+func interpolateEnv(value string) (interpolated string, err error) {
+	interpolated = envReferenceRegexp.ReplaceAllStringFunc(value, func(reference string) string {
+		match := envReferenceRegexp.FindStringSubmatch(reference)
+		if found, ok := os.LookupEnv(match[1]); ok && (found != "" || match[2] == "") {
+			return found
+		}
+		if match[2] != "" {
+			return match[3]
+		}
+		if err == nil {
+			err = fmt.Errorf("environment variable %v is not set, set it or give a default like ${%v:-value}", match[1], match[1])
+		}
+		return reference
+	})
+	return interpolated, err
+}
+
+// GO_TESTCOV_MIN_COVERAGE=85 => --min-coverage=85 for every option, so ci templates shared by many repos can configure
+// go-testcov without editing command lines, they come after the config file and before the command line
+// flags take true or false, names are the variables the options came from for error messages
+func environmentArgs() (args []string, names []string, err error) {
+	for _, option := range availableOptions {
+		name := "GO_TESTCOV_" + strings.ToUpper(strings.Replace(option.name, "-", "_", -1))
+		value := os.Getenv(name)
+		if value == "" {
+			continue // unset or emptied by a template
+		}
+		arg, err := configArg(option.name, value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v: %v", name, err)
+		}
+		if arg != "" {
+			args = append(args, arg)
+			names = append(names, name)
+		}
+	}
+	return args, names, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"os"
+	"strings"
+)
+
+// constructors whose result is an error, by package
+var errorConstructors = map[string][]string{
+	"fmt":    {"Errorf"},
+	"errors": {"New", "Wrap", "Wrapf", "WithMessage", "WithStack", "Join"},
+}
+
+// does the expression look like an error without type checking: err, parseErr, ErrNotFound, io.ErrUnexpectedEOF,
+// fmt.Errorf(...) or errors.New(...)
+func isErrorExpression(expression ast.Expr) bool {
+	switch e := expression.(type) {
+	case *ast.Ident:
+		return e.Name == "err" || strings.HasSuffix(e.Name, "Err") || strings.HasPrefix(e.Name, "Err")
+	case *ast.SelectorExpr:
+		return strings.HasPrefix(e.Sel.Name, "Err")
+	case *ast.CallExpr:
+		if selector, ok := e.Fun.(*ast.SelectorExpr); ok {
+			if pkg, ok := selector.X.(*ast.Ident); ok {
+				return containsString(errorConstructors[pkg.Name], selector.Sel.Name)
+			}
+		}
+	}
+	return false
+}
+
+// an error return inside an untested section
+type untestedErrorPath struct {
+	displayPath string
+	readPath    string
+	line        int
+	code        string
+}
+
+// error returns inside the sections, in order
+func findUntestedErrorPaths(displayPath string, readPath string, sections []Section, source sourceFile) (paths []untestedErrorPath) {
+	for _, section := range sections {
+		for _, line := range source.errorReturns {
+			if section.Line <= line && line <= section.EndLine {
+				paths = append(paths, untestedErrorPath{displayPath, readPath, line, strings.TrimSpace(source.line(line))})
+			}
+		}
+	}
+	return paths
+}
+
+// error paths are where untested code bites hardest in production, so they are listed with a threshold of their own,
+// findings are errors when there are more than --max-untested-error-paths
+func reportUntestedErrorPaths(paths []untestedErrorPath, allowed int) (findings []finding, failed bool) {
+	if len(paths) == 0 {
+		return nil, false
+	}
+	failed = len(paths) > allowed
+	severity := "warning"
+	if failed {
+		severity = "error"
+	}
+	details := fmt.Sprintf("(%v current vs %v allowed by --max-untested-error-paths)", len(paths), allowed)
+	_, _ = fmt.Fprintf(os.Stderr, "untested error paths %v:\n", details)
+	for _, path := range paths {
+		_, _ = fmt.Fprintf(os.Stderr, "%v:%v %v\n", path.displayPath, path.line, path.code)
+		findings = append(findings, finding{
+			Section:  Section{Path: path.readPath, Line: path.line, Column: 1},
+			Severity: severity, Code: codeUntestedErrorPath, Message: "untested error path " + details,
+		})
+	}
+	return findings, failed
+}
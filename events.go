@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// something that happened during a run, written as one json object per line with --events=FILE so tools can follow
+// long monorepo runs while they happen, it looks like:
+//
+//	{"time": "2024-01-02T03:04:05Z", "event": "start", "args": ["./..."]}
+//	{"time": "2024-01-02T03:04:06Z", "event": "package", "package": "example.com/m/pkg", "status": "ok"}
+//	{"time": "2024-01-02T03:04:07Z", "event": "section", "path": "pkg/a.go", "line": 3, "column": 2, "endLine": 5, "endColumn": 3}
+//	{"time": "2024-01-02T03:04:07Z", "event": "file", "path": "pkg/a.go", "untested": 2, "configured": 1, "status": "failed"}
+//	{"time": "2024-01-02T03:04:07Z", "event": "verdict", "status": "FAIL", "coverage": 85.5}
+//
+// package status is "ok", "FAIL" or "skip" (no test files) as go test printed it,
+// file status is the same as in the json report, verdict status is "PASS" or "FAIL" and coverage is missing when go test failed
+type event struct {
+	Time       string   `json:"time"`
+	Event      string   `json:"event"`
+	Args       []string `json:"args,omitempty"`
+	Package    string   `json:"package,omitempty"`
+	Path       string   `json:"path,omitempty"`
+	Line       int      `json:"line,omitempty"`
+	Column     int      `json:"column,omitempty"`
+	EndLine    int      `json:"endLine,omitempty"`
+	EndColumn  int      `json:"endColumn,omitempty"`
+	Untested   *int     `json:"untested,omitempty"`
+	Configured *int     `json:"configured,omitempty"`
+	Status     string   `json:"status,omitempty"`
+	Coverage   *float64 `json:"coverage,omitempty"`
+}
+
+// where events go, nil when --events was not given, set for the whole run like the command runner
+var eventStream io.Writer
+
+// open the file events are written to, "-" writes them to stdout, "/dev/fd/3" to an inherited file descriptor
+func openEventStream(path string) (close func()) {
+	if path == "-" {
+		eventStream = os.Stdout
+		return func() { eventStream = nil }
+	}
+	file, err := os.Create(path)
+	check(err)
+	eventStream = file
+	return func() {
+		eventStream = nil
+		_ = file.Close()
+	}
+}
+
+func emitEvent(e event) {
+	if eventStream == nil {
+		return
+	}
+	e.Time = timeNow().UTC().Format(time.RFC3339Nano)
+	content, err := json.Marshal(e)
+	check(err)
+	_, _ = eventStream.Write(append(content, '\n'))
+}
+
+// section and file events for everything a check found, then the verdict
+func emitResultEvents(result runResult) {
+	for _, finding := range result.findings {
+		if finding.Code == codeNewUntestedSection {
+			emitEvent(event{Event: "section", Path: finding.Path, Line: finding.Line, Column: finding.Column, EndLine: finding.EndLine, EndColumn: finding.EndColumn})
+		}
+	}
+	for _, file := range result.files {
+		untested, configured := file.Untested, file.Configured
+		emitEvent(event{Event: "file", Path: file.Path, Untested: &untested, Configured: &configured, Status: file.Status})
+	}
+	verdict := event{Event: "verdict", Status: "PASS"}
+	if result.exitCode != 0 {
+		verdict.Status = "FAIL"
+	}
+	if result.coverage >= 0 {
+		coverage := result.coverage
+		verdict.Coverage = &coverage
+	}
+	emitEvent(verdict)
+}
+
+// "ok  	example.com/m/pkg	0.1s" or "FAIL	example.com/m/pkg [build failed]" or "?   	example.com/m/pkg	[no test files]"
+var packageResultRegexp = regexp.MustCompile(`^(ok|FAIL|\?)\s+(\S+)`)
+
+// emits a package event for each package go test finished, when its output passes through
+type packageEventWriter struct {
+	pending []byte // incomplete last line
+}
+
+func (w *packageEventWriter) Write(data []byte) (int, error) {
+	w.pending = append(w.pending, data...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end == -1 {
+			return len(data), nil
+		}
+		emitPackageEvent(string(w.pending[:end]))
+		w.pending = w.pending[end+1:]
+	}
+}
+
+func emitPackageEvent(line string) {
+	match := packageResultRegexp.FindStringSubmatch(strings.TrimRight(line, "\r"))
+	if match == nil {
+		return
+	}
+	status := match[1]
+	if status == "?" {
+		status = "skip"
+	}
+	emitEvent(event{Event: "package", Package: match[2], Status: status})
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"time"
+)
+
+// when the grace period of a file marked with //testcov:experimental ends, based on when the marker was added
+// ok is false when the file is not experimental or the grace period is over
+func experimentalUntil(path string, source sourceFile, opts options) (until time.Time, ok bool) {
+	if source.experimentalLine == 0 {
+		return
+	}
+	vcs, found := detectVersionControl(opts.vcs)
+	if !found {
+		return // outside of repositories there is no history, so it is enforced
+	}
+	added, found := vcs.lineAddedAt(path, source.experimentalLine)
+	if !found {
+		return // cannot tell how long the code was experimental, so it is enforced
+	}
+	until = added.AddDate(0, 0, opts.experimentalDays)
+	return until, timeNow().Before(until)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// run the tests and export their coverage in a format other tools understand
+func runExport(argv []string) (exitCode int) {
+	// export options are handled here, so they are not passed to go test
+	format, diffBase, rest := "", "", []string{}
+	for _, arg := range argv {
+		switch {
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
+		case strings.HasPrefix(arg, "--diff-base="):
+			diffBase = strings.TrimPrefix(arg, "--diff-base=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if (format != "unidiff-overlay" || diffBase == "") && format != "heatmap-csv" && format != "heatmap-json" {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: go-testcov export --format=unidiff-overlay --diff-base=REVISION [go test arguments]\n"+
+			"       go-testcov export --format=heatmap-csv|heatmap-json [go test arguments]")
+		return 2
+	}
+
+	opts, goTestArgs, err := parseOptions(rest)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer useOptions(opts)()
+
+	coveragePath, cleanup := coverageProfilePath(opts)
+	defer cleanup()
+	goTestArgs = append(append([]string{"test"}, goTestArgs...), "-coverprofile", coveragePath)
+	if exitCode = runGoTest(goTestArgs, opts); exitCode != 0 {
+		return exitCode
+	}
+
+	wd, err := os.Getwd()
+	check(err)
+	switch format {
+	case "heatmap-csv":
+		fmt.Print(formatDebtDensityCsv(debtDensities(coveragePath, wd, opts)))
+	case "heatmap-json":
+		content, err := json.MarshalIndent(debtDensities(coveragePath, wd, opts), "", "  ")
+		check(err)
+		fmt.Println(string(content))
+	default:
+		diff := commandOutput("git", "diff", "--relative", diffBase)
+		fmt.Print(coverageOverlay(diff, lineCoverage(coveragePath, wd, opts)))
+	}
+	return 0
+}
+
+// how much untested code a file has for its size, to plot where coverage debt is concentrated
+type debtDensity struct {
+	Path       string  `json:"path"`
+	Lines      int     `json:"lines"`
+	Statements int     `json:"statements"`
+	Untested   int     `json:"untested"` // statements no test covered
+	Density    float64 `json:"density"`  // untested statements per 100 lines
+}
+
+// debt density of each covered file that can be read, densest first
+func debtDensities(coverageFilePath string, workingDirectory string, opts options) (densities []debtDensity) {
+	densities = []debtDensity{}
+	for path, coverage := range statementCoverageByPath(coverageFilePath, opts) {
+		displayPath, readPath := normalizeCoveredPath(path, workingDirectory)
+		content, err := ioutil.ReadFile(readPath)
+		if err != nil {
+			continue // source is not available, like for vendored code
+		}
+		lines := strings.Count(string(content), "\n")
+		untested := coverage.statements - coverage.covered
+		density := 0.0
+		if lines > 0 {
+			density = roundPercent(float64(untested)*100/float64(lines), 2)
+		}
+		densities = append(densities, debtDensity{Path: displayPath, Lines: lines, Statements: coverage.statements, Untested: untested, Density: density})
+	}
+	sort.Slice(densities, func(i, j int) bool {
+		if densities[i].Density != densities[j].Density {
+			return densities[i].Density > densities[j].Density
+		}
+		return densities[i].Path < densities[j].Path
+	})
+	return
+}
+
+// debt densities with a header row, for spreadsheets
+func formatDebtDensityCsv(densities []debtDensity) string {
+	var content strings.Builder
+	writer := csv.NewWriter(&content)
+	check(writer.Write([]string{"path", "lines", "statements", "untested", "density"}))
+	for _, d := range densities {
+		check(writer.Write([]string{d.Path, strconv.Itoa(d.Lines), strconv.Itoa(d.Statements), strconv.Itoa(d.Untested), strconv.FormatFloat(d.Density, 'f', 2, 64)}))
+	}
+	writer.Flush()
+	check(writer.Error())
+	return content.String()
+}
+
+// for each file relative to the working directory and each line with code, whether any test covered it
+func lineCoverage(coverageFilePath string, workingDirectory string, opts options) (covered map[string]map[int]bool) {
+	covered = map[string]map[int]bool{}
+	for path, lines := range lineHits(coverageFilePath, workingDirectory, opts) {
+		covered[path] = map[int]bool{}
+		for lineNumber, hits := range lines {
+			covered[path][lineNumber] = hits > 0
+		}
+	}
+	return
+}
+
+// for each file relative to the working directory and each line with code, the most hits of any block on the line
+func lineHits(coverageFilePath string, workingDirectory string, opts options) (hits map[string]map[int]int) {
+	hits = map[string]map[int]int{}
+	eachProfileLine(coverageFilePath, func(line string) {
+		if strings.Count(line, " ") != 2 {
+			return
+		}
+		section := NewSection(line)
+		if skipReason(section.Path, opts) != "" {
+			return
+		}
+		displayPath, _ := normalizeCoveredPath(section.Path, workingDirectory)
+		if hits[displayPath] == nil {
+			hits[displayPath] = map[int]int{}
+		}
+		for lineNumber := section.Line; lineNumber <= section.EndLine; lineNumber++ {
+			if previous, found := hits[displayPath][lineNumber]; !found || section.Hits > previous {
+				hits[displayPath][lineNumber] = section.Hits
+			}
+		}
+	})
+	return
+}
+
+// prefix each line of a unified diff with a coverage gutter, so reviewers see untested new lines in context:
+// "+ " for added lines that are covered, "- " for added lines that are not covered and "  " for everything else
+func coverageOverlay(diff string, covered map[string]map[int]bool) string {
+	var overlay strings.Builder
+	path := ""
+	newLine := 0
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if line == "" {
+			continue
+		}
+		gutter := "  "
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			path = strings.TrimPrefix(strings.TrimSpace(line[4:]), "b/")
+		case strings.HasPrefix(line, "@@"):
+			if match := hunkHeader.FindStringSubmatch(line); match != nil {
+				newLine = stringToInt(match[1])
+			}
+		case strings.HasPrefix(line, "+"):
+			if hit, isCode := covered[path][newLine]; isCode {
+				gutter = map[bool]string{true: "+ ", false: "- "}[hit]
+			}
+			newLine++
+		case strings.HasPrefix(line, " "):
+			newLine++
+		}
+		overlay.WriteString(gutter + line)
+	}
+	return overlay.String()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// header go generators put above the package clause, https://golang.org/s/generatedcode
+var generatedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// headers found by covered path as written in the profile, so files are not read again for every line of a profile,
+// useOptions starts a new cache for the working directory of each run, nil outside of runs
+var generatedHeaders map[string]bool
+var generatedHeadersDirectory string
+
+// does the covered file start with a "// Code generated ... DO NOT EDIT." header, files that can not be read have none
+func hasGeneratedHeader(path string) bool {
+	if found, ok := generatedHeaders[path]; ok {
+		return found
+	}
+	directory := generatedHeadersDirectory
+	if generatedHeaders == nil {
+		wd, err := os.Getwd()
+		check(err)
+		directory = wd
+	}
+	_, readPath := normalizeCoveredPath(path, directory)
+	if !filepath.IsAbs(readPath) {
+		readPath = filepath.Join(directory, readPath)
+	}
+
+	found := false
+	if file, err := os.Open(readPath); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() && !strings.HasPrefix(scanner.Text(), "package ") {
+			if generatedHeader.MatchString(strings.TrimRight(scanner.Text(), "\r")) {
+				found = true
+				break
+			}
+		}
+		_ = file.Close()
+	}
+	if generatedHeaders != nil {
+		generatedHeaders[path] = found
+	}
+	return found
+}
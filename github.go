@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// findings as github actions workflow commands, so they show up as annotations on the lines of the pull request diff
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#setting-an-error-message
+func formatGithub(result runResult) string {
+	content := ""
+	for _, finding := range result.findings {
+		properties := []string{}
+		if finding.Path != "" {
+			properties = append(properties, "file="+escapeGithubProperty(finding.Path), fmt.Sprintf("line=%v", finding.Line))
+			if finding.Column != 0 {
+				properties = append(properties, fmt.Sprintf("col=%v", finding.Column))
+			}
+			if finding.EndLine != 0 {
+				properties = append(properties, fmt.Sprintf("endLine=%v", finding.EndLine), fmt.Sprintf("endColumn=%v", finding.EndColumn))
+			}
+		}
+		properties = append(properties, "title="+escapeGithubProperty("go-testcov "+finding.Code))
+		content += fmt.Sprintf("::%v %v::%v\n", finding.Severity, strings.Join(properties, ","), escapeGithubData(finding.Message))
+	}
+	return content
+}
+
+// github actions set GITHUB_ACTIONS=true, annotations are added there without --format=github
+func runningInGithubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+func escapeGithubData(data string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(data)
+}
+
+func escapeGithubProperty(property string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(property)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+// gitlab code quality report, so merge requests show untested sections in their code quality widget
+// https://docs.gitlab.com/ee/ci/testing/code_quality.html#implement-a-custom-tool
+type gitlabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitlabLocation `json:"location"`
+}
+
+type gitlabLocation struct {
+	Path  string      `json:"path"`
+	Lines gitlabLines `json:"lines"`
+}
+
+type gitlabLines struct {
+	Begin int `json:"begin"`
+	End   int `json:"end,omitempty"`
+}
+
+// gitlab has no error or warning, findings that fail the run are major
+var gitlabSeverities = map[string]string{"error": "major", "warning": "minor"}
+
+// findings with a location as code quality issues, fingerprints identify the same finding in the base and head report,
+// they use the function and the position among its findings instead of line numbers, which change when code above is edited
+func formatGitlab(result runResult) string {
+	issues := []gitlabIssue{}
+	occurrences := map[string]int{}
+	for _, finding := range result.findings {
+		if finding.Path == "" {
+			continue // the widget only shows issues in files
+		}
+		section := fmt.Sprintf("%v %v %v", finding.Code, finding.Path, finding.Function)
+		identity := fmt.Sprintf("%v %v", section, occurrences[section])
+		occurrences[section]++
+		issues = append(issues, gitlabIssue{
+			Description: finding.Message,
+			CheckName:   finding.Code,
+			Fingerprint: fmt.Sprintf("%x", sha256.Sum256([]byte(identity))),
+			Severity:    gitlabSeverities[finding.Severity],
+			Location:    gitlabLocation{Path: finding.Path, Lines: gitlabLines{Begin: finding.Line, End: finding.EndLine}},
+		})
+	}
+	content, err := json.MarshalIndent(issues, "", "  ")
+	check(err)
+	return string(content) + "\n"
+}
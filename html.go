@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// a single html page with the untested section accounting of every checked file and the annotated source of every
+// covered file, like `go tool cover -html` but showing why the run failed
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>go-testcov</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+pre { margin: 0; }
+.failed { color: #c00; }
+.covered { background: #dfd; }
+.untested { background: #fdd; }
+.line { color: #999; user-select: none; }
+</style>
+</head>
+<body>
+<h1>go-testcov {{.Status}}</h1>
+<p>coverage {{.Coverage}}</p>
+<table>
+<tr><th>file</th><th>untested</th><th>configured</th><th>scope</th><th>status</th></tr>
+{{- range .Files}}
+<tr class="{{.Status}}"><td><a href="#{{.Path}}">{{.Path}}</a></td><td>{{.Untested}}</td><td>{{.Configured}}</td><td>{{.Scope}}</td><td>{{.Status}}</td></tr>
+{{- end}}
+</table>
+{{- if .Findings}}
+<ul>
+{{- range .Findings}}
+<li class="{{.Severity}}">{{if .Path}}{{.Path}}:{{.Line}}: {{end}}{{.Code}} {{.Message}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- range .Sources}}
+<h2 id="{{.Path}}">{{.Path}}</h2>
+<pre>
+{{- range .Lines}}
+<span class="{{.Class}}"><span class="line">{{printf "%5d" .Number}}</span> {{.Code}}</span>
+{{- end}}
+</pre>
+{{- end}}
+</body>
+</html>
+`))
+
+type htmlPage struct {
+	Status   string
+	Coverage string
+	Files    []fileResult
+	Findings []finding
+	Sources  []htmlSource
+}
+
+type htmlSource struct {
+	Path  string
+	Lines []htmlLine
+}
+
+type htmlLine struct {
+	Number int
+	Code   string
+	Class  string // "covered", "untested" or empty for lines without code
+}
+
+func formatHtml(result runResult) string {
+	page := htmlPage{Status: "PASS", Coverage: "unknown", Files: result.files, Findings: result.findings}
+	if result.exitCode != 0 {
+		page.Status = "FAIL"
+	}
+	if result.coverage >= 0 {
+		page.Coverage = formatPercent(result.coverage, result.precision)
+	}
+
+	paths := []string{}
+	for path := range result.lines {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue // source is not readable from here, the summary still shows it
+		}
+		source := htmlSource{Path: path}
+		for index, code := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+			line := htmlLine{Number: index + 1, Code: code}
+			if hits, found := result.lines[path][index+1]; found {
+				line.Class = "untested"
+				if hits > 0 {
+					line.Class = "covered"
+				}
+			}
+			source.Lines = append(source.Lines, line)
+		}
+		page.Sources = append(page.Sources, source)
+	}
+
+	var content bytes.Buffer
+	check(htmlTemplate.Execute(&content, page))
+	return content.String()
+}
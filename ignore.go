@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// keep untested sections that are not marked with "untested section" comment
+// NOTE: this is a bit rough as it does not account for partial lines via start/end characters
+// TODO: warn about sections that have a comment but are not uncovered
+func removeSectionsMarkedWithInlineComment(sections []Section, source sourceFile) []Section {
+	ignored := inlineIgnores(sections, source)
+	kept := []Section{}
+	for _, section := range sections {
+		if _, ok := ignored[section]; !ok {
+			kept = append(kept, section)
+		}
+	}
+	return kept
+}
+
+// line number of the "untested section" comment that marks each ignored section
+// scoped comments mark all sections of their function, the next N sections, starting with the section they are in,
+// or all sections starting between a "begin" and an "end" comment
+func inlineIgnores(sections []Section, source sourceFile) (ignored map[Section]int) {
+	ignored = map[Section]int{}
+	sorted := append([]Section{}, sections...)
+	sortSections(sorted)
+
+	for _, section := range sorted {
+		if line := inlineIgnoreLine(section, source); line != 0 {
+			ignored[section] = line
+		}
+	}
+
+	begin := 0 // line of the "begin" comment of the current region
+	for _, commentLine := range source.directiveLines() {
+		directive := source.directives[commentLine]
+		switch directive.scope {
+		case "begin":
+			begin = commentLine
+		case "end":
+			for _, section := range sorted {
+				if _, done := ignored[section]; !done && begin != 0 && begin <= section.Line && section.Line <= commentLine {
+					ignored[section] = begin
+				}
+			}
+			begin = 0
+		case "function":
+			function, found := source.enclosingFunction(commentLine)
+			for _, section := range sorted {
+				if _, done := ignored[section]; !done && found && function[0] <= section.Line && section.EndLine <= function[1] {
+					ignored[section] = commentLine
+				}
+			}
+		case "next":
+			remaining := directive.count
+			for _, section := range sorted {
+				if _, done := ignored[section]; !done && remaining > 0 && section.EndLine >= commentLine {
+					ignored[section] = commentLine
+					remaining--
+				}
+			}
+		}
+	}
+	return
+}
+
+// line number of the "untested section" comment that marks the section, 0 if it is not marked
+// the comment can be anywhere in the section, on the header of the statement that opens it, or on its own line above
+// keyword scoped comments like "untested section: else" only mark sections opened by that keyword, for one-liners with multiple sections
+func inlineIgnoreLine(section Section, source sourceFile) int {
+	opener := sectionOpener(section, source)
+	marks := func(lineNumber int, above bool) bool {
+		directive, ok := source.directives[lineNumber]
+		switch {
+		case !ok || directive.budget || (above && !directive.ownLine):
+			return false
+		case directive.scope == "":
+			return true
+		case directive.scope == "function" || directive.scope == "next" || directive.scope == "begin" || directive.scope == "end":
+			return false // handled for all sections at once
+		default:
+			return regexp.MustCompile("\\b" + directive.scope + "\\b").MatchString(opener)
+		}
+	}
+
+	firstLine := section.Line
+	if headerStart, ok := source.headerStarts[firstLine]; ok {
+		firstLine = headerStart
+	}
+	for lineNumber := firstLine; lineNumber <= section.EndLine; lineNumber++ {
+		if marks(lineNumber, false) {
+			return lineNumber
+		}
+	}
+	if marks(firstLine-1, true) {
+		return firstLine - 1 // inline ignore above it
+	}
+	return 0
+}
+
+// code that opens the section, "if x { a() } else { b() }" => " else { " for the else section
+// uses the statement header when the section starts on its own line
+func sectionOpener(section Section, source sourceFile) string {
+	lines := source.lines
+	if section.Line > len(lines) {
+		return ""
+	}
+	opener := lines[section.Line-1]
+	if section.Column >= 1 && section.Column-1 < len(opener) {
+		opener = opener[:section.Column-1]
+	}
+	if headerStart, ok := source.headerStarts[section.Line]; ok && strings.TrimSpace(opener) == "" {
+		header := []string{}
+		for _, line := range lines[headerStart-1 : section.Line-1] {
+			header = append(header, strings.SplitN(line, "//", 2)[0]) // comments are not code
+		}
+		opener = strings.Join(header, "\n")
+	}
+	return opener[strings.LastIndex(opener, "}")+1:]
+}
+
+// print why each untested section was not reported, to debug how inline comments and the configured count interact
+func explainIgnoredSections(sections []Section, source sourceFile, displayPath string, budget string) {
+	ignored := inlineIgnores(sections, source)
+	sortSections(sections)
+	for _, section := range sections {
+		if line, ok := ignored[section]; ok {
+			_, _ = fmt.Fprintf(os.Stderr, "%v:%v ignored by inline comment on line %v\n", displayPath, section.Location(), line)
+		} else if budget != "" {
+			_, _ = fmt.Fprintf(os.Stderr, "%v:%v counted against %v\n", displayPath, section.Location(), budget)
+		}
+	}
+}
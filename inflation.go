@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// find budgets that grew and inline ignores that were added since the revision, so raising the allowed untested
+// sections needs an explicit --allow-budget-increase instead of slipping through review
+// paths are the changed files relative to the current directory, files matching the allowlist globs may add ignores
+func checkBudgetIncrease(revision string, vcs versionControl, paths []string, allowed bool, allowlist []string) (findings []finding) {
+	severity, suffix := "error", "(--forbid-budget-increase)"
+	if allowed {
+		severity, suffix = "warning", "(allowed with --allow-budget-increase)"
+	}
+	return checkInflation(revision, vcs, paths, true, severity, suffix, allowlist)
+}
+
+// find inline ignores that were added since the revision, so contributors can not opt out of the check in the same PR
+// that adds the untested code without --allow-new-ignores or an allowlist entry
+func checkNewIgnores(revision string, vcs versionControl, paths []string, allowed bool, allowlist []string) (findings []finding) {
+	severity, suffix := "error", "(--forbid-new-ignores)"
+	if allowed {
+		severity, suffix = "warning", "(allowed with --allow-new-ignores)"
+	}
+	return checkInflation(revision, vcs, paths, false, severity, suffix, allowlist)
+}
+
+func checkInflation(revision string, vcs versionControl, paths []string, budgets bool, severity string, suffix string, allowlist []string) (findings []finding) {
+	findings = []finding{}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue // deleted
+		}
+		before, _ := vcs.fileAt(revision, path)
+		oldSource := parseSourceFile(path, before)
+		newSource := parseSourceFile(path, readFile(path))
+
+		for _, packageScope := range []bool{false, true} {
+			oldCount, _ := oldSource.budget(packageScope)
+			newCount, lineNumber := newSource.budget(packageScope)
+			if budgets && newCount > oldCount {
+				message := fmt.Sprintf("increases untested sections from %v to %v", oldCount, newCount)
+				_, _ = fmt.Fprintf(os.Stderr, "%v:%v: %v %v\n", path, lineNumber, message, suffix)
+				findings = append(findings, finding{Section: Section{Path: path, Line: lineNumber, Column: 1}, Severity: severity, Code: codeBudgetIncrease, Message: message})
+			}
+		}
+
+		if matchesAnyPathGlob(path, allowlist) {
+			continue
+		}
+		for _, lineNumber := range addedIgnores(oldSource, newSource) {
+			message := "adds an untested section comment"
+			_, _ = fmt.Fprintf(os.Stderr, "%v:%v: %v %v\n", path, lineNumber, message, suffix)
+			findings = append(findings, finding{Section: Section{Path: path, Line: lineNumber, Column: 1}, Severity: severity, Code: codeBudgetIncrease, Message: message})
+		}
+	}
+	return
+}
+
+// parse a file of globs for files that may add ignores, one per line, "#" starts a comment line
+func parseIgnoreAllowlist(path string) (globs []string, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	globs = []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			globs = append(globs, line)
+		}
+	}
+	return globs, nil
+}
+
+// lines of ignores in the new source that were not in the old source, ignores that only moved are not added
+func addedIgnores(oldSource sourceFile, newSource sourceFile) (lineNumbers []int) {
+	existing := map[string]int{}
+	for _, lineNumber := range oldSource.directiveLines() {
+		if !oldSource.directives[lineNumber].budget {
+			existing[strings.TrimSpace(oldSource.line(lineNumber))]++
+		}
+	}
+	for _, lineNumber := range newSource.directiveLines() {
+		if newSource.directives[lineNumber].budget {
+			continue
+		}
+		code := strings.TrimSpace(newSource.line(lineNumber))
+		if existing[code] > 0 {
+			existing[code]--
+		} else {
+			lineNumbers = append(lineNumbers, lineNumber)
+		}
+	}
+	return
+}
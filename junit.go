@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// junit xml report with one test case per checked file, so jenkins and circleci show coverage failures in their test tabs
+type junitTestSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func formatJunit(result runResult) string {
+	suite := junitSuite{Name: "go-testcov", Cases: []junitTestCase{}}
+	for _, file := range result.files {
+		testCase := junitTestCase{ClassName: "go-testcov", Name: file.Path}
+		if file.Status == "failed" {
+			lines := []string{}
+			for _, finding := range result.findings {
+				if finding.Path == file.Path && finding.Code == codeNewUntestedSection {
+					lines = append(lines, fmt.Sprintf("%v:%v.%v,%v.%v: %v", finding.Path, finding.Line, finding.Column, finding.EndLine, finding.EndColumn, finding.Message))
+				}
+			}
+			message := fmt.Sprintf("%v untested sections but %v configured", file.Untested, file.Configured)
+			testCase.Failure = &junitFailure{Message: message, Text: strings.Join(lines, "\n")}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	// errors without a file have no test case of their own, so each becomes one named after its code
+	for _, finding := range result.findings {
+		if finding.Path == "" && finding.Severity == "error" {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				ClassName: "go-testcov", Name: finding.Code, Failure: &junitFailure{Message: finding.Message},
+			})
+		}
+	}
+
+	suite.Tests = len(suite.Cases)
+	for _, testCase := range suite.Cases {
+		if testCase.Failure != nil {
+			suite.Failures++
+		}
+	}
+	content, err := xml.MarshalIndent(junitTestSuites{Suites: []junitSuite{suite}}, "", "  ")
+	check(err)
+	return xml.Header + string(content) + "\n"
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lcov tracefile with the hits of every line, so genhtml, coveralls or editor gutters can show go coverage without converting it
+// https://manpages.debian.org/unstable/lcov/geninfo.1.en.html#TRACEFILE_FORMAT
+func formatLcov(result runResult) string {
+	paths := []string{}
+	for path := range result.lines {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var content strings.Builder
+	for _, path := range paths {
+		lineNumbers := []int{}
+		for lineNumber := range result.lines[path] {
+			lineNumbers = append(lineNumbers, lineNumber)
+		}
+		sort.Ints(lineNumbers)
+
+		content.WriteString("TN:\nSF:" + path + "\n")
+		hit := 0
+		for _, lineNumber := range lineNumbers {
+			hits := result.lines[path][lineNumber]
+			if hits > 0 {
+				hit++
+			}
+			content.WriteString(fmt.Sprintf("DA:%v,%v\n", lineNumber, hits))
+		}
+		content.WriteString(fmt.Sprintf("LF:%v\nLH:%v\nend_of_record\n", len(lineNumbers), hit))
+	}
+	return content.String()
+}
+
+// hits of each line by path from an lcov tracefile written by formatLcov
+func parseLcov(content string) (lines map[string]map[int]int) {
+	lines = map[string]map[int]int{}
+	path := ""
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "SF:") {
+			path = line[3:]
+			lines[path] = map[int]int{}
+		} else if strings.HasPrefix(line, "DA:") {
+			parts := strings.SplitN(line[3:], ",", 2)
+			lineNumber, err := strconv.Atoi(parts[0])
+			check(err)
+			hits, err := strconv.Atoi(parts[1])
+			check(err)
+			lines[path][lineNumber] = hits
+		}
+	}
+	return
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// find "untested section" comments that can never match a coverage block, so users learn why their ignore is not working
+func lintInlineIgnores(path string) (problems []string) {
+	src := []byte(readFile(path))
+	lines := strings.Split(string(src), "\n")
+	fileSet := token.NewFileSet()
+	file := fileSet.AddFile(path, -1, len(src))
+
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	for {
+		pos, tok, literal := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.STRING {
+			literal = literal[1 : len(literal)-1] // remove quotes so the comment can end the string
+		}
+		directive, found, _ := parseDirective(literal)
+		if !found || directive.budget {
+			continue
+		}
+		position := fileSet.Position(pos)
+		switch {
+		case tok == token.STRING:
+			problems = append(problems, lintProblem(position, "is inside a string literal"))
+		case tok != token.COMMENT:
+			continue
+		case strings.HasSuffix(path, "_test.go"):
+			problems = append(problems, lintProblem(position, "is in a _test.go file, which never has coverage"))
+		case directive.scope != "begin" && directive.scope != "end" && isBraceOnly(lines[position.Line-1][:position.Column-1]):
+			problems = append(problems, lintProblem(position, "trails a line with only a brace, put it inside the block it should ignore"))
+		}
+	}
+	return
+}
+
+func lintProblem(position token.Position, problem string) string {
+	return fmt.Sprintf("%v:%v: untested section comment %v", position.Line, position.Column, problem)
+}
+
+func isBraceOnly(code string) bool {
+	code = strings.TrimSpace(code)
+	return code == "{" || code == "}"
+}
+
+// lint all go files in the given directories, including tests since that is where misplaced comments often end up
+// directories maps the path to display to the path to read
+func printInlineIgnoreProblems(directories map[string]string) {
+	displayDirectories := []string{}
+	for displayDirectory := range directories {
+		displayDirectories = append(displayDirectories, displayDirectory)
+	}
+	sort.Strings(displayDirectories)
+
+	for _, displayDirectory := range displayDirectories {
+		readDirectory := directories[displayDirectory]
+		files, err := ioutil.ReadDir(readDirectory)
+		check(err)
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(file.Name(), ".go") {
+				continue
+			}
+			for _, problem := range lintInlineIgnores(filepath.Join(readDirectory, file.Name())) {
+				_, _ = fmt.Fprintf(os.Stderr, "%v:%v\n", filepath.Join(displayDirectory, file.Name()), problem)
+			}
+		}
+	}
+}
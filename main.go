@@ -1,128 +1,613 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
 )
 
 // reused regex
-var inlineIgnore = "//.*untested section(\\s|,|$)"
-var anyInlineIgnore = regexp.MustCompile(inlineIgnore)
-var startsWithInlineIgnore = regexp.MustCompile("^\\s*" + inlineIgnore)
-var perFileIgnore = regexp.MustCompile("// *untested sections: *([0-9]+)")
 var generatedFile = regexp.MustCompile("/*generated.*\\.go$")
 
 // test injection point to enable test coverage of exit behavior
 var exitFunction func(code int) = os.Exit
 
-// delegate to runGoTestAndCheckCoverage, so we have an easy to test method
+// delegate to run, so we have an easy to test method
 func main() {
 	argv := os.Args[1:len(os.Args)] // remove executable name
-	exitFunction(runGoTestAndCheckCoverage(argv))
+	exitFunction(run(argv))
+}
+
+// run a sub-command or go test when none was given
+func run(argv []string) (exitCode int) {
+	if len(argv) > 0 && argv[0] == "compare-refs" {
+		return runCompareRefs(argv[1:])
+	}
+	if len(argv) > 0 && argv[0] == "badge" {
+		return runBadge(argv[1:])
+	}
+	if len(argv) > 0 && argv[0] == "run" {
+		return runBatch(argv[1:])
+	}
+	if len(argv) > 0 && argv[0] == "export" {
+		return runExport(argv[1:])
+	}
+	if len(argv) > 0 && argv[0] == "cache" {
+		return runCache(argv[1:])
+	}
+	if len(argv) > 0 && argv[0] == "selftest" {
+		return runSelftest(argv[1:])
+	}
+	if len(argv) > 0 && argv[0] == "report" {
+		return runReport(argv[1:])
+	}
+	if len(argv) > 0 && argv[0] == "baseline" {
+		return runBaseline(argv[1:])
+	}
+	return runGoTestAndCheckCoverage(argv)
 }
 
 // run go test with given arguments + coverage and inspect coverage after run
 func runGoTestAndCheckCoverage(argv []string) (exitCode int) {
-	coveragePath := "coverage.out"
+	opts, argv, err := parseOptions(argv)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer useOptions(opts)()
+	if opts.events != "" {
+		defer openEventStream(opts.events)()
+	}
+	emitEvent(event{Event: "start", Args: argv})
+	result := goTestAndCheckCoverage(argv, opts)
+	if opts.verbose {
+		printTimings(result.phases)
+	}
+	writeReports(result, opts)
+	if containsString(argv, "-json") && result.coverage >= 0 {
+		fmt.Print(coverageTestEvents(result, opts.precision)) // go test already reported its own failure
+	}
+	emitResultEvents(result)
+	_, _ = fmt.Fprintln(os.Stderr, summaryLine(result, opts.precision))
+	return result.exitCode
+}
+
+// run go test in the current directory and check its coverage
+func goTestAndCheckCoverage(argv []string, opts options) (result runResult) {
+	result.precision = opts.precision
+	argv = withHelperPackages(argv, opts.helpers)
+	coveragePath, cleanup := coverageProfilePath(opts)
 	_ = os.Remove(coveragePath) // remove file if it exists, to avoid confusion when test run fails
 
 	// allow users to keep the coverage.out file when they passed -cover manually
 	// TODO: parse options to find the location the user wanted and use+keep that
-	if !containsString(argv, "-cover") {
-		defer os.Remove(coveragePath)
+	if !containsString(argv, "-cover") || opts.readOnly {
+		defer cleanup()
+	}
+
+	start := timeNow()
+	runTests := func() int {
+		if len(opts.modes) > 0 {
+			return runGoTestInModes(argv, opts, coveragePath)
+		}
+		return runGoTest(append(append([]string{"test"}, argv...), "-coverprofile", coveragePath), opts)
+	}
+	if opts.subprocessCoverage {
+		result.exitCode = withSubprocessCoverage(coveragePath, runTests)
+	} else {
+		result.exitCode = runTests()
+	}
+	result.phases.measure("go test", start)
+
+	if result.exitCode != 0 {
+		result.findings, result.files, result.skipped, result.coverage = []finding{}, []fileResult{}, []skippedFile{}, -1
+		return
+	}
+	result.exitCode, result.findings, result.files, result.skipped = checkCoverage(coveragePath, opts, &result.phases)
+	result.statements, result.covered = statementCoverage(coveragePath, opts)
+	result.coverage = coveragePercent(result.statements, result.covered)
+	if needsLines(opts.reports) {
+		wd, err := os.Getwd()
+		check(err)
+		result.lines = lineHits(coveragePath, wd, opts)
+	}
+	return
+}
+
+// where go test writes the coverage profile, a temporary directory with --read-only so nothing is written into the repo
+func coverageProfilePath(opts options) (path string, cleanup func()) {
+	if !opts.readOnly {
+		return "coverage.out", func() { _ = os.Remove("coverage.out") }
+	}
+	directory, err := ioutil.TempDir("", "go-testcov-profile")
+	check(err)
+	return filepath.Join(directory, "coverage.out"), func() { _ = os.RemoveAll(directory) }
+}
+
+// run go test once per covermode and merge the profiles, for teams that also need -race runs which require atomic mode
+// merged blocks are untested only when no run covered them, the same as for -coverpkg
+func runGoTestInModes(argv []string, opts options, coveragePath string) (exitCode int) {
+	merged, err := os.Create(coveragePath)
+	check(err)
+	defer merged.Close()
+	writer := bufio.NewWriter(merged)
+
+	for i, mode := range opts.modes {
+		modePath := filepath.Join(filepath.Dir(coveragePath), fmt.Sprintf("coverage.%v.out", mode))
+		defer os.Remove(modePath)
+
+		_, _ = fmt.Fprintf(os.Stderr, "go test -covermode=%v\n", mode)
+		modeArgv := append(append([]string{"test"}, argv...), "-covermode", mode, "-coverprofile", modePath)
+		if exitCode = runGoTest(modeArgv, opts); exitCode != 0 {
+			return exitCode
+		}
+
+		if i == 0 {
+			_, err = fmt.Fprintf(writer, "mode: %v\n", mode) // keep the mode line of the first profile
+			check(err)
+		}
+		eachProfileLine(modePath, func(line string) {
+			_, err = fmt.Fprintln(writer, line)
+			check(err)
+		})
 	}
+	check(writer.Flush())
+	return 0
+}
 
-	argv = append([]string{"test"}, argv...)
-	argv = append(argv, "-coverprofile", coveragePath)
-	exitCode = runCommand("go", argv...)
+// helper packages have no tests of their own, adding them to -coverpkg lets the tests of the other packages cover them,
+// the packages under test are kept in -coverpkg so they are still checked
+func withHelperPackages(argv []string, helpers []string) []string {
+	if len(helpers) == 0 {
+		return argv
+	}
+	result := append([]string{}, argv...)
+	for i, arg := range result {
+		if strings.HasPrefix(arg, "-coverpkg=") {
+			result[i] = arg + "," + strings.Join(helpers, ",")
+			return result
+		}
+		if arg == "-coverpkg" && i+1 < len(result) {
+			result[i+1] += "," + strings.Join(helpers, ",")
+			return result
+		}
+	}
 
-	if exitCode != 0 {
-		return exitCode
+	packages := []string{}
+	for _, arg := range argv {
+		if arg == "." || strings.HasPrefix(arg, "./") || strings.HasPrefix(arg, "../") {
+			packages = append(packages, arg)
+		}
+	}
+	if len(packages) == 0 {
+		packages = []string{"."} // go test tests the current package without packages
 	}
-	return checkCoverage(coveragePath)
+	return append([]string{"-coverpkg=" + strings.Join(append(packages, helpers...), ",")}, result...)
 }
 
 // check coverage for each path that has coverage
-func checkCoverage(coverageFilePath string) (exitCode int) {
+// records how long each phase took in phases
+func checkCoverage(coverageFilePath string, opts options, phases *timings) (exitCode int, findings []finding, files []fileResult, skipped []skippedFile) {
 	exitCode = 0
-	untestedSections := untestedSections(coverageFilePath)
+	findings = []finding{}
+	files = []fileResult{}
+	skipped = []skippedFile{}
+	start := timeNow()
+	untestedSections, malformed := untestedSections(coverageFilePath)
 	sectionsByPath := groupSectionsByPath(untestedSections)
+	phases.measure("profile parsing", start)
+
+	// a broken profile could hide untested sections, so it fails instead of passing with what could be read,
+	// unless the line is about a file that is not checked anyway like a generated one
+	for _, line := range malformed {
+		if skipReason(strings.SplitN(line, ":", 2)[0], opts) != "" {
+			continue
+		}
+		problem := fmt.Sprintf("malformed coverage profile line %q", line)
+		_, _ = fmt.Fprintln(os.Stderr, problem)
+		findings = append(findings, finding{Severity: "error", Code: codeProfileParseError, Message: problem})
+		exitCode = 1
+	}
 
 	wd, err := os.Getwd()
 	check(err)
 
+	// in diff mode only files that changed since the given revision are checked
+	var changed map[string]bool
+	var vcs versionControl
+	if opts.diff != "" {
+		var found bool
+		vcs, found = detectVersionControl(opts.vcs)
+		if !found {
+			_, _ = fmt.Fprintln(os.Stderr, "Could not find a git or hg repository for --diff, use --vcs to select one")
+			return 2, findings, files, skipped
+		}
+		changed = changedFilesSince(opts.diff, vcs, wd)
+	}
+
+	lintDirectories := map[string]string{}
+	criticalPackages := map[string]bool{}        // by directory, to only read each package once
+	packageBudgets := map[string]packageBudget{} // by directory, to only read each package once
+	pooledFiles := map[string][]pooledFile{}     // files that share their package budget, by directory
+	staleFiles := map[string]string{}            // display paths of files with less untested sections than configured, by covered path
+	usedOverrides := map[string]bool{}
+	unbudgetedFiles := map[string]unbudgetedFile{} // failing files without a budget comment, by covered path
+	errorPaths := []untestedErrorPath{}
+	warnings := newWarningCollector(opts.warnings)
+
+	// print untested sections above the budget and record them as findings, returns the status of the file
+	reportUntested := func(displayPath string, readPath string, sections []Section, source sourceFile, details string, critical bool) (status string) {
+		printUntestedSections(sections, displayPath, details, source, opts.splitLines)
+		severity, status, suppression := "error", "failed", ""
+		if until, experimental := experimentalUntil(readPath, source, opts); experimental && !critical {
+			_, _ = fmt.Fprintf(os.Stderr, "%v is experimental (%v), not failing until %v\n", displayPath, experimentalMarker, until.UTC().Format("2006-01-02"))
+			severity, status, suppression = "warning", "warning", "experimental until "+until.UTC().Format("2006-01-02")
+		} else {
+			exitCode = 1 // at least 1 failure, so say to add more tests
+		}
+		for _, section := range sections {
+			function, category := section.function(source), section.category(source)
+			if !opts.splitLines {
+				findings = append(findings, finding{
+					Section: Section{
+						Path: readPath, Line: section.Line, Column: section.Column, EndLine: section.EndLine, EndColumn: section.EndColumn,
+						Statements: section.Statements, Function: function, Category: category, Suppression: suppression,
+					},
+					Severity: severity, Code: codeNewUntestedSection, Message: "new untested section introduced " + details,
+				})
+				continue
+			}
+			for _, lineNumber := range section.codeLines(source) {
+				findings = append(findings, finding{
+					Section:  Section{Path: readPath, Line: lineNumber, Column: 1, Function: function, Category: category, Suppression: suppression},
+					Severity: severity, Code: codeNewUntestedSection, Message: "untested line of section " + section.Location() + " " + details,
+				})
+			}
+		}
+		return status
+	}
+
+	start = timeNow()
 	iterateBySortedKey(sectionsByPath, func(path string, sections []Section) {
-		// skip generated files since their coverage does not matter and would often have gaps
-		if generatedFile.MatchString(path) {
+		displayPath, readPath := normalizeCoveredPath(path, wd)
+		reason := skipReason(path, opts)
+		if reason == "" && changed != nil && !changed[absolutePath(readPath, wd)] {
+			reason = "not changed since " + opts.diff
+		}
+		if reason != "" {
+			skipped = append(skipped, skippedFile{Path: readPath, Reason: reason})
+			if opts.verbose {
+				_, _ = fmt.Fprintf(os.Stderr, "%v skipped: %v\n", displayPath, reason)
+			}
 			return
 		}
 
-		displayPath, readPath := normalizeCoveredPath(path, wd)
-		configuredUntested, configuredUntestedAtLine := configuredUntestedForFile(readPath)
-		lines := strings.Split(readFile(readPath), "\n")
-		sections = removeSectionsMarkedWithInlineComment(sections, lines)
-		actualUntested := len(sections)
+		lintDirectories[filepath.Dir(displayPath)] = filepath.Dir(readPath)
+
+		source := parseSourceFile(readPath, readFile(readPath))
+		if printDirectiveErrors(source, displayPath) {
+			exitCode = 1
+		}
+		for lineNumber, err := range source.directiveErrors {
+			findings = append(findings, finding{Section: Section{Path: readPath, Line: lineNumber, Column: 1}, Severity: "error", Code: codeInvalidDirective, Message: "invalid directive: " + err.Error()})
+		}
+		configuredUntested, configuredUntestedAtLine := source.configuredUntested()
+		allSections := sections
+		sections = removeSectionsMarkedWithInlineComment(sections, source)
+		sections, staleFunctionBudgets := applyFunctionBudgets(sections, source, displayPath, opts.functionBudgets)
+		actualUntested := untestedCount(sections, opts.countStatements)
 		details := fmt.Sprintf("(%v current vs %v configured)", actualUntested, configuredUntested)
 
-		if actualUntested == configuredUntested {
-			// exactly as much as we expected, nothing to do
+		// critical code like security or money handling needs full coverage, so nothing can be ignored
+		directory := filepath.Dir(readPath)
+		if _, ok := criticalPackages[directory]; !ok {
+			criticalPackages[directory] = isCriticalPackage(directory)
+		}
+		critical := source.critical != "" || criticalPackages[directory]
+		scope := "file"
+		if critical {
+			sections, actualUntested, configuredUntested = allSections, untestedCount(allSections, opts.countStatements), 0
+			details = fmt.Sprintf("(%v current vs 0 allowed by %v)", actualUntested, criticalMarker)
+			scope = "critical"
+		}
+
+		// budgets do not hide error paths, only inline comments do
+		if opts.errorPaths {
+			remaining := allSections
+			if !critical {
+				remaining = removeSectionsMarkedWithInlineComment(allSections, source)
+			}
+			errorPaths = append(errorPaths, findUntestedErrorPaths(displayPath, readPath, remaining, source)...)
+		}
+
+		// one-off budgets for emergencies are logged loudly so they are not forgotten
+		override, overridden := opts.overrides[filepath.Clean(displayPath)]
+		if overridden {
+			usedOverrides[filepath.Clean(displayPath)] = true
+			message := fmt.Sprintf("allows %v untested sections instead of %v because of --override, remove it after the emergency", override, configuredUntested)
+			_, _ = fmt.Fprintf(os.Stderr, "OVERRIDE: %v %v\n", displayPath, message)
+			findings = append(findings, finding{Section: Section{Path: readPath, Line: 1, Column: 1}, Severity: "warning", Code: codeBudgetOverride, Message: message})
+			configuredUntested = override
+			details = fmt.Sprintf("(%v current vs %v allowed by --override)", actualUntested, override)
+			scope = "override"
+		}
+
+		if !critical {
+			for _, budget := range sortedFunctionBudgets(staleFunctionBudgets) {
+				functionDetails := fmt.Sprintf("(%v current vs %v configured)", staleFunctionBudgets[budget], budget.count)
+				warnings.warn(displayPath, fmt.Sprintf(
+					"%v#%v has less untested sections %v, decrement configured untested?\nconfigured on: %v:%v\n",
+					displayPath, budget.function, functionDetails, budget.file, budget.line))
+				findings = append(findings, finding{
+					Section:  Section{Path: budget.file, Line: budget.line, Column: 1},
+					Severity: "warning", Code: codeStaleBudget, Message: budget.function + " has less untested sections " + functionDetails + ", decrement configured untested?",
+				})
+			}
+		}
+
+		// files without a budget of their own share the budget of their package, or else of the allowance matching them,
+		// which is checked once all files are known
+		if !critical && !overridden && configuredUntestedAtLine == 0 {
+			if _, ok := packageBudgets[directory]; !ok {
+				packageBudgets[directory] = findPackageBudget(directory)
+			}
+			pool := directory
+			if !packageBudgets[directory].found {
+				var allowance packageBudget
+				if allowance, pool = findPathAllowance(displayPath, opts.allowances); allowance.found {
+					packageBudgets[pool] = allowance
+				}
+			}
+			if pool != "" {
+				pooledFiles[pool] = append(pooledFiles[pool], pooledFile{displayPath, readPath, source, allSections, sections})
+				return
+			}
+		}
+
+		if opts.explainIgnores && !critical {
+			budget := "" // sections only count against the configured untested when they all fit
+			if actualUntested <= configuredUntested && overridden {
+				budget = fmt.Sprintf("--override=%v=%v", displayPath, override)
+			} else if actualUntested <= configuredUntested {
+				budget = fmt.Sprintf("untested sections: %v configured on %v:%v", configuredUntested, readPath, configuredUntestedAtLine)
+			}
+			explainIgnoredSections(allSections, source, displayPath, budget)
+		}
+
+		status := "ok"
+		if actualUntested == configuredUntested || (overridden && actualUntested < configuredUntested) {
+			// exactly as much as we expected or within the override, nothing to do
 		} else if actualUntested > configuredUntested {
-			printUntestedSections(sections, displayPath, details)
-			exitCode = 1 // at least 1 failure, so say to add more tests
+			status = reportUntested(displayPath, readPath, sections, source, details, critical)
+			if scope == "file" && configuredUntestedAtLine == 0 {
+				severity := map[string]string{"failed": "error", "warning": "warning"}[status]
+				unbudgetedFiles[path] = unbudgetedFile{displayPath, readPath, actualUntested, severity}
+			}
 		} else {
-			_, _ = fmt.Fprintf(
-				os.Stderr,
-				"%v has less untested sections %v, decrement configured untested?\nconfigured on: %v:%v",
-				displayPath, details, readPath, configuredUntestedAtLine)
+			status = "stale"
+			warnings.warn(displayPath, fmt.Sprintf(
+				"%v has less untested sections %v, decrement configured untested?\nconfigured on: %v:%v\n",
+				displayPath, details, readPath, configuredUntestedAtLine))
+			staleFiles[path] = displayPath
+			findings = append(findings, finding{
+				Section:  Section{Path: readPath, Line: configuredUntestedAtLine, Column: 1},
+				Severity: "warning", Code: codeStaleBudget, Message: "less untested sections " + details + ", decrement configured untested?",
+			})
 		}
+		files = append(files, fileResult{Path: readPath, Untested: actualUntested, Configured: configuredUntested, Scope: scope, Status: status})
 	})
 
-	return exitCode
+	directories := []string{}
+	for directory := range pooledFiles {
+		directories = append(directories, directory)
+	}
+	sort.Strings(directories)
+	for _, directory := range directories {
+		budget, pool := packageBudgets[directory], pooledFiles[directory]
+		actualUntested := 0
+		for _, file := range pool {
+			actualUntested += untestedCount(file.sections, opts.countStatements)
+		}
+		details := fmt.Sprintf("(%v current vs %v configured for the package)", actualUntested, budget.count)
+		configuredOn, scope := fmt.Sprintf("%v:%v", budget.path, budget.line), "package"
+		if budget.glob != "" {
+			details = fmt.Sprintf("(%v current vs %v allowed for %v)", actualUntested, budget.count, budget.glob)
+			configuredOn, scope = fmt.Sprintf("--allowance=%v=%v", budget.glob, budget.count), "allowance"
+		}
+		stale := actualUntested < budget.count && changed == nil // in diff mode unchanged files are not counted
+
+		for _, file := range pool {
+			if opts.explainIgnores {
+				explanation := "" // sections only count against the configured untested when they all fit
+				if actualUntested <= budget.count {
+					explanation = fmt.Sprintf("untested sections (%v): %v configured on %v", scope, budget.count, configuredOn)
+				}
+				explainIgnoredSections(file.allSections, file.source, file.displayPath, explanation)
+			}
+			status := "ok"
+			if actualUntested > budget.count && len(file.sections) > 0 {
+				status = reportUntested(file.displayPath, file.readPath, file.sections, file.source, details, false)
+			} else if stale {
+				status = "stale"
+			}
+			files = append(files, fileResult{Path: file.readPath, Untested: untestedCount(file.sections, opts.countStatements), Configured: budget.count, Scope: scope, Status: status})
+		}
+
+		if stale && budget.glob != "" {
+			warnings.warn(budget.glob, fmt.Sprintf(
+				"files matching %v have less untested sections %v, decrement the allowance?\nconfigured on: %v\n",
+				budget.glob, details, configuredOn))
+			findings = append(findings, finding{
+				Severity: "warning", Code: codeStaleBudget, Message: "less untested sections " + details + ", decrement the allowance?",
+			})
+		} else if stale {
+			displayDirectory := filepath.Dir(pool[0].displayPath)
+			warnings.warn(displayDirectory, fmt.Sprintf(
+				"package %v has less untested sections %v, decrement configured untested?\nconfigured on: %v\n",
+				displayDirectory, details, configuredOn))
+			findings = append(findings, finding{
+				Section:  Section{Path: budget.path, Line: budget.line, Column: 1},
+				Severity: "warning", Code: codeStaleBudget, Message: "less untested sections " + details + ", decrement configured untested?",
+			})
+		}
+	}
+
+	overridePaths := []string{}
+	for path := range opts.overrides {
+		overridePaths = append(overridePaths, path)
+	}
+	sort.Strings(overridePaths)
+	for _, path := range overridePaths {
+		if !usedOverrides[path] {
+			warnings.warn(path, fmt.Sprintf("--override=%v=%v is not needed, %v has no untested sections or was not checked\n", path, opts.overrides[path], path))
+		}
+	}
+
+	if opts.errorPaths {
+		errorPathFindings, failed := reportUntestedErrorPaths(errorPaths, opts.maxUntestedErrorPaths)
+		if failed {
+			exitCode = 1
+		}
+		findings = append(findings, errorPathFindings...)
+	}
+
+	phases.measure("source scanning", start)
+
+	var inflation []finding
+	if opts.forbidBudgetIncrease { // also finds new ignores
+		inflation = checkBudgetIncrease(opts.diff, vcs, vcs.changedFiles(opts.diff), opts.allowBudgetIncrease, opts.ignoreAllowlist)
+	} else if opts.forbidNewIgnores {
+		inflation = checkNewIgnores(opts.diff, vcs, vcs.changedFiles(opts.diff), opts.allowNewIgnores, opts.ignoreAllowlist)
+	}
+	for _, finding := range inflation {
+		if finding.Severity == "error" {
+			exitCode = 1
+		}
+		findings = append(findings, finding)
+	}
+
+	start = timeNow()
+	if opts.lintIgnores {
+		printInlineIgnoreProblems(lintDirectories)
+	}
+
+	if changed != nil && opts.baseline != "" {
+		baseline, cleanup, found, err := resolveBaseline(opts.baseline, opts.diff, vcs)
+		defer cleanup()
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			return 2, findings, files, skipped
+		}
+		if found {
+			opts.baseline = baseline
+			printCoverageDelta(coverageFilePath, changed, wd, opts)
+			findings = append(findings, newFilesWithoutTests(baseline, unbudgetedFiles, opts)...)
+			if opts.showResolved {
+				printResolvedSections(baseline, sectionsByPath, staleFiles)
+			}
+		} else {
+			_, _ = fmt.Fprintf(
+				os.Stderr, "no baseline coverage found for %v or its last %v ancestors, not showing coverage changes\n",
+				opts.diff, baselineAncestorLimit)
+		}
+	}
+
+	// percentage gate catches slow erosion that stays within the section budgets, so it is reported separately
+	if opts.minCoverage > 0 || opts.target != nil {
+		if problem := checkMinCoverage(coverageFilePath, opts); problem != "" {
+			exitCode = 1
+			findings = append(findings, finding{Severity: "error", Code: codeLowTotalCoverage, Message: problem})
+		}
+	}
+	if opts.minFileCoverage > 0 || len(opts.fileCoverageThresholds) > 0 {
+		fileFindings := checkFileCoverage(coverageFilePath, changed, wd, opts)
+		if len(fileFindings) > 0 {
+			exitCode = 1
+		}
+		findings = append(findings, fileFindings...)
+	}
+	for _, gate := range opts.dependencyGates {
+		for _, finding := range checkDependencyCoverage(coverageFilePath, gate, opts) {
+			exitCode = 1
+			findings = append(findings, finding)
+		}
+	}
+	warnings.print()
+	phases.measure("reporting", start)
+
+	return exitCode, findings, files, skipped
+}
+
+// a file whose untested sections count against the budget of its package
+type pooledFile struct {
+	displayPath string
+	readPath    string
+	source      sourceFile
+	allSections []Section // including the ones ignored with inline comments
+	sections    []Section
 }
 
-func printUntestedSections(sections []Section, displayPath string, details string) {
+// skip excluded and generated files since their coverage does not matter and would often have gaps,
+// unless users maintain generated files by hand and force checking them
+// returns why the path is skipped so silent exclusions can be audited, or "" when it is checked
+func skipReason(path string, opts options) string {
+	for _, glob := range opts.exclude {
+		if matchesPathGlob(path, glob) {
+			return "matches --exclude " + glob
+		}
+	}
+	if len(opts.files) > 0 && !matchesAnyPathGlob(path, opts.files) {
+		return "not in --files"
+	}
+	if matchesAnyPathGlob(path, opts.forceCheck) {
+		return ""
+	}
+	for _, pattern := range append([]*regexp.Regexp{generatedFile}, opts.generated...) {
+		if pattern.MatchString(path) {
+			return "matches generated file pattern " + pattern.String()
+		}
+	}
+	if hasGeneratedHeader(path) {
+		return "has a generated code header"
+	}
+	return ""
+}
+
+// with splitLines each line of code in a section is printed, since ranges like 12.2,47.16 are hard to read
+func printUntestedSections(sections []Section, displayPath string, details string, source sourceFile, splitLines bool) {
 	// TODO: color when tty
 	_, _ = fmt.Fprintf(os.Stderr, "%v new untested sections introduced %v\n", displayPath, details)
 
-	// sort sections since go coverage output is not sorted
-	sort.Slice(sections, func(i, j int) bool {
-		return sections[i].sortValue < sections[j].sortValue
-	})
+	sortSections(sections)
 
 	// print copy-paste friendly snippets
 	for _, section := range sections {
-		_, _ = fmt.Fprintln(os.Stderr, displayPath+":"+section.Location())
+		if !splitLines {
+			_, _ = fmt.Fprintln(os.Stderr, displayPath+":"+section.Location())
+			continue
+		}
+		for _, lineNumber := range section.codeLines(source) {
+			_, _ = fmt.Fprintf(os.Stderr, "%v:%v: %v\n", displayPath, lineNumber, strings.TrimSpace(source.line(lineNumber)))
+		}
 	}
 }
 
-// keep untested sections that are marked with "untested section" comment
-// need to be careful to not change the list while iterating, see https://pauladamsmith.com/blog/2016/07/go-modify-slice-iteration.html
-// NOTE: this is a bit rough as it does not account for partial lines via start/end characters
-// TODO: warn about sections that have a comment but are not uncovered
-func removeSectionsMarkedWithInlineComment(sections []Section, lines []string) []Section {
-	uncheckedSections := sections
-	sections = []Section{}
-	for _, section := range uncheckedSections {
-		for lineNumber := section.startLine; lineNumber <= section.endLine; lineNumber++ {
-			if anyInlineIgnore.MatchString(lines[lineNumber-1]) {
-				break // section is ignored
-			} else if lineNumber >= 2 && startsWithInlineIgnore.MatchString(lines[lineNumber-2]) {
-				break // section is ignored by inline ignore above it
-			} else if lineNumber == section.endLine {
-				sections = append(sections, section) // keep the section
-			}
-		}
-	}
-	return sections
+// sort sections since go coverage output is not sorted
+func sortSections(sections []Section) {
+	sort.Slice(sections, func(i, j int) bool {
+		return sections[i].sortValue < sections[j].sortValue
+	})
 }
 
 func groupSectionsByPath(sections []Section) (grouped map[string][]Section) {
 	grouped = map[string][]Section{}
 	for _, section := range sections {
-		path := section.path
+		path := section.Path
 		group, ok := grouped[path]
 		if !ok {
 			grouped[path] = []Section{}
@@ -133,33 +618,50 @@ func groupSectionsByPath(sections []Section) (grouped map[string][]Section) {
 }
 
 // Find the untested sections given a coverage path
-func untestedSections(coverageFilePath string) (sections []Section) {
+// with -coverpkg every test binary lists the same blocks, a block is only untested when no test binary covered it
+// lines that are not blocks are returned as malformed
+func untestedSections(coverageFilePath string) (sections []Section, malformed []string) {
 	sections = []Section{}
-	content := readFile(coverageFilePath)
 
-	lines := splitWithoutEmpty(content, '\n')
-
-	// remove the initial `set: mode` line
-	if len(lines) == 0 {
-		return
-	}
-	lines = lines[1:]
-
-	// we want lines that end in " 0", they have no coverage
-	for _, line := range lines {
-		if strings.HasSuffix(line, " 0") {
-			sections = append(sections, NewSection(line))
+	// we want blocks that end in " 0" in every line they appear, they have no coverage
+	blocks := []string{}
+	covered := map[string]bool{}
+	eachProfileLine(coverageFilePath, func(line string) {
+		if !profileLinePattern.MatchString(line) {
+			malformed = append(malformed, line)
+			return
+		}
+		block := line[0:strings.LastIndex(line, " ")]
+		if _, seen := covered[block]; !seen {
+			blocks = append(blocks, block)
+		}
+		covered[block] = covered[block] || !strings.HasSuffix(line, " 0")
+	})
+	for _, block := range blocks {
+		if !covered[block] {
+			sections = append(sections, NewSection(block+" 0"))
 		}
 	}
 
 	return
 }
 
+// untested sections of a profile, for comparisons that leave reporting malformed lines to checkCoverage
+func sectionsOf(coverageFilePath string) []Section {
+	sections, _ := untestedSections(coverageFilePath)
+	return sections
+}
+
 // find relative path of file in current directory
 func findFile(path string) (readPath string) {
+	return findFileInDirectory("", path)
+}
+
+// find relative path of file in the given directory, by removing leading directories like the module name until it exists
+func findFileInDirectory(directory string, path string) (readPath string) {
 	parts := strings.Split(path, string(os.PathSeparator))
 	for len(parts) > 0 {
-		_, err := os.Stat(strings.Join(parts, string(os.PathSeparator)))
+		_, err := os.Stat(filepath.Join(directory, strings.Join(parts, string(os.PathSeparator))))
 		if err != nil {
 			parts = parts[1:] // shift directory to continue to look for file
 		} else {
@@ -169,8 +671,30 @@ func findFile(path string) (readPath string) {
 	return strings.Join(parts, string(os.PathSeparator))
 }
 
-// remove path prefix like "github.com/user/lib", but cache the call to os.Get
+// path of a covered file relative to the root of its module like "pkg/a.go", so output is the same on every machine
+// no matter from which directory of the module go-testcov runs, and the path to read it from the working directory
 func normalizeCoveredPath(path string, workingDirectory string) (displayPath string, readPath string) {
+	module := findGoModule(workingDirectory)
+	if module != nil && strings.HasPrefix(path, module.path+"/") {
+		displayPath = strings.TrimPrefix(path, module.path+"/")
+		readPath = filepath.Join(module.root, filepath.FromSlash(displayPath))
+		if relative, err := filepath.Rel(workingDirectory, readPath); err == nil {
+			readPath = relative
+		}
+	} else {
+		displayPath, readPath = guessCoveredPath(path, workingDirectory)
+	}
+
+	if absolutePaths {
+		if absolute, err := filepath.Abs(readPath); err == nil {
+			displayPath = absolute
+		}
+	}
+	return filepath.ToSlash(displayPath), readPath
+}
+
+// remove path prefix like "github.com/user/lib" outside of modules, but cache the call to os.Get
+func guessCoveredPath(path string, workingDirectory string) (displayPath string, readPath string) {
 	modulePrefixSize := 3 // foo.com/bar/baz + file.go
 	separator := string(os.PathSeparator)
 	parts := strings.SplitN(path, separator, modulePrefixSize+1)
@@ -212,13 +736,5 @@ func normalizeCoveredPath(path string, workingDirectory string) (displayPath str
 // How many sections are expected to be untested, 0 if not configured
 // also return at what line we found the comment so we can point the user to it
 func configuredUntestedForFile(path string) (count int, lineNumber int) {
-	content := readFile(path)
-	match := perFileIgnore.FindStringSubmatch(content)
-	if len(match) == 2 {
-		index := perFileIgnore.FindStringIndex(content)[0]
-		linesBeforeMatch := strings.Count(content[0:index], "\n")
-		return stringToInt(match[1]), linesBeforeMatch + 1
-	} else {
-		return 0, 0
-	}
+	return parseSourceFile(path, readFile(path)).configuredUntested()
 }
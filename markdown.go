@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// markdown table of the checked files, for pull request descriptions or the job summary of github actions
+// with --format=markdown:$GITHUB_STEP_SUMMARY
+func formatMarkdown(result runResult) string {
+	verdict := "PASS"
+	if result.exitCode != 0 {
+		verdict = "FAIL"
+	}
+	content := "### go-testcov " + verdict + "\n\n"
+	if result.coverage >= 0 {
+		content += fmt.Sprintf("Coverage: %v\n\n", formatPercent(result.coverage, result.precision))
+	}
+
+	if len(result.files) > 0 {
+		content += "| File | New untested | Untested | Configured | Status |\n| --- | ---: | ---: | ---: | --- |\n"
+		for _, file := range result.files {
+			added := file.Untested - file.Configured
+			if added < 0 {
+				added = 0
+			}
+			content += fmt.Sprintf("| %v | %v | %v | %v | %v |\n", escapeMarkdownCell(file.Path), added, file.Untested, file.Configured, file.Status)
+		}
+		content += "\n"
+	}
+
+	// errors the table can not show since they have no file, listed below it
+	for _, finding := range result.findings {
+		if finding.Path == "" && finding.Severity == "error" {
+			content += fmt.Sprintf("- **%v** %v\n", finding.Code, finding.Message)
+		}
+	}
+	return content
+}
+
+func escapeMarkdownCell(cell string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(cell)
+}
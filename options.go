@@ -0,0 +1,550 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// options that configure go-testcov itself, everything else is passed to go test
+type options struct {
+	diff string // only check files changed since this revision
+	vcs  string // version control system to use for diff, detected when empty
+
+	baseline string // coverage file of the base revision to compare against
+
+	stage      string              // "presubmit" only checks the diff, "postsubmit" checks everything, so ci stages share one command line
+	stageSkips map[string][]string // options each stage turns off by stage, defaultStageSkips when not configured
+
+	forbidBudgetIncrease bool     // fail when budgets grew or ignores were added since the diff revision
+	allowBudgetIncrease  bool     // only warn about them, for PRs that raise budgets on purpose
+	forbidNewIgnores     bool     // fail when ignores were added since the diff revision
+	allowNewIgnores      bool     // only warn about them
+	ignoreAllowlist      []string // globs of files that may add ignores
+
+	forceCheck []string         // globs of files to check even though they look generated
+	generated  []*regexp.Regexp // patterns of generated files in addition to the built in one
+	exclude    []string         // globs of files to never check
+	files      []string         // globs of the only files to check, for pre-commit hooks
+	helpers    []string         // packages like ./testutil/... that are covered by the tests of other packages
+
+	verbose        bool   // print details like skipped files
+	warnings       string // "summary" prints warnings in one block, "full" where they happen, "off" not at all
+	splitLines     bool   // report each line of untested sections
+	showResolved   bool   // list sections that were untested in the baseline when files have less untested sections than configured
+	explainIgnores bool   // print why untested sections were not reported
+	lintIgnores    bool   // warn about untested section comments that can never match
+
+	countStatements bool // count untested statements against budgets instead of untested sections
+
+	functionBudgets []functionBudget // untested sections allowed per function, from --budgets
+	overrides       map[string]int   // untested sections allowed by path for one run, from --override
+	allowances      []pathAllowance  // untested sections allowed for all files matching a glob together
+
+	minCoverage     float64          // fail when total statement coverage percentage is below this
+	target          *coverageTarget  // raise minCoverage a little every day until a goal is reached
+	dependencyGates []dependencyGate // coverage each package in the dependency tree of a package needs
+	precision       int              // decimals of percentages in output and when comparing them to thresholds
+
+	minFileCoverage        float64                 // fail when a file has a lower statement coverage percentage
+	fileCoverageThresholds []fileCoverageThreshold // minFileCoverage for files matching a glob instead
+
+	errorPaths            bool // list error returns in untested sections
+	maxUntestedErrorPaths int  // fail when more error returns than this are untested
+
+	modes []string // run go test once per covermode and merge their coverage
+
+	subprocessCoverage bool // fold the coverage of instrumented binaries tests run into the profile
+
+	readOnly bool // never write into the repo, for read-only checkouts and hermetic build sandboxes
+
+	viaGotestsum bool // run the tests with gotestsum so its output formatting and junit files come from the same run
+
+	goWrapper []string // command that runs go, like "docker run --rm golang" or "bazel run @go_sdk//:bin/go --"
+
+	captureOutput bool // only show go test output when it fails
+	alwaysShow    bool // show captured output also when go test passed
+
+	experimentalDays int // how long files marked with //testcov:experimental only warn
+
+	nolint []string // linter names that make nolint comments ignore untested sections
+
+	ignoreMarkers []string // comment texts that ignore untested sections like "untested section" does, replacing the defaults
+
+	budgetPattern *regexp.Regexp // additional budget comment syntax
+
+	absolutePaths bool // show absolute paths instead of paths relative to the module root
+
+	reports []reportDestination // formats to write findings in and where to write them
+
+	goTestJSON bool // go test -json writes test events to stdout, so reports must not mix into it
+
+	events string // file to stream newline delimited json events to while running
+}
+
+// an option users can pass as `--name=value` or `--name` for flags
+type option struct {
+	name  string
+	flag  bool // does not take a value
+	apply func(opts *options, value string) error
+}
+
+// options each stage turns off: presubmit stays fast by only checking what the change touched,
+// postsubmit checks everything including the thresholds
+var defaultStageSkips = map[string][]string{
+	"presubmit":  {"min-coverage", "target", "deps-min-coverage"},
+	"postsubmit": {"diff", "baseline", "show-resolved", "forbid-budget-increase", "forbid-new-ignores"},
+}
+
+// how to turn off each option a stage can skip, by option name
+var stageResets = map[string]func(opts *options){
+	"min-coverage":           func(opts *options) { opts.minCoverage = 0 },
+	"target":                 func(opts *options) { opts.target = nil },
+	"deps-min-coverage":      func(opts *options) { opts.dependencyGates = nil },
+	"min-file-coverage":      func(opts *options) { opts.minFileCoverage, opts.fileCoverageThresholds = 0, nil },
+	"diff":                   func(opts *options) { opts.diff = "" },
+	"baseline":               func(opts *options) { opts.baseline = "" },
+	"show-resolved":          func(opts *options) { opts.showResolved = false },
+	"forbid-budget-increase": func(opts *options) { opts.forbidBudgetIncrease = false },
+	"forbid-new-ignores":     func(opts *options) { opts.forbidNewIgnores = false },
+}
+
+// configure which options a stage skips instead of its defaults, an empty value skips nothing
+func addStageSkips(opts *options, stage string, value string) error {
+	names := splitWithoutEmpty(value, ',')
+	for _, name := range names {
+		if _, ok := stageResets[name]; !ok {
+			supported := []string{}
+			for name := range stageResets {
+				supported = append(supported, name)
+			}
+			sort.Strings(supported)
+			return fmt.Errorf("%v can not be skipped, supported are %v", name, strings.Join(supported, ", "))
+		}
+	}
+	if opts.stageSkips == nil {
+		opts.stageSkips = map[string][]string{}
+	}
+	opts.stageSkips[stage] = append(append([]string{}, opts.stageSkips[stage]...), names...)
+	return nil
+}
+
+var defaultExperimentalDays = 30
+
+var defaultPrecision = 1
+
+var coverModes = []string{"set", "count", "atomic"}
+
+var availableOptions = []option{
+	{name: "diff", apply: func(opts *options, value string) error {
+		opts.diff = value
+		return nil
+	}},
+	{name: "stage", apply: func(opts *options, value string) error {
+		if value != "presubmit" && value != "postsubmit" {
+			return fmt.Errorf("unknown stage %v, supported are presubmit, postsubmit", value)
+		}
+		opts.stage = value
+		return nil
+	}},
+	{name: "presubmit-skip", apply: func(opts *options, value string) error {
+		return addStageSkips(opts, "presubmit", value)
+	}},
+	{name: "postsubmit-skip", apply: func(opts *options, value string) error {
+		return addStageSkips(opts, "postsubmit", value)
+	}},
+	{name: "vcs", apply: func(opts *options, value string) error {
+		if _, ok := versionControls[value]; !ok {
+			return fmt.Errorf("unknown version control system %v, supported are %v", value, strings.Join(versionControlNames(), ", "))
+		}
+		opts.vcs = value
+		return nil
+	}},
+	{name: "baseline", apply: func(opts *options, value string) error {
+		opts.baseline = value
+		return nil
+	}},
+	{name: "force-check", apply: func(opts *options, value string) error {
+		opts.forceCheck = append(opts.forceCheck, splitWithoutEmpty(value, ',')...)
+		return nil
+	}},
+	{name: "exclude", apply: func(opts *options, value string) error {
+		opts.exclude = append(opts.exclude, splitWithoutEmpty(value, ',')...)
+		return nil
+	}},
+	{name: "files", apply: func(opts *options, value string) error {
+		for _, glob := range splitWithoutEmpty(value, ',') {
+			opts.files = append(opts.files, filepath.ToSlash(filepath.Clean(glob)))
+		}
+		return nil
+	}},
+	{name: "helpers", apply: func(opts *options, value string) error {
+		opts.helpers = append(opts.helpers, splitWithoutEmpty(value, ',')...)
+		return nil
+	}},
+	{name: "warnings", apply: func(opts *options, value string) error {
+		if !containsString(warningModes, value) {
+			return fmt.Errorf("unknown warnings mode %v, supported are %v", value, strings.Join(warningModes, ", "))
+		}
+		opts.warnings = value
+		return nil
+	}},
+	{name: "verbose", flag: true, apply: func(opts *options, value string) error {
+		opts.verbose = true
+		return nil
+	}},
+	{name: "split-lines", flag: true, apply: func(opts *options, value string) error {
+		opts.splitLines = true
+		return nil
+	}},
+	{name: "count-statements", flag: true, apply: func(opts *options, value string) error {
+		opts.countStatements = true
+		return nil
+	}},
+	{name: "absolute-paths", flag: true, apply: func(opts *options, value string) error {
+		opts.absolutePaths = true
+		return nil
+	}},
+	{name: "explain-ignores", flag: true, apply: func(opts *options, value string) error {
+		opts.explainIgnores = true
+		return nil
+	}},
+	{name: "lint-ignores", flag: true, apply: func(opts *options, value string) error {
+		opts.lintIgnores = true
+		return nil
+	}},
+	{name: "min-coverage", apply: func(opts *options, value string) (err error) {
+		opts.minCoverage, err = parsePercent(value)
+		return
+	}},
+	{name: "target", apply: func(opts *options, value string) (err error) {
+		opts.target, err = parseCoverageTarget(value)
+		return err
+	}},
+	{name: "deps-min-coverage", apply: func(opts *options, value string) error {
+		separator := strings.LastIndex(value, "=")
+		if separator == -1 {
+			return fmt.Errorf("invalid dependency coverage %v, expected package=percent", value)
+		}
+		percent, err := parsePercent(value[separator+1:])
+		if err != nil {
+			return err
+		}
+		opts.dependencyGates = append(opts.dependencyGates, dependencyGate{pattern: value[:separator], percent: percent})
+		return nil
+	}},
+	{name: "min-file-coverage", apply: func(opts *options, value string) error {
+		separator := strings.LastIndex(value, "=")
+		percent, err := parsePercent(value[separator+1:])
+		if err != nil {
+			return err
+		}
+		if separator == -1 {
+			opts.minFileCoverage = percent
+		} else {
+			opts.fileCoverageThresholds = append(opts.fileCoverageThresholds, fileCoverageThreshold{glob: value[:separator], percent: percent})
+		}
+		return nil
+	}},
+	{name: "modes", apply: func(opts *options, value string) error {
+		opts.modes = splitWithoutEmpty(value, ',')
+		for _, mode := range opts.modes {
+			if !containsString(coverModes, mode) {
+				return fmt.Errorf("unknown covermode %v, supported are %v", mode, strings.Join(coverModes, ", "))
+			}
+		}
+		return nil
+	}},
+	{name: "read-only", flag: true, apply: func(opts *options, value string) error {
+		opts.readOnly = true
+		return nil
+	}},
+	{name: "via-gotestsum", flag: true, apply: func(opts *options, value string) error {
+		opts.viaGotestsum = true
+		return nil
+	}},
+	{name: "go-wrapper", apply: func(opts *options, value string) error {
+		opts.goWrapper = strings.Fields(value)
+		if len(opts.goWrapper) == 0 {
+			return fmt.Errorf("--go-wrapper needs a command to run go with")
+		}
+		return nil
+	}},
+	{name: "capture-output", flag: true, apply: func(opts *options, value string) error {
+		opts.captureOutput = true
+		return nil
+	}},
+	{name: "always-show", flag: true, apply: func(opts *options, value string) error {
+		opts.alwaysShow = true
+		return nil
+	}},
+	{name: "experimental-days", apply: func(opts *options, value string) (err error) {
+		opts.experimentalDays, err = strconv.Atoi(value)
+		if err != nil || opts.experimentalDays < 0 {
+			return fmt.Errorf("invalid number of days %v", value)
+		}
+		return nil
+	}},
+	{name: "budgets", apply: func(opts *options, value string) (err error) {
+		opts.functionBudgets, err = parseFunctionBudgets(value)
+		return err
+	}},
+	{name: "forbid-budget-increase", flag: true, apply: func(opts *options, value string) error {
+		opts.forbidBudgetIncrease = true
+		return nil
+	}},
+	{name: "allow-budget-increase", flag: true, apply: func(opts *options, value string) error {
+		opts.allowBudgetIncrease = true
+		return nil
+	}},
+	{name: "forbid-new-ignores", flag: true, apply: func(opts *options, value string) error {
+		opts.forbidNewIgnores = true
+		return nil
+	}},
+	{name: "allow-new-ignores", flag: true, apply: func(opts *options, value string) error {
+		opts.allowNewIgnores = true
+		return nil
+	}},
+	{name: "ignore-allowlist", apply: func(opts *options, value string) (err error) {
+		opts.ignoreAllowlist, err = parseIgnoreAllowlist(value)
+		return err
+	}},
+	{name: "show-resolved", flag: true, apply: func(opts *options, value string) error {
+		opts.showResolved = true
+		return nil
+	}},
+	{name: "allowance", apply: func(opts *options, value string) error {
+		allowance, err := parsePathAllowance(value)
+		opts.allowances = append(opts.allowances, allowance)
+		return err
+	}},
+	{name: "override", apply: func(opts *options, value string) error {
+		separator := strings.LastIndex(value, "=")
+		if separator == -1 {
+			return fmt.Errorf("invalid override %v, expected path=count", value)
+		}
+		count, err := strconv.Atoi(value[separator+1:])
+		if err != nil || count < 0 {
+			return fmt.Errorf("invalid override %v, expected path=count", value)
+		}
+		if opts.overrides == nil {
+			opts.overrides = map[string]int{}
+		}
+		opts.overrides[filepath.Clean(value[:separator])] = count
+		return nil
+	}},
+	{name: "max-untested-error-paths", apply: func(opts *options, value string) (err error) {
+		opts.maxUntestedErrorPaths, err = strconv.Atoi(value)
+		if err != nil || opts.maxUntestedErrorPaths < 0 {
+			return fmt.Errorf("invalid max-untested-error-paths %v, expected a number", value)
+		}
+		opts.errorPaths = true
+		return nil
+	}},
+	{name: "precision", apply: func(opts *options, value string) (err error) {
+		opts.precision, err = strconv.Atoi(value)
+		if err != nil || opts.precision < 0 || opts.precision > 10 {
+			return fmt.Errorf("invalid precision %v, expected a number of decimals between 0 and 10", value)
+		}
+		return nil
+	}},
+	{name: "ignore-marker", apply: func(opts *options, value string) error {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("invalid ignore marker, expected the comment text like coverage:ignore")
+		}
+		opts.ignoreMarkers = append(opts.ignoreMarkers, strings.TrimSpace(value))
+		return nil
+	}},
+	{name: "nolint", apply: func(opts *options, value string) error {
+		opts.nolint = splitWithoutEmpty(value, ',')
+		return nil
+	}},
+	{name: "generated", apply: func(opts *options, value string) error {
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return fmt.Errorf("invalid generated file pattern %v: %v", value, err)
+		}
+		opts.generated = append(opts.generated, pattern)
+		return nil
+	}},
+	{name: "budget-pattern", apply: func(opts *options, value string) (err error) {
+		opts.budgetPattern, err = regexp.Compile(value)
+		if err != nil {
+			return fmt.Errorf("invalid budget pattern %v: %v", value, err)
+		}
+		if opts.budgetPattern.NumSubexp() != 1 {
+			return fmt.Errorf("invalid budget pattern %v: expected one group that captures the count", value)
+		}
+		return nil
+	}},
+	{name: "format", apply: func(opts *options, value string) error {
+		parts := strings.SplitN(value, ":", 2)
+		path := ""
+		if len(parts) == 2 {
+			path = parts[1]
+		}
+		return addReport(opts, parts[0], path)
+	}},
+	{name: "output", apply: func(opts *options, value string) error {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("expected --output=FORMAT=FILE but got --output=%v", value)
+		}
+		return addReport(opts, parts[0], parts[1])
+	}},
+	{name: "ide-report", apply: func(opts *options, value string) error {
+		opts.reports = append(opts.reports, reportDestination{format: "json", path: value})
+		return nil
+	}},
+	{name: "quickfix", apply: func(opts *options, value string) error {
+		opts.reports = append(opts.reports, reportDestination{format: "quickfix", path: value})
+		return nil
+	}},
+	{name: "subprocess-coverage", flag: true, apply: func(opts *options, value string) error {
+		opts.subprocessCoverage = true
+		return nil
+	}},
+	{name: "events", apply: func(opts *options, value string) error {
+		opts.events = value
+		return nil
+	}},
+	{name: "badge", apply: func(opts *options, value string) error {
+		opts.reports = append(opts.reports, reportDestination{format: "badge", path: value})
+		return nil
+	}},
+	{name: "html", apply: func(opts *options, value string) error {
+		opts.reports = append(opts.reports, reportDestination{format: "html", path: value})
+		return nil
+	}},
+}
+
+// write a report in the format to the path, or to stdout when the path is empty
+func addReport(opts *options, format string, path string) error {
+	if _, ok := reportFormats[format]; !ok {
+		return fmt.Errorf("unknown format %v, supported are %v", format, strings.Join(reportFormatNames(), ", "))
+	}
+	opts.reports = append(opts.reports, reportDestination{format: format, path: path})
+	return nil
+}
+
+// split go-testcov options from the arguments that go to go test
+func parseOptions(argv []string) (opts options, rest []string, err error) {
+	opts.experimentalDays = defaultExperimentalDays
+	opts.precision = defaultPrecision
+	opts.warnings = "summary"
+	rest = []string{}
+	configArgs, configGoTestArgs, configPath, err := loadConfig()
+	if err != nil {
+		return
+	}
+	envArgs, envNames, err := environmentArgs()
+	if err != nil {
+		return
+	}
+	for index, arg := range append(append(configArgs, envArgs...), argv...) {
+		option, value, found := findOption(arg)
+		if !found {
+			rest = append(rest, arg)
+			continue
+		}
+		if value, err = interpolateEnv(value); err != nil {
+			err = fmt.Errorf("%v: %v", arg, err)
+		} else {
+			err = option.apply(&opts, value)
+		}
+		switch { // point to where the option came from
+		case err == nil:
+		case index < len(configArgs):
+			return opts, rest, fmt.Errorf("%v: %v", configPath, err)
+		case index < len(configArgs)+len(envArgs):
+			return opts, rest, fmt.Errorf("%v: %v", envNames[index-len(configArgs)], err)
+		default:
+			return opts, rest, err
+		}
+	}
+
+	if len(rest) == 0 && len(configGoTestArgs) > 0 {
+		rest = configGoTestArgs
+	}
+
+	if opts.stage == "presubmit" && opts.diff == "" {
+		return opts, rest, fmt.Errorf("--stage=presubmit needs --diff to know what changed")
+	}
+	if opts.stage != "" {
+		skips, configured := opts.stageSkips[opts.stage]
+		if !configured {
+			skips = defaultStageSkips[opts.stage]
+		}
+		for _, name := range skips {
+			stageResets[name](&opts)
+		}
+	}
+	if opts.forbidBudgetIncrease && opts.diff == "" {
+		return opts, rest, fmt.Errorf("--forbid-budget-increase needs --diff to know what changed")
+	}
+	if opts.forbidNewIgnores && opts.diff == "" {
+		return opts, rest, fmt.Errorf("--forbid-new-ignores needs --diff to know what changed")
+	}
+	opts.goTestJSON = containsString(rest, "-json")
+	return
+}
+
+// directives are parsed and commands run in many places, so their settings are configured globally for one run,
+// restore puts the previous settings back so runs do not leak into each other
+func useOptions(opts options) (restore func()) {
+	oldNolintNames, oldIgnoreMarkers, oldBudgetPattern := nolintNames, ignoreMarkers, budgetPattern
+	oldAbsolutePaths, oldRunner := absolutePaths, runner
+	oldGeneratedHeaders, oldGeneratedHeadersDirectory := generatedHeaders, generatedHeadersDirectory
+	if opts.nolint != nil {
+		nolintNames = opts.nolint
+	}
+	if opts.ignoreMarkers != nil {
+		ignoreMarkers = opts.ignoreMarkers
+	}
+	if opts.budgetPattern != nil {
+		budgetPattern = opts.budgetPattern
+	}
+	absolutePaths = opts.absolutePaths
+	if opts.goWrapper != nil {
+		runner = goWrapperRunner{wrapper: opts.goWrapper, runner: oldRunner}
+	}
+	wd, err := os.Getwd()
+	check(err)
+	generatedHeaders, generatedHeadersDirectory = map[string]bool{}, wd
+	return func() {
+		nolintNames, ignoreMarkers, budgetPattern = oldNolintNames, oldIgnoreMarkers, oldBudgetPattern
+		absolutePaths, runner = oldAbsolutePaths, oldRunner
+		generatedHeaders, generatedHeadersDirectory = oldGeneratedHeaders, oldGeneratedHeadersDirectory
+	}
+}
+
+// "85" or "85.5%" => 85.5, always with a "." so thresholds mean the same in every locale
+func parsePercent(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || !(percent >= 0 && percent <= 100) { // also rejects NaN
+		return 0, fmt.Errorf("invalid percentage %v, expected a number between 0 and 100", value)
+	}
+	return percent, nil
+}
+
+// "--diff=main" => diff option and "main"
+func findOption(arg string) (found option, value string, ok bool) {
+	if !strings.HasPrefix(arg, "--") {
+		return
+	}
+	parts := strings.SplitN(arg[2:], "=", 2)
+	for _, option := range availableOptions {
+		if option.name != parts[0] || option.flag == (len(parts) == 2) {
+			continue
+		}
+		if len(parts) == 2 {
+			value = parts[1]
+		}
+		return option, value, true
+	}
+	return
+}
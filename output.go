@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// run go test, with --capture-output its output is only shown when it fails, to keep successful ci logs short
+// with --via-gotestsum the tests run through gotestsum, which takes the go test arguments after "--" and without "test"
+func runGoTest(argv []string, opts options) (exitCode int) {
+	name := "go"
+	if opts.viaGotestsum {
+		name, argv = "gotestsum", append([]string{"--"}, argv[1:]...)
+	}
+	if !opts.captureOutput {
+		if eventStream == nil {
+			return runCommand(name, argv...)
+		}
+		return runner.run("", nil, io.MultiWriter(os.Stdout, &packageEventWriter{}), os.Stderr, name, argv)
+	}
+
+	exitCode, output := runCommandCapturingOutput(name, argv...)
+	for _, line := range strings.Split(output, "\n") {
+		emitPackageEvent(line)
+	}
+	if exitCode != 0 || opts.alwaysShow {
+		fmt.Print(output)
+	}
+	if exitCode != 0 {
+		if failures := extractTestFailures(output); len(failures) > 0 {
+			_, _ = fmt.Fprintf(os.Stderr, "failed tests:\n%v\n", strings.Join(failures, "\n"))
+		}
+	}
+	return exitCode
+}
+
+// "--- FAIL: TestFoo" lines and the messages logged below them, which are indented deeper
+func extractTestFailures(output string) (failures []string) {
+	failureIndent := -1
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		indent := len(line) - len(trimmed)
+		switch {
+		case strings.HasPrefix(trimmed, "--- FAIL:"):
+			failureIndent = indent
+			failures = append(failures, line)
+		case failureIndent != -1 && indent > failureIndent && trimmed != "" && !strings.HasPrefix(trimmed, "--- ") && !strings.HasPrefix(trimmed, "=== "):
+			failures = append(failures, line)
+		default:
+			failureIndent = -1
+		}
+	}
+	return
+}
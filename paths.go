@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// show absolute paths instead of paths relative to the module root, for tools that can not resolve relative paths
+var absolutePaths = false
+
+// module that contains a directory, profiles list files by module path so this tells where they are on disk
+type goModule struct {
+	path string // like "github.com/user/lib"
+	root string // directory of the go.mod
+}
+
+var goModPathRegexp = regexp.MustCompile(`(?m)^module\s+"?([^"\s]+)"?`)
+
+// modules found by directory, so the go.mod is not read again for every line of a profile
+var goModules = map[string]*goModule{}
+
+// nearest go.mod at or above the directory, nil when there is none
+func findGoModule(directory string) *goModule {
+	if module, found := goModules[directory]; found {
+		return module
+	}
+	var module *goModule
+	for current := directory; ; current = filepath.Dir(current) {
+		content, err := ioutil.ReadFile(filepath.Join(current, "go.mod"))
+		if err == nil {
+			if match := goModPathRegexp.FindStringSubmatch(string(content)); match != nil {
+				module = &goModule{path: match[1], root: current}
+			}
+			break
+		}
+		if !os.IsNotExist(err) || filepath.Dir(current) == current {
+			break
+		}
+	}
+	goModules[directory] = module
+	return module
+}
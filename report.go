@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// a problem go-testcov found at a location in the code
+type finding struct {
+	Section
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// outcome of checking a file that has untested sections, for reports that list every checked file
+type fileResult struct {
+	Path       string `json:"path"`
+	Untested   int    `json:"untested"`   // untested sections that are not ignored
+	Configured int    `json:"configured"` // untested sections the budget allows
+	Scope      string `json:"scope"`      // where the budget comes from: "file", "package", "allowance", "critical" or "override"
+	Status     string `json:"status"`     // "ok", "failed", "warning" when experimental or "stale" when less untested than configured
+}
+
+// file whose untested sections were not checked, so silent exclusions can be audited
+type skippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"` // like "matches --exclude vendor/**" or "not changed since main"
+}
+
+// everything a run found, reports are written from it
+type runResult struct {
+	exitCode   int
+	findings   []finding
+	files      []fileResult
+	skipped    []skippedFile
+	phases     timings
+	coverage   float64                // total statement coverage percentage, -1 when go test failed
+	statements int                    // statements the total coverage is based on
+	covered    int                    // covered statements the total coverage is based on
+	precision  int                    // decimal places of coverage percentages, from --precision
+	lines      map[string]map[int]int // hits of each line with code by path, only for formats that need them like lcov
+}
+
+// stable codes for each kind of finding, so automation can route and deduplicate findings without parsing messages
+// codes are never renamed or reused
+const (
+	codeNewUntestedSection    = "NEW_UNTESTED_SECTION"    // more untested sections than configured
+	codeStaleBudget           = "STALE_BUDGET"            // less untested sections than configured
+	codeInvalidDirective      = "INVALID_DIRECTIVE"       // malformed untested section comment
+	codeLowTotalCoverage      = "LOW_TOTAL_COVERAGE"      // total coverage below --min-coverage or what --target requires today
+	codeBudgetIncrease        = "BUDGET_INCREASE"         // budget raised or ignore added with --forbid-budget-increase
+	codeBudgetOverride        = "BUDGET_OVERRIDE"         // budget replaced with --override
+	codeLowDependencyCoverage = "LOW_DEPENDENCY_COVERAGE" // package in the dependency tree of a --deps-min-coverage package below its coverage
+	codeNewFileWithoutTests   = "NEW_FILE_WITHOUT_TESTS"  // file that is not in the --baseline has untested sections and no budget
+	codeUntestedErrorPath     = "UNTESTED_ERROR_PATH"     // error return in an untested section with --max-untested-error-paths
+	codeLowFileCoverage       = "LOW_FILE_COVERAGE"       // file coverage below --min-file-coverage
+	codeLowPackageCoverage    = "LOW_PACKAGE_COVERAGE"    // package of a --deps-min-coverage gate below its coverage
+	codeProfileParseError     = "PROFILE_PARSE_ERROR"     // line of the coverage profile that is not "path:start,end statements hits"
+)
+
+// report written with --format=json:FILE or --ide-report=FILE so editor plugins can show findings in their problem views, it looks like:
+//
+//	{
+//	  "version": 1,
+//	  "findings": [
+//	    {"path": "pkg/a.go", "line": 3, "column": 2, "endLine": 5, "endColumn": 3, "severity": "error", "code": "NEW_UNTESTED_SECTION", "message": "...",
+//	     "statements": 2, "function": "(*Server).Shutdown"}
+//	  ],
+//	  "files": [
+//	    {"path": "pkg/a.go", "untested": 2, "configured": 1, "scope": "file", "status": "failed"}
+//	  ],
+//	  "skipped": [
+//	    {"path": "pkg/a.pb.go", "reason": "has a generated code header"}
+//	  ],
+//	  "timings": [
+//	    {"phase": "go test", "seconds": 1.5}
+//	  ]
+//	}
+//
+// paths are relative to the directory go-testcov ran in unless the file is outside of it,
+// lines and columns start at 1, endLine and endColumn are 0 when the finding is not a range,
+// severity is "error" when the finding fails the run and "warning" when it does not
+// code is one of the stable codes below, findings about the whole run like LOW_TOTAL_COVERAGE have an empty path and line 0
+// statements and function are only set for NEW_UNTESTED_SECTION and left out when unknown
+// files are the checked files that have untested sections, with the counts they were checked with,
+// for "package" scope configured is the budget of the whole package
+// timings are how long each phase of go-testcov took, in the order they ran
+// the report is written after every run, with no findings when everything passed or tests failed, so plugins can clear old problems
+// version is incremented when fields are removed or change their meaning, new fields can be added without a new version
+type ideReport struct {
+	Version           int           `json:"version"`
+	Coverage          float64       `json:"coverage"`
+	Statements        int           `json:"statements"`
+	CoveredStatements int           `json:"coveredStatements"`
+	Findings          []finding     `json:"findings"`
+	Files             []fileResult  `json:"files"`
+	Skipped           []skippedFile `json:"skipped"`
+	Timings           timings       `json:"timings"`
+}
+
+var ideReportVersion = 1
+
+// formats findings can be written in with --format=NAME:FILE, by name
+var reportFormats = map[string]func(result runResult) string{
+	"azure":            formatAzure,
+	"badge":            formatSvgBadge,
+	"checkstyle":       formatCheckstyle,
+	"json":             formatIdeReport,
+	"github":           formatGithub,
+	"gitlab":           formatGitlab,
+	"html":             formatHtml,
+	"junit":            formatJunit,
+	"lcov":             formatLcov,
+	"markdown":         formatMarkdown,
+	"quickfix":         formatQuickfix,
+	"rdjson":           formatRdjson,
+	"rdjsonl":          formatRdjsonl,
+	"sarif":            formatSarif,
+	"shields":          formatShields,
+	"shields-untested": formatShieldsUntested,
+	"sonarqube":        formatSonarqube,
+	"tap":              formatTap,
+	"teamcity":         formatTeamcity,
+}
+
+func reportFormatNames() (names []string) {
+	for name := range reportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// formats that need the hits of every line, which are expensive to collect for big profiles
+var lineReportFormats = []string{"html", "lcov", "sonarqube"}
+
+// does any report need the hits of every line
+func needsLines(destinations []reportDestination) bool {
+	for _, destination := range destinations {
+		if containsString(lineReportFormats, destination.format) {
+			return true
+		}
+	}
+	return false
+}
+
+// where to write a report, stdout when path is empty or "-" and stderr when it is "/dev/stderr", also on windows
+type reportDestination struct {
+	format string
+	path   string
+}
+
+// does any report go to stdout
+func writesToStdout(destinations []reportDestination) bool {
+	for _, destination := range destinations {
+		if destination.path == "" || destination.path == "-" {
+			return true
+		}
+	}
+	return false
+}
+
+func containsReportFormat(destinations []reportDestination, format string) bool {
+	for _, destination := range destinations {
+		if destination.format == format {
+			return true
+		}
+	}
+	return false
+}
+
+// write the reports users asked for, so one run can produce all formats ci needs
+func writeReports(result runResult, opts options) {
+	sortFindings(result.findings)
+	sort.SliceStable(result.files, func(i, j int) bool { return result.files[i].Path < result.files[j].Path })
+	destinations := opts.reports
+	if runningInGithubActions() && !containsReportFormat(destinations, "github") {
+		// github reads workflow commands from stderr too, so annotations do not break reports or go test -json events on stdout
+		destination := reportDestination{format: "github"}
+		if opts.goTestJSON || writesToStdout(destinations) {
+			destination.path = "/dev/stderr"
+		}
+		destinations = append(destinations, destination)
+	}
+	for _, destination := range destinations {
+		content := reportFormats[destination.format](result)
+		if destination.path == "/dev/stderr" {
+			_, _ = fmt.Fprint(os.Stderr, content)
+		} else if destination.path == "" || destination.path == "-" {
+			fmt.Print(content)
+		} else {
+			check(ioutil.WriteFile(destination.path, []byte(content), 0644))
+		}
+	}
+}
+
+// single line verdict printed last, so ci log scrapers and humans skimming logs get the outcome without reading the report
+func summaryLine(result runResult, precision int) string {
+	verdict := "PASS"
+	if result.exitCode != 0 {
+		verdict = "FAIL"
+	}
+	if result.coverage < 0 {
+		return fmt.Sprintf("go-testcov: %v go_test_exit=%v", verdict, result.exitCode)
+	}
+
+	sections := map[string]bool{}
+	files := map[string]bool{}
+	overrides := 0
+	for _, finding := range result.findings {
+		if finding.Code == codeNewUntestedSection && finding.Severity == "error" {
+			section := fmt.Sprintf("%v:%v.%v,%v.%v", finding.Path, finding.Line, finding.Column, finding.EndLine, finding.EndColumn)
+			if finding.EndLine == 0 {
+				section = finding.Path + " " + finding.Message // --split-lines reports each line of a section with the same message
+			}
+			sections[section] = true
+			files[finding.Path] = true
+		}
+		if finding.Code == codeBudgetOverride {
+			overrides++
+		}
+	}
+	line := fmt.Sprintf("go-testcov: %v new_untested=%v files=%v coverage=%v", verdict, len(sections), len(files), formatPercent(result.coverage, precision))
+	if overrides > 0 {
+		line += fmt.Sprintf(" overrides=%v", overrides) // emergency budgets should not go unnoticed
+	}
+	return line
+}
+
+func formatIdeReport(result runResult) string {
+	content, err := json.MarshalIndent(ideReport{
+		Version: ideReportVersion, Coverage: result.coverage, Statements: result.statements, CoveredStatements: result.covered,
+		Findings: result.findings, Files: result.files, Skipped: result.skipped, Timings: result.phases,
+	}, "", "  ")
+	check(err)
+	return string(content) + "\n"
+}
+
+// findings as "path:line:column: message" lines, which vims default errorformat understands,
+// so `:cfile FILE` loads them into the quickfix list
+func formatQuickfix(result runResult) string {
+	content := ""
+	for _, finding := range result.findings {
+		if finding.Path == "" {
+			continue // not about a location vim could jump to
+		}
+		message := finding.Message
+		if finding.Severity == "warning" {
+			message = "warning: " + message
+		}
+		content += fmt.Sprintf("%v:%v:%v: %v\n", finding.Path, finding.Line, finding.Column, message)
+	}
+	return content
+}
+
+// sort by location so reports are stable
+func sortFindings(findings []finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+}
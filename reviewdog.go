@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// reviewdog diagnostic format, so `reviewdog -f=rdjson` posts findings as review comments on github, gitlab or bitbucket
+// https://github.com/reviewdog/reviewdog/tree/master/proto/rdf
+type rdjsonResult struct {
+	Source      rdjsonSource       `json:"source"`
+	Severity    string             `json:"severity"`
+	Diagnostics []rdjsonDiagnostic `json:"diagnostics"`
+}
+
+type rdjsonSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type rdjsonDiagnostic struct {
+	Message  string         `json:"message"`
+	Location rdjsonLocation `json:"location"`
+	Severity string         `json:"severity"`
+	Source   *rdjsonSource  `json:"source,omitempty"` // only in rdjsonl, where there is no result to hold it
+	Code     rdjsonCode     `json:"code"`
+}
+
+type rdjsonLocation struct {
+	Path  string      `json:"path"`
+	Range rdjsonRange `json:"range"`
+}
+
+type rdjsonRange struct {
+	Start rdjsonPosition  `json:"start"`
+	End   *rdjsonPosition `json:"end,omitempty"`
+}
+
+type rdjsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+}
+
+type rdjsonCode struct {
+	Value string `json:"value"`
+}
+
+var rdjsonGoTestcov = rdjsonSource{Name: "go-testcov", URL: "https://github.com/grosser/go-testcov"}
+
+// all findings with a location in one document, for `reviewdog -f=rdjson`
+func formatRdjson(result runResult) string {
+	content, err := json.MarshalIndent(rdjsonResult{Source: rdjsonGoTestcov, Severity: "ERROR", Diagnostics: rdjsonDiagnostics(result, false)}, "", "  ")
+	check(err)
+	return string(content) + "\n"
+}
+
+// one finding with a location per line, for `reviewdog -f=rdjsonl`
+func formatRdjsonl(result runResult) string {
+	lines := []string{}
+	for _, diagnostic := range rdjsonDiagnostics(result, true) {
+		content, err := json.Marshal(diagnostic)
+		check(err)
+		lines = append(lines, string(content)+"\n")
+	}
+	return strings.Join(lines, "")
+}
+
+func rdjsonDiagnostics(result runResult, withSource bool) (diagnostics []rdjsonDiagnostic) {
+	diagnostics = []rdjsonDiagnostic{}
+	for _, finding := range result.findings {
+		if finding.Path == "" {
+			continue // reviewdog comments on lines
+		}
+		diagnostic := rdjsonDiagnostic{
+			Message:  finding.Message,
+			Location: rdjsonLocation{Path: finding.Path, Range: rdjsonRange{Start: rdjsonPosition{Line: finding.Line, Column: finding.Column}}},
+			Severity: strings.ToUpper(finding.Severity),
+			Code:     rdjsonCode{Value: finding.Code},
+		}
+		if finding.EndLine != 0 {
+			diagnostic.Location.Range.End = &rdjsonPosition{Line: finding.EndLine, Column: finding.EndColumn}
+		}
+		if withSource {
+			diagnostic.Source = &rdjsonGoTestcov
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return
+}
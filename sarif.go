@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+)
+
+// what each finding code means, shown by code scanning tools next to their results
+var sarifRuleDescriptions = map[string]string{
+	codeNewUntestedSection:    "More untested sections than configured",
+	codeStaleBudget:           "Less untested sections than configured",
+	codeInvalidDirective:      "Malformed untested section comment",
+	codeLowTotalCoverage:      "Total coverage below --min-coverage or --target",
+	codeBudgetIncrease:        "Budget raised or untested section comment added",
+	codeBudgetOverride:        "Budget replaced with --override",
+	codeNewFileWithoutTests:   "New file without tests or budget",
+	codeLowDependencyCoverage: "Dependency of a --deps-min-coverage package below its coverage",
+	codeUntestedErrorPath:     "Error return that no test reaches",
+	codeLowFileCoverage:       "File coverage below --min-file-coverage",
+	codeLowPackageCoverage:    "Package of a --deps-min-coverage gate below its coverage",
+	codeProfileParseError:     "Malformed coverage profile line",
+}
+
+// sarif 2.1.0 log, the subset github code scanning needs to show findings as pull request annotations
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// findings with a location as sarif results, with one rule per finding code
+func formatSarif(result runResult) string {
+	rules := []sarifRule{}
+	results := []sarifResult{}
+	seen := map[string]bool{}
+	for _, finding := range result.findings {
+		if finding.Path == "" {
+			continue // code scanning needs a location
+		}
+		if !seen[finding.Code] {
+			seen[finding.Code] = true
+			rules = append(rules, sarifRule{ID: finding.Code, ShortDescription: sarifMessage{Text: sarifRuleDescriptions[finding.Code]}})
+		}
+		results = append(results, sarifResult{
+			RuleID:  finding.Code,
+			Level:   finding.Severity,
+			Message: sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(finding.Path)},
+				Region:           sarifRegion{StartLine: finding.Line, StartColumn: finding.Column, EndLine: finding.EndLine, EndColumn: finding.EndColumn},
+			}}},
+		})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "go-testcov", InformationURI: "https://github.com/grosser/go-testcov", Rules: rules}},
+			Results: results,
+		}},
+	}
+	content, err := json.MarshalIndent(log, "", "  ")
+	check(err)
+	return string(content) + "\n"
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// json schema of the json report, generated from the structs it is written from so the two can not drift apart,
+// it is published as report.schema.json and printed with `go-testcov report schema`
+// fields without omitempty are required, unknown fields are allowed since new fields are added without a new version
+func reportSchema() map[string]interface{} {
+	schema := jsonSchemaOf(reflect.TypeOf(ideReport{}))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["$id"] = fmt.Sprintf("https://github.com/grosser/go-testcov/report-v%v.schema.json", ideReportVersion)
+	schema["title"] = "go-testcov json report"
+	properties := schema["properties"].(map[string]interface{})
+	properties["version"] = map[string]interface{}{"type": "integer", "const": ideReportVersion}
+
+	// codes are stable, so consumers can rely on knowing all of them
+	codes := []string{}
+	for code := range sarifRuleDescriptions {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	findingProperties := properties["findings"].(map[string]interface{})["items"].(map[string]interface{})["properties"]
+	findingProperties.(map[string]interface{})["code"] = map[string]interface{}{"type": "string", "enum": codes}
+	return schema
+}
+
+func jsonSchemaOf(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := strings.Split(field.Tag.Get("json"), ",")
+			if field.Anonymous && tag[0] == "" {
+				// embedded structs like the Section of a finding are written as fields of their own
+				embedded := jsonSchemaOf(field.Type)
+				for name, property := range embedded["properties"].(map[string]interface{}) {
+					properties[name] = property
+				}
+				required = append(required, embedded["required"].([]string)...)
+				continue
+			}
+			if tag[0] == "" || tag[0] == "-" {
+				continue
+			}
+			properties[tag[0]] = jsonSchemaOf(field.Type)
+			if len(tag) == 1 || tag[1] != "omitempty" {
+				required = append(required, tag[0])
+			}
+		}
+		sort.Strings(required)
+		return map[string]interface{}{"type": "object", "properties": properties, "required": required}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": jsonSchemaOf(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	}
+	panic("no json schema for " + t.String())
+}
+
+func formatReportSchema() string {
+	content, err := json.MarshalIndent(reportSchema(), "", "  ")
+	check(err)
+	return string(content) + "\n"
+}
+
+// print the schema of the json report or check that a report matches it, so consumers can verify compatibility
+func runReport(argv []string) (exitCode int) {
+	if len(argv) == 1 && argv[0] == "schema" {
+		fmt.Print(formatReportSchema())
+		return 0
+	}
+	if len(argv) != 2 || argv[0] != "validate" {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: go-testcov report schema|validate FILE")
+		return 2
+	}
+
+	content, err := ioutil.ReadFile(argv[1])
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	problems := validateReport(content)
+	if len(problems) > 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "%v is not a valid version %v report:\n%v\n", argv[1], ideReportVersion, strings.Join(problems, "\n"))
+		return 1
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "%v is a valid version %v report\n", argv[1], ideReportVersion)
+	return 0
+}
+
+// problems of a report, reports of newer versions are not checked further since their fields may mean something else
+func validateReport(content []byte) (problems []string) {
+	var report interface{}
+	if err := json.Unmarshal(content, &report); err != nil {
+		return []string{err.Error()}
+	}
+	if object, ok := report.(map[string]interface{}); ok {
+		if version, ok := object["version"].(float64); ok && version > float64(ideReportVersion) {
+			return []string{fmt.Sprintf("version %v is newer than this go-testcov understands, update go-testcov", version)}
+		}
+	}
+	return validateJSONSchema(report, reportSchema(), "report")
+}
+
+// the parts of json schema reportSchema uses: type, const, enum, properties, required and items
+func validateJSONSchema(value interface{}, schema map[string]interface{}, path string) (problems []string) {
+	if expected, ok := schema["const"]; ok && fmt.Sprint(value) != fmt.Sprint(expected) {
+		return []string{fmt.Sprintf("%v: expected %v but got %v", path, expected, value)}
+	}
+	if allowed, ok := schema["enum"].([]string); ok {
+		if text, isString := value.(string); isString && !containsString(allowed, text) {
+			return []string{fmt.Sprintf("%v: unknown %v", path, text)}
+		}
+	}
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{path + ": expected an object"}
+		}
+		for _, name := range schema["required"].([]string) {
+			if _, found := object[name]; !found {
+				problems = append(problems, fmt.Sprintf("%v: missing %v", path, name))
+			}
+		}
+		properties := schema["properties"].(map[string]interface{})
+		names := []string{}
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, found := object[name]; found {
+				problems = append(problems, validateJSONSchema(property, properties[name].(map[string]interface{}), path+"."+name)...)
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{path + ": expected an array"}
+		}
+		for i, item := range items {
+			problems = append(problems, validateJSONSchema(item, schema["items"].(map[string]interface{}), fmt.Sprintf("%v[%v]", path, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			problems = append(problems, path+": expected a string")
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != math.Trunc(number) {
+			problems = append(problems, path+": expected an integer")
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			problems = append(problems, path+": expected a number")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, path+": expected a boolean")
+		}
+	}
+	return problems
+}
@@ -6,14 +6,21 @@ import (
 	"strings"
 )
 
-// Section represents a line as produced by `go test`
+// Section is a block of code as produced by `go test`, it is also where each finding points to, so every report
+// format reads the location and details of untested sections from the same fields
+// findings that are not about a block, like a stale budget comment, only have a location
 type Section struct {
-	path      string
-	startLine int
-	startChar int
-	endLine   int
-	endChar   int
-	sortValue int
+	Path        string `json:"path"`
+	Line        int    `json:"line"` // where the section starts
+	Column      int    `json:"column"`
+	EndLine     int    `json:"endLine"`
+	EndColumn   int    `json:"endColumn"`
+	Statements  int    `json:"statements,omitempty"`  // statements in the block, 0 when the line does not list them
+	Hits        int    `json:"-"`                     // how often the block ran, 0 for untested sections
+	Function    string `json:"function,omitempty"`    // function the section is in, like "(*Server).Shutdown"
+	Category    string `json:"category,omitempty"`    // keyword that opens the section, like "if", "else" or "case"
+	Suppression string `json:"suppression,omitempty"` // why an untested section does not fail, like "experimental until 2030-01-02"
+	sortValue   int
 }
 
 // NewSection parses a coverage line as produces by `go test`, for example "foo/bar.go:1.2,3.5 1 0"
@@ -29,13 +36,70 @@ func NewSection(line string) Section {
 	startChar := stringToInt(locations[1])
 	endLine := stringToInt(locations[2])
 	endChar := stringToInt(locations[3])
+	statements, hits := 0, stringToInt(locations[len(locations)-1])
+	if len(locations) > 5 {
+		statements = stringToInt(locations[4])
+	}
 
 	// allow sorting multiple sections from the same path
 	sortValue := startLine*100000 + startChar
 
-	return Section{path, startLine, startChar, endLine, endChar, sortValue}
+	return Section{
+		Path: path, Line: startLine, Column: startChar, EndLine: endLine, EndColumn: endChar,
+		Statements: statements, Hits: hits, sortValue: sortValue,
+	}
 }
 
 func (s Section) Location() string {
-	return fmt.Sprintf("%v.%v,%v.%v", s.startLine, s.startChar, s.endLine, s.endChar)
+	return fmt.Sprintf("%v.%v,%v.%v", s.Line, s.Column, s.EndLine, s.EndColumn)
+}
+
+// what the sections count against budgets, their number or with --count-statements their statements,
+// so one giant untested function is not cheaper than three tiny ones
+func untestedCount(sections []Section, countStatements bool) (count int) {
+	if !countStatements {
+		return len(sections)
+	}
+	for _, section := range sections {
+		if section.Statements > 0 {
+			count += section.Statements
+		} else {
+			count++ // profiles without statement counts still count each section
+		}
+	}
+	return count
+}
+
+// name of the function the section is in like "(*Server).Shutdown", empty outside of functions or when the source could not be parsed
+func (s Section) function(source sourceFile) string {
+	for name, lines := range source.functionNames {
+		if lines[0] <= s.Line && s.EndLine <= lines[1] {
+			return name
+		}
+	}
+	return ""
+}
+
+// keyword that opens the section like "if" or "range", empty when it is not opened by one like the body of a function
+func (s Section) category(source sourceFile) string {
+	keywords := regexp.MustCompile(`\b(`+strings.Join(directiveKeywords, "|")+`)\b`).FindAllString(sectionOpener(s, source), -1)
+	if len(keywords) == 0 {
+		return ""
+	}
+	return keywords[len(keywords)-1]
+}
+
+// line numbers of the section that contain code, skipping blank lines, comments and closing braces
+func (s Section) codeLines(source sourceFile) (lineNumbers []int) {
+	for lineNumber := s.Line; lineNumber <= s.EndLine; lineNumber++ {
+		code := strings.TrimSpace(source.line(lineNumber))
+		if code == "" || code == "}" || strings.HasPrefix(code, "//") {
+			continue
+		}
+		lineNumbers = append(lineNumbers, lineNumber)
+	}
+	if len(lineNumbers) == 0 {
+		lineNumbers = []int{s.Line} // keep the section visible even when the file changed since the test run
+	}
+	return
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// miniature module the selftest checks, with a covered file, a new untested section, a budget, an inline ignore
+// and a generated file, so every kind of rule has something to act on
+var selftestFiles = map[string]string{
+	"go.mod":          "module selftest\n\ngo 1.12\n",
+	"covered.go":      "package selftest\n\nfunc Covered() int {\n\treturn 1\n}\n",
+	"untested.go":     "package selftest\n\nfunc Untested(a bool) int {\n\tif a {\n\t\treturn 1\n\t}\n\treturn 0\n}\n",
+	"budgeted.go":     "package selftest\n\n// untested sections: 1\n\nfunc Budgeted(a bool) int {\n\tif a {\n\t\treturn 1\n\t}\n\treturn 0\n}\n",
+	"ignored.go":      "package selftest\n\nfunc Ignored(a bool) int {\n\tif a { // untested section\n\t\treturn 1\n\t}\n\treturn 0\n}\n",
+	"zz_generated.go": "package selftest\n\nfunc Generated(a bool) int {\n\tif a {\n\t\treturn 1\n\t}\n\treturn 0\n}\n",
+	"selftest_test.go": "package selftest\n\nimport \"testing\"\n\nfunc TestSelftest(t *testing.T) {\n" +
+		"\tif Covered()+Untested(false)+Budgeted(false)+Ignored(false)+Generated(false) != 1 {\n\t\tt.Fatal(\"wrong\")\n\t}\n}\n",
+}
+
+// go test and go build flags that take their value as the next argument when it is not given with "="
+var goTestValueFlags = []string{
+	"asmflags", "bench", "benchtime", "blockprofile", "blockprofilerate", "C", "count", "coverpkg", "covermode",
+	"coverprofile", "cpu", "cpuprofile", "exec", "fuzz", "fuzztime", "gcflags", "ldflags", "list", "memprofile",
+	"memprofilerate", "mod", "modfile", "o", "outputdir", "overlay", "p", "parallel", "pkgdir", "run", "shuffle",
+	"skip", "tags", "timeout", "toolexec", "vet",
+}
+
+// run the miniature module through the whole pipeline with the users options, so configuration changes can be
+// checked without touching the real ci, options that need the users repo like --diff are not exercised
+func runSelftest(argv []string) (exitCode int) {
+	opts, rest, err := parseOptions(argv)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer useOptions(opts)()
+	if opts.diff != "" || opts.baseline != "" || len(opts.dependencyGates) > 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "selftest: --diff, --baseline and --deps-min-coverage are not exercised, they need the history and packages of your repo")
+		opts.diff, opts.baseline, opts.dependencyGates = "", "", nil
+		opts.forbidBudgetIncrease, opts.forbidNewIgnores, opts.showResolved = false, false, false
+	}
+
+	directory, err := ioutil.TempDir("", "go-testcov-selftest")
+	check(err)
+	defer os.RemoveAll(directory)
+	for name, content := range selftestFiles {
+		check(ioutil.WriteFile(filepath.Join(directory, name), []byte(content), 0644))
+	}
+
+	// reports go into the module too, so a selftest never overwrites real reports
+	for i := range opts.reports {
+		opts.reports[i].path = filepath.Join(directory, "report."+opts.reports[i].format)
+	}
+
+	// go test flags like -race are kept, packages of the users repo do not exist in the miniature module
+	goTestArgs := []string{}
+	for i := 0; i < len(rest); i++ {
+		if !strings.HasPrefix(rest[i], "-") {
+			continue
+		}
+		goTestArgs = append(goTestArgs, rest[i])
+		if containsString(goTestValueFlags, strings.TrimLeft(rest[i], "-")) && i+1 < len(rest) {
+			i++
+			goTestArgs = append(goTestArgs, rest[i]) // "-timeout 5m" keeps its value
+		}
+	}
+	goTestArgs = append(goTestArgs, "./...")
+
+	var result runResult
+	inDirectory(directory, func() {
+		result = goTestAndCheckCoverage(goTestArgs, opts)
+		writeReports(result, opts)
+	})
+	if result.coverage < 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "selftest: FAIL go test failed")
+		return result.exitCode
+	}
+	fmt.Print(selftestSummary(result, opts))
+	fmt.Println("selftest: PASS")
+	return 0
+}
+
+// which rules found something, which files were excluded and which reports were written
+func selftestSummary(result runResult, opts options) string {
+	codes := []string{}
+	for _, finding := range result.findings {
+		if !containsString(codes, finding.Code) {
+			codes = append(codes, finding.Code)
+		}
+	}
+	sort.Strings(codes)
+
+	checked := []string{}
+	for _, file := range result.files {
+		checked = append(checked, fmt.Sprintf("%v (%v)", file.Path, file.Status))
+	}
+
+	excluded := []string{}
+	names := []string{}
+	for name := range selftestFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if reason := skipReason("selftest/"+name, opts); reason != "" && strings.HasSuffix(name, ".go") {
+			excluded = append(excluded, fmt.Sprintf("%v (%v)", name, reason))
+		}
+	}
+
+	formats := []string{}
+	for _, destination := range opts.reports {
+		formats = append(formats, destination.format)
+	}
+
+	lines := []string{
+		"verdict: " + strings.TrimPrefix(summaryLine(result, opts.precision), "go-testcov: "),
+		"rules: " + noneWhenEmpty(codes),
+		"checked: " + noneWhenEmpty(checked),
+		"excluded: " + noneWhenEmpty(excluded),
+		"formats: " + noneWhenEmpty(formats),
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func noneWhenEmpty(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// shields.io endpoint badge, publish the file as a ci artifact or on github pages and point
+// https://img.shields.io/endpoint?url=... at it for a badge that is always up to date
+// https://shields.io/badges/endpoint-badge
+type shieldsEndpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+	IsError       bool   `json:"isError,omitempty"`
+}
+
+// total statement coverage, colored like the badge command
+func formatShields(result runResult) string {
+	badge := shieldsEndpoint{SchemaVersion: 1, Label: "coverage", Message: "unknown", Color: "lightgrey", IsError: true}
+	if result.coverage >= 0 {
+		badge.Message, badge.Color, badge.IsError = formatPercent(result.coverage, result.precision), badgeColor(result.coverage), false
+	}
+	return formatShieldsEndpoint(badge)
+}
+
+// untested sections of all checked files, green when there are none and red when the check failed
+func formatShieldsUntested(result runResult) string {
+	badge := shieldsEndpoint{SchemaVersion: 1, Label: "untested sections", Message: "unknown", Color: "lightgrey", IsError: true}
+	if result.coverage >= 0 {
+		untested := 0
+		for _, file := range result.files {
+			untested += file.Untested
+		}
+		badge.Message, badge.Color, badge.IsError = strconv.Itoa(untested), "brightgreen", false
+		if result.exitCode != 0 {
+			badge.Color = "red"
+		} else if untested > 0 {
+			badge.Color = "yellow"
+		}
+	}
+	return formatShieldsEndpoint(badge)
+}
+
+func formatShieldsEndpoint(badge shieldsEndpoint) string {
+	content, err := json.Marshal(badge)
+	check(err)
+	return string(content) + "\n"
+}
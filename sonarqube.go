@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/xml"
+	"sort"
+)
+
+// sonarqube generic test coverage report, so sonarqube gets coverage from the same run instead of running go test again
+// https://docs.sonarsource.com/sonarqube/latest/analyzing-source-code/test-coverage/generic-test-data/
+type sonarqubeCoverage struct {
+	XMLName xml.Name        `xml:"coverage"`
+	Version int             `xml:"version,attr"`
+	Files   []sonarqubeFile `xml:"file"`
+}
+
+type sonarqubeFile struct {
+	Path  string          `xml:"path,attr"`
+	Lines []sonarqubeLine `xml:"lineToCover"`
+}
+
+type sonarqubeLine struct {
+	LineNumber int  `xml:"lineNumber,attr"`
+	Covered    bool `xml:"covered,attr"`
+}
+
+func formatSonarqube(result runResult) string {
+	report := sonarqubeCoverage{Version: 1, Files: []sonarqubeFile{}}
+	paths := []string{}
+	for path := range result.lines {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		file := sonarqubeFile{Path: path}
+		for lineNumber, hits := range result.lines[path] {
+			file.Lines = append(file.Lines, sonarqubeLine{LineNumber: lineNumber, Covered: hits > 0})
+		}
+		sort.Slice(file.Lines, func(i, j int) bool { return file.Lines[i].LineNumber < file.Lines[j].LineNumber })
+		report.Files = append(report.Files, file)
+	}
+	content, err := xml.MarshalIndent(report, "", "  ")
+	check(err)
+	return xml.Header + string(content) + "\n"
+}
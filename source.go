@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// what we know about a covered source file to decide which sections are ignored
+type sourceFile struct {
+	lines []string
+
+	// map the first line of a block to the line where the statement that opens it starts,
+	// so comments on the opening brace or on multi-line headers like long function signatures apply to the block
+	// blocks start after the opening brace in old go versions and at the first statement in new ones, so both are mapped
+	headerStarts map[int]int
+
+	// first and last line of each function, including its doc comment
+	functions [][2]int
+
+	// first and last line of each declared function by name like "(*Server).Shutdown", for function budgets
+	functionNames map[string][2]int
+
+	// lines of return statements that return an error like `return err` or `return fmt.Errorf(...)`
+	errorReturns []int
+
+	directives      map[int]directive // by line number
+	directiveErrors map[int]error     // malformed directives by line number
+
+	critical string // "file" or "package" when marked with //testcov:critical
+
+	experimentalLine int // line of the //testcov:experimental marker, 0 when there is none
+}
+
+// parse a source file, files that are not valid go only have lines and directives
+func parseSourceFile(path string, content string) (source sourceFile) {
+	source = sourceFile{
+		lines:           strings.Split(content, "\n"),
+		headerStarts:    map[int]int{},
+		directives:      map[int]directive{},
+		directiveErrors: map[int]error{},
+	}
+	for index, line := range source.lines {
+		found, ok, err := parseDirective(line)
+		if err != nil {
+			source.directiveErrors[index+1] = err
+		} else if ok {
+			source.directives[index+1] = found
+		}
+		if scope := parseCriticalDirective(line); scope != "" && source.critical != "package" {
+			source.critical = scope
+		}
+		if source.experimentalLine == 0 && strings.TrimSpace(line) == experimentalMarker {
+			source.experimentalLine = index + 1
+		}
+	}
+
+	// regions need both ends, otherwise everything below a forgotten "end" would be ignored
+	begin := 0
+	for _, lineNumber := range source.directiveLines() {
+		switch source.directives[lineNumber].scope {
+		case "begin":
+			if begin != 0 {
+				source.directiveErrors[begin] = fmt.Errorf("expected \"untested section: end\" before the next begin")
+				delete(source.directives, begin)
+			}
+			begin = lineNumber
+		case "end":
+			if begin == 0 {
+				source.directiveErrors[lineNumber] = fmt.Errorf("expected \"untested section: begin\" before end")
+				delete(source.directives, lineNumber)
+			}
+			begin = 0
+		}
+	}
+	if begin != 0 {
+		source.directiveErrors[begin] = fmt.Errorf("expected \"untested section: end\" after begin")
+		delete(source.directives, begin)
+	}
+
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, path, content, parser.ParseComments)
+	if err != nil {
+		return
+	}
+	source.functionNames = map[string][2]int{}
+
+	line := func(pos token.Pos) int { return fileSet.Position(pos).Line }
+	addBlock := func(start token.Pos, opening token.Pos, body []ast.Stmt) {
+		startLine := line(start)
+		blockLines := []int{line(opening)}
+		if len(body) > 0 {
+			blockLines = append(blockLines, line(body[0].Pos()))
+		}
+		for _, blockLine := range blockLines {
+			if existing, ok := source.headerStarts[blockLine]; startLine < blockLine && (!ok || startLine < existing) {
+				source.headerStarts[blockLine] = startLine
+			}
+		}
+	}
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncDecl:
+			start := n.Pos()
+			if n.Doc != nil {
+				start = n.Doc.Pos()
+				// "untested section" in the doc comment is about the whole function, not only its first block
+				for lineNumber := line(n.Doc.Pos()); lineNumber <= line(n.Doc.End()); lineNumber++ {
+					if found, ok := source.directives[lineNumber]; ok && !found.budget && !found.packageBudget && found.scope == "" {
+						found.scope = "function"
+						source.directives[lineNumber] = found
+					}
+				}
+			}
+			source.functions = append(source.functions, [2]int{line(start), line(n.End())})
+			source.functionNames[functionName(n)] = [2]int{line(start), line(n.End())}
+			if n.Body != nil {
+				addBlock(n.Pos(), n.Body.Lbrace, n.Body.List)
+			}
+		case *ast.FuncLit:
+			source.functions = append(source.functions, [2]int{line(n.Pos()), line(n.End())})
+			addBlock(n.Pos(), n.Body.Lbrace, n.Body.List)
+		case *ast.IfStmt:
+			addBlock(n.Pos(), n.Body.Lbrace, n.Body.List)
+			if elseBlock, ok := n.Else.(*ast.BlockStmt); ok {
+				addBlock(elseBlock.Lbrace, elseBlock.Lbrace, elseBlock.List)
+			}
+		case *ast.ForStmt:
+			addBlock(n.Pos(), n.Body.Lbrace, n.Body.List)
+		case *ast.RangeStmt:
+			addBlock(n.Pos(), n.Body.Lbrace, n.Body.List)
+		case *ast.CaseClause:
+			addBlock(n.Pos(), n.Colon, n.Body)
+		case *ast.CommClause:
+			addBlock(n.Pos(), n.Colon, n.Body)
+		case *ast.ReturnStmt:
+			for _, result := range n.Results {
+				if isErrorExpression(result) {
+					source.errorReturns = append(source.errorReturns, line(n.Pos()))
+					break
+				}
+			}
+		}
+		return true
+	})
+	return
+}
+
+// "Shutdown", "Server.Shutdown" or "(*Server).Shutdown"
+func functionName(function *ast.FuncDecl) string {
+	if function.Recv == nil || len(function.Recv.List) == 0 {
+		return function.Name.Name
+	}
+	receiver := types.ExprString(function.Recv.List[0].Type)
+	if strings.HasPrefix(receiver, "*") {
+		receiver = "(" + receiver + ")"
+	}
+	return receiver + "." + function.Name.Name
+}
+
+// code of a line, "" when the file is shorter since it changed after the test run
+func (source sourceFile) line(lineNumber int) string {
+	if lineNumber < 1 || lineNumber > len(source.lines) {
+		return ""
+	}
+	return source.lines[lineNumber-1]
+}
+
+// line numbers of all directives in order
+func (source sourceFile) directiveLines() (lineNumbers []int) {
+	for lineNumber := range source.directives {
+		lineNumbers = append(lineNumbers, lineNumber)
+	}
+	sort.Ints(lineNumbers)
+	return
+}
+
+// first "untested sections: N" budget and the line it is on, 0 if not configured
+func (source sourceFile) configuredUntested() (count int, lineNumber int) {
+	return source.budget(false)
+}
+
+// first file or "untested sections (package): N" budget and the line it is on, 0 if not configured
+func (source sourceFile) budget(packageScope bool) (count int, lineNumber int) {
+	for _, lineNumber := range source.directiveLines() {
+		if directive := source.directives[lineNumber]; directive.budget && directive.packageBudget == packageScope {
+			return directive.count, lineNumber
+		}
+	}
+	return 0, 0
+}
+
+// innermost function that contains the line
+func (source sourceFile) enclosingFunction(lineNumber int) (function [2]int, found bool) {
+	for _, candidate := range source.functions {
+		if candidate[0] <= lineNumber && lineNumber <= candidate[1] && (!found || candidate[0] >= function[0]) {
+			function, found = candidate, true
+		}
+	}
+	return
+}
+
+// is any go file in the directory marked with "//testcov:critical package"
+func isCriticalPackage(directory string) bool {
+	files, err := ioutil.ReadDir(directory)
+	check(err)
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".go") || strings.HasSuffix(file.Name(), "_test.go") {
+			continue
+		}
+		for _, line := range strings.Split(readFile(filepath.Join(directory, file.Name())), "\n") {
+			if parseCriticalDirective(line) == "package" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// "untested sections (package): N" budget that files of a package without a budget of their own share,
+// for teams that move code between files often
+type packageBudget struct {
+	count int
+	path  string // file and line the budget is configured on
+	line  int
+	glob  string // files the budget is for when it comes from --allowance instead of a package
+	found bool
+}
+
+// first package budget in the go files of the directory
+func findPackageBudget(directory string) (budget packageBudget) {
+	files, err := ioutil.ReadDir(directory)
+	check(err)
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".go") || strings.HasSuffix(file.Name(), "_test.go") {
+			continue
+		}
+		path := filepath.Join(directory, file.Name())
+		for index, line := range strings.Split(readFile(path), "\n") {
+			if found, ok, _ := parseDirective(line); ok && found.packageBudget {
+				return packageBudget{count: found.count, path: path, line: index + 1, found: true}
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// environment variable with the directory instrumented binaries should write their counters to
+var subprocessCoverDirVariable = "GO_TESTCOV_COVERDIR"
+
+// collect the coverage of instrumented binaries that tests run, like clis built with `go build -cover` and exec'd by
+// their tests, by pointing GO_TESTCOV_COVERDIR at a temporary directory while go test runs and folding what they wrote
+// into the profile of the tests, so their code does not show up as untested
+// GOCOVERDIR can not be used since test binaries built with -cover replace it with a directory of their own, so tests
+// pass it on like cmd.Env = append(os.Environ(), "GOCOVERDIR="+os.Getenv("GO_TESTCOV_COVERDIR"))
+func withSubprocessCoverage(coveragePath string, fn func() int) (exitCode int) {
+	directory, err := ioutil.TempDir("", "go-testcov-subprocesses")
+	check(err)
+	defer os.RemoveAll(directory)
+	counters := filepath.Join(directory, "counters")
+	check(os.Mkdir(counters, 0700))
+
+	old, found := os.LookupEnv(subprocessCoverDirVariable)
+	check(os.Setenv(subprocessCoverDirVariable, counters))
+	exitCode = fn()
+	if found {
+		check(os.Setenv(subprocessCoverDirVariable, old))
+	} else {
+		check(os.Unsetenv(subprocessCoverDirVariable))
+	}
+	if exitCode != 0 {
+		return exitCode
+	}
+	return foldSubprocessCoverage(counters, filepath.Join(directory, "subprocesses.out"), coveragePath)
+}
+
+// convert the counters to a text profile and append its blocks to the profile of the tests, blocks that are in both
+// are untested only when neither covered them, the same as for merged --modes profiles
+func foldSubprocessCoverage(counters string, textPath string, coveragePath string) (exitCode int) {
+	entries, err := ioutil.ReadDir(counters)
+	check(err)
+	if len(entries) == 0 {
+		return 0 // no instrumented binary ran
+	}
+	exitCode, output := runCommandCapturingOutput("go", "tool", "covdata", "textfmt", "-i="+counters, "-o="+textPath)
+	if exitCode != 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "converting subprocess coverage failed:\n%v", output)
+		return exitCode
+	}
+
+	profile, err := os.OpenFile(coveragePath, os.O_APPEND|os.O_WRONLY, 0644)
+	check(err)
+	defer profile.Close()
+	writer := bufio.NewWriter(profile)
+	for _, line := range strings.Split(readFile(textPath), "\n") {
+		if line != "" && !strings.HasPrefix(line, "mode:") {
+			_, err = writer.WriteString(line + "\n")
+			check(err)
+		}
+	}
+	check(writer.Flush())
+	return 0
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"html"
+)
+
+// colors of the shields.io color names the badges use
+var badgeHexColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"lightgrey":   "#9f9f9f",
+}
+
+// svg coverage badge that looks like the shields.io ones, so repositories can commit or publish a badge without a service
+func formatSvgBadge(result runResult) string {
+	message, color := "unknown", "lightgrey"
+	if result.coverage >= 0 {
+		message, color = formatPercent(result.coverage, result.precision), badgeColor(result.coverage)
+	}
+	return svgBadge("coverage", message, badgeHexColors[color])
+}
+
+// text widths are estimated from the number of characters, which is close enough for labels and percentages
+func svgBadge(label string, message string, color string) string {
+	labelWidth, messageWidth := 7*len(label)+10, 7*len(message)+10
+	width := labelWidth + messageWidth
+	title := html.EscapeString(label + ": " + message)
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]v" height="20" role="img" aria-label="%[2]v">
+<title>%[2]v</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]v" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="%[3]v" height="20" fill="#555"/><rect x="%[3]v" width="%[4]v" height="20" fill="%[5]v"/><rect width="%[1]v" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11"><text x="%[6]v" y="14">%[7]v</text><text x="%[8]v" y="14">%[9]v</text></g>
+</svg>
+`, width, title, labelWidth, messageWidth, color, labelWidth/2, html.EscapeString(label), labelWidth+messageWidth/2, html.EscapeString(message))
+}
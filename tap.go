@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+)
+
+// a tap test point, diagnostics are shown below failed points
+type tapPoint struct {
+	ok          bool
+	name        string
+	diagnostics []string
+}
+
+// test anything protocol report with one test point per checked file, failures list their untested sections as diagnostics
+// https://testanything.org/tap-version-13-specification.html
+func formatTap(result runResult) string {
+	points := []tapPoint{}
+	for _, file := range result.files {
+		point := tapPoint{ok: file.Status != "failed", name: file.Path}
+		if !point.ok {
+			point.diagnostics = append(point.diagnostics, fmt.Sprintf("%v untested sections but %v configured", file.Untested, file.Configured))
+			for _, finding := range result.findings {
+				if finding.Path == file.Path && finding.Code == codeNewUntestedSection {
+					point.diagnostics = append(point.diagnostics, fmt.Sprintf("%v:%v.%v,%v.%v", finding.Path, finding.Line, finding.Column, finding.EndLine, finding.EndColumn))
+				}
+			}
+		}
+		points = append(points, point)
+	}
+
+	for _, finding := range result.findings {
+		if finding.Path == "" && finding.Severity == "error" {
+			points = append(points, tapPoint{name: finding.Code, diagnostics: []string{finding.Message}})
+		}
+	}
+
+	content := fmt.Sprintf("TAP version 13\n1..%v\n", len(points))
+	for i, point := range points {
+		status := "ok"
+		if !point.ok {
+			status = "not ok"
+		}
+		content += fmt.Sprintf("%v %v - %v\n", status, i+1, point.name)
+		for _, diagnostic := range point.diagnostics {
+			content += "# " + diagnostic + "\n"
+		}
+	}
+	return content
+}
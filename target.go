@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// coverage goal that ramps up over time, from --target="90% by 2025-12-31 from 2025-01-01", so goals tighten
+// automatically instead of via manual config bumps, the ramp starts at --min-coverage (or 0) on the from date
+type coverageTarget struct {
+	percent float64
+	by      time.Time
+	from    time.Time
+}
+
+var coverageTargetRegexp = regexp.MustCompile(`^\s*(\S+)\s+by\s+(\d{4}-\d{2}-\d{2})\s+from\s+(\d{4}-\d{2}-\d{2})\s*$`)
+
+const coverageTargetDate = "2006-01-02"
+
+func parseCoverageTarget(value string) (target *coverageTarget, err error) {
+	invalid := fmt.Errorf("invalid target %v, expected like 90%% by 2025-12-31 from 2025-01-01", value)
+	match := coverageTargetRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return nil, invalid
+	}
+	target = &coverageTarget{}
+	if target.percent, err = parsePercent(match[1]); err != nil {
+		return nil, err
+	}
+	if target.by, err = time.Parse(coverageTargetDate, match[2]); err != nil {
+		return nil, invalid
+	}
+	if target.from, err = time.Parse(coverageTargetDate, match[3]); err != nil {
+		return nil, invalid
+	}
+	if !target.from.Before(target.by) {
+		return nil, fmt.Errorf("invalid target %v, the from date needs to be before the by date", value)
+	}
+	return target, nil
+}
+
+// percentage required on the day, linear between --min-coverage on the from date and the target on the by date
+func scheduledMinCoverage(target coverageTarget, minCoverage float64, now time.Time) float64 {
+	if minCoverage >= target.percent || !now.After(target.from) {
+		return minCoverage
+	}
+	if !now.Before(target.by) {
+		return target.percent
+	}
+	progress := float64(now.Sub(target.from)) / float64(target.by.Sub(target.from))
+	return minCoverage + (target.percent-minCoverage)*progress
+}
+
+func (target coverageTarget) String() string {
+	return fmt.Sprintf("%v%% by %v", target.percent, target.by.Format(coverageTargetDate))
+}
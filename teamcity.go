@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// teamcity service messages, so builds chart coverage, list findings in their inspections tab and fail with the summary
+// https://www.jetbrains.com/help/teamcity/service-messages.html
+func formatTeamcity(result runResult) string {
+	content := ""
+	if result.coverage >= 0 {
+		content += teamcityMessage("buildStatisticValue", "key", "CodeCoverageS", "value", fmt.Sprintf("%.2f", result.coverage))
+	}
+
+	codes := []string{}
+	seen := map[string]bool{}
+	for _, finding := range result.findings {
+		if finding.Path != "" && !seen[finding.Code] {
+			seen[finding.Code] = true
+			codes = append(codes, finding.Code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		content += teamcityMessage("inspectionType", "id", code, "name", code, "category", "go-testcov", "description", sarifRuleDescriptions[code])
+	}
+	for _, finding := range result.findings {
+		if finding.Path == "" {
+			continue // inspections are about files
+		}
+		content += teamcityMessage(
+			"inspection", "typeId", finding.Code, "message", finding.Message, "file", finding.Path,
+			"line", fmt.Sprint(finding.Line), "SEVERITY", strings.ToUpper(finding.Severity),
+		)
+	}
+
+	if result.exitCode != 0 {
+		content += teamcityMessage("buildProblem", "description", summaryLine(result, result.precision), "identity", "go-testcov")
+	}
+	return content
+}
+
+// ##teamcity[name key='value' ...] with escaped values
+func teamcityMessage(name string, attributes ...string) string {
+	escape := strings.NewReplacer("|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]")
+	message := "##teamcity[" + name
+	for i := 0; i < len(attributes); i += 2 {
+		message += fmt.Sprintf(" %v='%v'", attributes[i], escape.Replace(attributes[i+1]))
+	}
+	return message + "]\n"
+}
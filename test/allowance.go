@@ -0,0 +1 @@
+../allowance.go
\ No newline at end of file
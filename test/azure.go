@@ -0,0 +1 @@
+../azure.go
\ No newline at end of file
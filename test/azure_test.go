@@ -0,0 +1,39 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("azure", func() {
+	Describe("formatAzure", func() {
+		It("logs an issue per finding and completes the task", func() {
+			Expect(formatAzure(runResult{
+				exitCode: 1,
+				findings: []finding{
+					untestedSectionFinding,
+					staleBudgetFinding,
+					lowTotalCoverageFinding,
+				},
+				coverage:  50,
+				precision: 2,
+			})).To(Equal(
+				"##vso[task.logissue type=error;sourcepath=a.go;linenumber=1;columnnumber=2;code=NEW_UNTESTED_SECTION;]new untested section introduced (1 current vs 0 configured)\n" +
+					"##vso[task.logissue type=warning;sourcepath=b.go;linenumber=1;code=STALE_BUDGET;]less untested sections (0 current vs 1 configured), decrement configured untested?\n" +
+					"##vso[task.logissue type=error;code=LOW_TOTAL_COVERAGE;]total coverage 50.0%AZP25 is below the required 75.0%AZP25\n" +
+					"##vso[task.complete result=Failed;]go-testcov: FAIL new_untested=1 files=1 coverage=50.00%AZP25\n",
+			))
+		})
+
+		It("succeeds with issues when there are only warnings", func() {
+			Expect(formatAzure(runResult{
+				findings:  []finding{{Section: Section{Path: "a;b.go", Line: 1}, Severity: "warning", Code: codeStaleBudget, Message: "less"}},
+				coverage:  100,
+				precision: 1,
+			})).To(Equal(
+				"##vso[task.logissue type=warning;sourcepath=a%3Bb.go;linenumber=1;code=STALE_BUDGET;]less\n" +
+					"##vso[task.complete result=SucceededWithIssues;]go-testcov: PASS new_untested=0 files=0 coverage=100.0%AZP25\n",
+			))
+		})
+	})
+})
@@ -0,0 +1 @@
+../badge.go
\ No newline at end of file
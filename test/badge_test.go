@@ -0,0 +1,91 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("badge", func() {
+	// fake go writes 75% coverage to where it was asked to write coverage
+	withCoverage := func(fn func()) {
+		withFakeGo("for last; do :; done; printf 'mode: set\\nfoo.go:1.1,1.5 3 1\\nfoo.go:2.1,2.5 1 0\\n' > \"$last\"", fn)
+	}
+
+	Describe("runBadge", func() {
+		It("prints the badge", func() {
+			withCoverage(func() {
+				expectCommand(
+					func() int { return run([]string{"badge", "./..."}) },
+					[]interface{}{0, "![coverage](https://img.shields.io/badge/coverage-75.0%25-green)\n", ""},
+				)
+			})
+		})
+
+		It("writes a badge of each package", func() {
+			withFakeGo("for last; do :; done; printf 'mode: set\\nexample.com/m/a.go:1.1,1.5 1 1\\nexample.com/m/pkg/api/b.go:1.1,1.5 3 1\\nexample.com/m/pkg/api/b.go:2.1,2.5 1 0\\n' > \"$last\"", func() {
+				writeFile("go.mod", "module example.com/m\n")
+				expectCommand(func() int { return run([]string{"badge", "--per-package=badges", "./..."}) }, []interface{}{0, "", ""})
+				Expect(readFile("badges/root.json")).To(Equal(`{"schemaVersion":1,"label":"coverage","message":"100.0%","color":"brightgreen"}` + "\n"))
+				Expect(readFile("badges/pkg-api.json")).To(Equal(`{"schemaVersion":1,"label":"coverage","message":"75.0%","color":"green"}` + "\n"))
+				Expect(readFile("badges/pkg-api.svg")).To(ContainSubstring("<title>coverage: 75.0%</title>"))
+				Expect(readFile("badges/root.svg")).To(ContainSubstring("<title>coverage: 100.0%</title>"))
+			})
+		})
+
+		It("does not write package badges with --read-only", func() {
+			expectCommand(
+				func() int { return run([]string{"badge", "--per-package=badges", "--read-only"}) },
+				[]interface{}{2, "", "--per-package writes badges, which --read-only forbids\n"},
+			)
+		})
+
+		It("fails when tests fail", func() {
+			withFakeGo("exit 3", func() {
+				expectCommand(func() int { return run([]string{"badge"}) }, []interface{}{3, "", ""})
+			})
+		})
+
+		It("updates the readme", func() {
+			withCoverage(func() {
+				writeFile("README.md", "# Foo\n<!-- go-testcov badge -->old<!-- /go-testcov badge -->\ntext\n")
+				expectCommand(func() int { return run([]string{"badge", "--update-readme"}) }, []interface{}{0, "", ""})
+				Expect(readFile("README.md")).To(Equal(
+					"# Foo\n<!-- go-testcov badge -->![coverage](https://img.shields.io/badge/coverage-75.0%25-green)<!-- /go-testcov badge -->\ntext\n",
+				))
+			})
+		})
+
+		It("fails when the readme has no markers", func() {
+			withCoverage(func() {
+				writeFile("Readme.md", "# Foo\n")
+				expectCommand(
+					func() int { return run([]string{"badge", "--update-readme"}) },
+					[]interface{}{2, "", "Could not find <!-- go-testcov badge --> ... <!-- /go-testcov badge --> in Readme.md, add them where the badge should go\n"},
+				)
+			})
+		})
+
+		It("fails when there is no readme", func() {
+			withCoverage(func() {
+				expectCommand(
+					func() int { return run([]string{"badge", "--update-readme"}) },
+					[]interface{}{2, "", "Could not find a readme, looked for README.md, Readme.md, readme.md\n"},
+				)
+			})
+		})
+	})
+
+	Describe("coverageBadge", func() {
+		It("colors by percentage", func() {
+			Expect(coverageBadge(100, 1)).To(HaveSuffix("-100.0%25-brightgreen)"))
+			Expect(coverageBadge(60, 1)).To(HaveSuffix("-yellow)"))
+			Expect(coverageBadge(45, 1)).To(HaveSuffix("-orange)"))
+			Expect(coverageBadge(10, 1)).To(HaveSuffix("-red)"))
+		})
+
+		It("uses the precision", func() {
+			Expect(coverageBadge(81.234, 2)).To(HaveSuffix("-81.23%25-green)"))
+			Expect(coverageBadge(81.234, 0)).To(HaveSuffix("-81%25-green)"))
+		})
+	})
+})
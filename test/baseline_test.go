@@ -0,0 +1,228 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fake vcs so ancestors do not need a repository
+type fakeAncestors []string
+
+func (fakeAncestors) changedFiles(revision string) []string { return nil }
+
+func (f fakeAncestors) ancestors(revision string, limit int) []string { return f }
+
+func (fakeAncestors) fileAt(revision string, path string) (string, bool) { return "", false }
+
+func (fakeAncestors) lineAddedAt(path string, line int) (time.Time, bool) { return time.Time{}, false }
+
+var _ = Describe("baseline", func() {
+	Describe("resolveBaseline", func() {
+		It("uses plain paths as they are", func() {
+			path, _, found, _ := resolveBaseline("base.out", "main", fakeAncestors{})
+			Expect(found).To(BeTrue())
+			Expect(path).To(Equal("base.out"))
+		})
+
+		It("uses the first ancestor that has coverage", func() {
+			inTempDir(func() {
+				writeFile("main-b.out", "b")
+				writeFile("main-c.out", "c")
+				path, _, found, _ := resolveBaseline("{branch}-{sha}.out", "main", fakeAncestors{"a", "b", "c"})
+				Expect(found).To(BeTrue())
+				Expect(path).To(Equal("main-b.out"))
+			})
+		})
+
+		It("does not find coverage when no ancestor has it", func() {
+			inTempDir(func() {
+				_, _, found, _ := resolveBaseline("{sha}.out", "main", fakeAncestors{"a"})
+				Expect(found).To(BeFalse())
+			})
+		})
+
+		It("downloads coverage and falls back to ancestors when it is missing", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/coverage/main/b.out" {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write([]byte("mode: set\n"))
+			}))
+			defer server.Close()
+
+			withCacheDir(func(dir string) {
+				path, cleanup, found, _ := resolveBaseline(server.URL+"/coverage/{branch}/{sha}.out", "main", fakeAncestors{"a", "b"})
+				Expect(found).To(BeTrue())
+				Expect(readFile(path)).To(Equal("mode: set\n"))
+				Expect(path).To(HavePrefix(dir))
+				cleanup()
+				Expect(path).To(BeAnExistingFile())
+			})
+		})
+
+		It("uses cached coverage of a sha without downloading it again", func() {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				_, _ = w.Write([]byte("mode: set\n"))
+			}))
+			defer server.Close()
+
+			withCacheDir(func(dir string) {
+				resolveBaseline(server.URL+"/{sha}.out", "main", fakeAncestors{"a"})
+				path, _, found, _ := resolveBaseline(server.URL+"/{sha}.out", "main", fakeAncestors{"a"})
+				Expect(found).To(BeTrue())
+				Expect(readFile(path)).To(Equal("mode: set\n"))
+				Expect(requests).To(Equal(1))
+			})
+		})
+
+		It("downloads coverage of a branch to a temp file since it changes", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("mode: set\n"))
+			}))
+			defer server.Close()
+
+			withCacheDir(func(dir string) {
+				path, cleanup, found, _ := resolveBaseline(server.URL+"/{branch}.out", "main", fakeAncestors{})
+				Expect(found).To(BeTrue())
+				Expect(path).ToNot(HavePrefix(dir))
+				cleanup()
+				Expect(path).ToNot(BeAnExistingFile())
+			})
+		})
+
+		It("downloads coverage from s3 with the aws cli", func() {
+			withFakeCommand("aws", "echo \"$@\" >> calls; case $3 in *b.out) echo 'mode: set' > $4;; *) echo 'An error occurred (404) when calling the HeadObject operation' >&2; exit 1;; esac", func() {
+				withCacheDir(func(dir string) {
+					inTempDir(func() {
+						path, _, found, _ := resolveBaseline("s3://bucket/coverage/{sha}.out", "main", fakeAncestors{"a", "b"})
+						Expect(found).To(BeTrue())
+						Expect(readFile(path)).To(Equal("mode: set\n"))
+						Expect(readFile("calls")).To(MatchRegexp("^s3 cp s3://bucket/coverage/a.out .*\ns3 cp s3://bucket/coverage/b.out .*\n$"))
+					})
+				})
+			})
+		})
+
+		It("fails when the cloud storage cli errors", func() {
+			withFakeCommand("gsutil", "echo 'AccessDeniedException: 403' >&2; exit 1", func() {
+				withCacheDir(func(dir string) {
+					_, _, found, err := resolveBaseline("gs://bucket/{sha}.out", "main", fakeAncestors{"a"})
+					Expect(found).To(BeFalse())
+					Expect(err).To(MatchError("downloading baseline gs://bucket/a.out with gsutil failed with exit code 1:\nAccessDeniedException: 403\n"))
+				})
+			})
+		})
+
+		It("fails when the artifact store errors", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			withCacheDir(func(dir string) {
+				_, _, _, err := resolveBaseline(server.URL+"/{sha}.out", "main", fakeAncestors{"a"})
+				Expect(err).To(MatchError("downloading baseline " + server.URL + "/a.out failed with status 500 Internal Server Error"))
+				_, _, _, err = resolveBaseline(server.URL+"/{branch}.out", "main", fakeAncestors{})
+				Expect(err).To(MatchError("downloading baseline " + server.URL + "/main.out failed with status 500 Internal Server Error"))
+			})
+		})
+
+		It("fails when the artifact store does not answer in time", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(100 * time.Millisecond)
+			}))
+			defer server.Close()
+			defer func(old time.Duration) { baselineDownloadTimeout = old }(baselineDownloadTimeout)
+			baselineDownloadTimeout = 10 * time.Millisecond
+
+			withCacheDir(func(dir string) {
+				_, _, _, err := resolveBaseline(server.URL+"/{sha}.out", "main", fakeAncestors{"a"})
+				Expect(err).To(MatchError(ContainSubstring("downloading baseline " + server.URL + "/a.out failed: ")))
+			})
+		})
+	})
+
+	Describe("runBaseline", func() {
+		It("publishes coverage over http with PUT", func() {
+			uploaded := ""
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				noError(err)
+				uploaded = r.Method + " " + r.URL.Path + " " + string(body)
+			}))
+			defer server.Close()
+
+			inTempDir(func() {
+				writeFile("coverage.out", "mode: set\n")
+				expectCommand(
+					func() int { return runBaseline([]string{"publish", "coverage.out", server.URL + "/coverage/a.out"}) },
+					[]interface{}{0, "", "published coverage.out to " + server.URL + "/coverage/a.out\n"},
+				)
+				Expect(uploaded).To(Equal("PUT /coverage/a.out mode: set\n"))
+			})
+		})
+
+		It("publishes coverage to s3 with the aws cli", func() {
+			withFakeCommand("aws", "echo \"$@\" > calls", func() {
+				inTempDir(func() {
+					writeFile("coverage.out", "mode: set\n")
+					expectCommand(
+						func() int { return runBaseline([]string{"publish", "coverage.out", "s3://bucket/a.out"}) },
+						[]interface{}{0, "", "published coverage.out to s3://bucket/a.out\n"},
+					)
+					Expect(readFile("calls")).To(Equal("s3 cp coverage.out s3://bucket/a.out\n"))
+				})
+			})
+		})
+
+		It("publishes coverage to a local path", func() {
+			inTempDir(func() {
+				writeFile("coverage.out", "mode: set\n")
+				expectCommand(
+					func() int { return runBaseline([]string{"publish", "coverage.out", "shared/main.out"}) },
+					[]interface{}{0, "", "published coverage.out to shared/main.out\n"},
+				)
+				Expect(readFile("shared/main.out")).To(Equal("mode: set\n"))
+			})
+		})
+
+		It("fails when the store rejects the upload", func() {
+			withFakeCommand("gsutil", "echo 'AccessDeniedException: 403' >&2; exit 1", func() {
+				inTempDir(func() {
+					writeFile("coverage.out", "mode: set\n")
+					expectCommand(
+						func() int { return runBaseline([]string{"publish", "coverage.out", "gs://bucket/a.out"}) },
+						[]interface{}{1, "", "uploading baseline gs://bucket/a.out with gsutil failed with exit code 1:\nAccessDeniedException: 403\n\n"},
+					)
+				})
+			})
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			}))
+			defer server.Close()
+			inTempDir(func() {
+				writeFile("coverage.out", "mode: set\n")
+				expectCommand(
+					func() int { return runBaseline([]string{"publish", "coverage.out", server.URL + "/a.out"}) },
+					[]interface{}{1, "", "uploading baseline " + server.URL + "/a.out failed with status 403 Forbidden\n"},
+				)
+			})
+		})
+
+		It("shows usage", func() {
+			expectCommand(
+				func() int { return runBaseline([]string{"publish", "coverage.out"}) },
+				[]interface{}{2, "", "Usage: go-testcov baseline publish COVERAGE_FILE LOCATION\n"},
+			)
+		})
+	})
+})
@@ -0,0 +1 @@
+../batch.go
\ No newline at end of file
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("batch", func() {
+	Describe("runBatch", func() {
+		// fake go reports an untested section in svc-b only
+		withDirectories := func(fn func()) {
+			withFakeGo("echo header > coverage.out; if [ $(basename $PWD) = svc-b ]; then echo foo:1.2,1.3 1 0 >> coverage.out; fi", func() {
+				withoutEnv("GOPATH", func() {
+					noError(os.MkdirAll("svc-a", 0700))
+					noError(os.MkdirAll("svc-b", 0700))
+					writeFile("svc-b/foo", "")
+					fn()
+				})
+			})
+		}
+
+		It("checks each directory and combines exit codes and reports", func() {
+			withDirectories(func() {
+				expectCommand(
+					func() int { return run([]string{"run", "svc-a", "svc-b", "--quickfix=report.qf", "./..."}) },
+					[]interface{}{
+						1,
+						"",
+						"go-testcov in svc-a\ngo-testcov: PASS new_untested=0 files=0 coverage=100.0%\ngo-testcov in svc-b\n" +
+							"foo new untested sections introduced (1 current vs 0 configured)\nfoo:1.2,1.3\n" +
+							"go-testcov: FAIL new_untested=1 files=1 coverage=0.0%\n" +
+							"svc-a ok\nsvc-b failed with exit code 1\n",
+					},
+				)
+				Expect(readFile("report.qf")).To(Equal("svc-b/foo:1:2: new untested section introduced (1 current vs 0 configured)\n"))
+				Expect("svc-b/coverage.out").ToNot(BeAnExistingFile())
+			})
+		})
+
+		It("passes when all directories pass", func() {
+			withDirectories(func() {
+				expectCommand(
+					func() int { return run([]string{"run", "svc-a"}) },
+					[]interface{}{0, "", "go-testcov in svc-a\ngo-testcov: PASS new_untested=0 files=0 coverage=100.0%\nsvc-a ok\n"},
+				)
+			})
+		})
+
+		It("uses the config of each directory", func() {
+			withDirectories(func() {
+				writeFile("svc-a/.go-testcov.yml", "min-coverage: lots\n")
+				writeFile("svc-b/.go-testcov.yml", "exclude: [foo]\n")
+				expectCommand(
+					func() int { return run([]string{"run", "svc-a", "svc-b", "--format=json:report.json"}) },
+					[]interface{}{
+						2,
+						"",
+						"go-testcov in svc-a\n.go-testcov.yml: invalid percentage lots, expected a number between 0 and 100\n" +
+							"go-testcov in svc-b\ngo-testcov: PASS new_untested=0 files=0 coverage=100.0%\n" +
+							"svc-a failed with exit code 2\nsvc-b ok\n",
+					},
+				)
+				Expect(readFile("report.json")).To(ContainSubstring(`"skipped": [
+    {
+      "path": "svc-b/foo",
+      "reason": "matches --exclude foo"
+    }
+  ],`))
+			})
+		})
+
+		It("fails on invalid options", func() {
+			withDirectories(func() {
+				expectCommand(
+					func() int { return run([]string{"run", "svc-a", "--min-coverage=nope"}) },
+					[]interface{}{2, "", "invalid percentage nope, expected a number between 0 and 100\n"},
+				)
+			})
+		})
+
+		It("checks directories in parallel and combines their reports", func() {
+			withDirectories(func() {
+				// fake go-testcov writes its findings to the json report it is given
+				fake := `report=${1#--format=json:}
+if [ $(basename $PWD) = svc-b ]; then
+  echo "testing $2" >&2
+  echo '{"version":1,"findings":[{"path":"foo","line":1,"column":2,"endLine":1,"endColumn":3,"severity":"error","code":"NEW_UNTESTED_SECTION","message":"new untested section introduced"}],"files":[],"timings":[],"coverage":0,"statements":1,"coveredStatements":0}' > $report
+  exit 1
+fi
+echo '{"version":1,"findings":[],"files":[],"timings":[],"coverage":100,"statements":3,"coveredStatements":3}' > $report`
+				withFakeCommand("fake-testcov", fake, func() {
+					original := selfExecutable
+					defer func() { selfExecutable = original }()
+					selfExecutable = func() string { return "fake-testcov" }
+
+					expectCommand(
+						func() int {
+							return run([]string{"run", "--parallel", "svc-a", "svc-b", "--quickfix=report.qf", "--format=shields:report.shields", "./..."})
+						},
+						[]interface{}{1, "", "svc-b: testing ./...\nsvc-a ok\nsvc-b failed with exit code 1\n"},
+					)
+					Expect(readFile("report.qf")).To(Equal("svc-b/foo:1:2: new untested section introduced\n"))
+					Expect(readFile("report.shields")).To(ContainSubstring(`"message":"75.0%"`))
+				})
+			})
+		})
+
+		It("produces the same result in parallel as one after the other", func() {
+			root, err := filepath.Abs("..")
+			noError(err)
+			withTempDir(func(bin string) {
+				build := exec.Command("go", "build", "-o", filepath.Join(bin, "go-testcov"), ".")
+				build.Dir = root
+				output, err := build.CombinedOutput()
+				Expect(err).ToNot(HaveOccurred(), string(output))
+				original := selfExecutable
+				defer func() { selfExecutable = original }()
+				selfExecutable = func() string { return filepath.Join(bin, "go-testcov") }
+
+				withFakeGo("echo mode: set > coverage.out; echo foo:1.2,1.3 1 $(grep -c hit foo) >> coverage.out", func() {
+					withoutEnv("GOPATH", func() {
+						noError(os.MkdirAll("svc-a", 0700))
+						noError(os.MkdirAll("svc-b", 0700))
+						writeFile("svc-a/foo", "hit\n")
+						writeFile("svc-b/foo", "")
+						writeFile("svc-a/.go-testcov.yml", "format: [quickfix:own.qf]\n")
+
+						results := map[string]ideReport{}
+						for _, mode := range []string{"sequential", "--parallel"} {
+							args := []string{"run", "svc-a", "svc-b", "--format=json:report.json", "--format=lcov:report.lcov", "./..."}
+							if mode == "--parallel" {
+								args = append([]string{"run", mode}, args[1:]...)
+							}
+							captureAll(func() { Expect(run(args)).To(Equal(1)) })
+
+							var report ideReport
+							noError(json.Unmarshal([]byte(readFile("report.json")), &report))
+							report.Timings = nil // how long each phase took differs
+							results[mode] = report
+							Expect(readFile("report.lcov")).To(Equal(
+								"TN:\nSF:svc-a/foo\nDA:1,1\nLF:1\nLH:1\nend_of_record\nTN:\nSF:svc-b/foo\nDA:1,0\nLF:1\nLH:0\nend_of_record\n",
+							))
+							Expect(readFile("svc-a/own.qf")).To(Equal(""))
+							noError(os.Remove("svc-a/own.qf"))
+						}
+						Expect(results["--parallel"]).To(Equal(results["sequential"]))
+						Expect(results["sequential"].Coverage).To(Equal(50.0))
+						Expect(results["sequential"].Findings).To(HaveLen(1))
+					})
+				})
+			})
+		})
+
+		It("shows usage without directories", func() {
+			withDirectories(func() {
+				expectCommand(
+					func() int { return run([]string{"run", "./..."}) },
+					[]interface{}{2, "", "Usage: go-testcov run [--parallel] DIRECTORY... [go test arguments]\n"},
+				)
+			})
+		})
+	})
+})
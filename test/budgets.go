@@ -0,0 +1 @@
+../budgets.go
\ No newline at end of file
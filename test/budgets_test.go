@@ -0,0 +1,64 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("budgets", func() {
+	Describe("parseFunctionBudgets", func() {
+		It("parses budgets and skips comments", func() {
+			inTempDir(func() {
+				writeFile("budgets", "# comment\n\npkg/x/y.go#(*Server).Shutdown: 2\n./y.go#parse:1\n")
+				budgets, err := parseFunctionBudgets("budgets")
+				noError(err)
+				Expect(budgets).To(Equal([]functionBudget{
+					{path: "pkg/x/y.go", function: "(*Server).Shutdown", count: 2, file: "budgets", line: 3},
+					{path: "y.go", function: "parse", count: 1, file: "budgets", line: 4},
+				}))
+			})
+		})
+
+		It("fails on malformed lines", func() {
+			inTempDir(func() {
+				writeFile("budgets", "y.go: 2\n")
+				_, err := parseFunctionBudgets("budgets")
+				Expect(err).To(MatchError("budgets:1: expected \"path#function: count\" but got \"y.go: 2\""))
+				writeFile("budgets", "y.go#parse: many\n")
+				_, err = parseFunctionBudgets("budgets")
+				Expect(err).To(MatchError("budgets:1: expected \"path#function: count\" but got \"y.go#parse: many\""))
+			})
+		})
+
+		It("fails on missing files", func() {
+			inTempDir(func() {
+				_, err := parseFunctionBudgets("budgets")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("applyFunctionBudgets", func() {
+		source := parseSourceFile("y.go", "package y\n\nfunc a() {\n\tb()\n\tc()\n}\n\nfunc d() {\n\te()\n}\n")
+		inA := []Section{NewSection("y.go:4.2,4.5 1 0"), NewSection("y.go:5.2,5.5 1 0")}
+		inD := NewSection("y.go:9.2,9.5 1 0")
+
+		It("removes sections of functions within their budget", func() {
+			remaining, stale := applyFunctionBudgets(append(inA, inD), source, "y.go", []functionBudget{{path: "y.go", function: "a", count: 2}})
+			Expect(remaining).To(Equal([]Section{inD}))
+			Expect(stale).To(BeEmpty())
+		})
+
+		It("keeps all sections of functions above their budget", func() {
+			remaining, _ := applyFunctionBudgets(append(inA, inD), source, "y.go", []functionBudget{{path: "y.go", function: "a", count: 1}})
+			Expect(remaining).To(Equal(append(inA, inD)))
+		})
+
+		It("finds stale budgets", func() {
+			budgets := []functionBudget{{path: "y.go", function: "d", count: 2}, {path: "y.go", function: "renamed", count: 1}, {path: "z.go", function: "a", count: 1}}
+			remaining, stale := applyFunctionBudgets([]Section{inD}, source, "y.go", budgets)
+			Expect(remaining).To(Equal([]Section{}))
+			Expect(stale).To(Equal(map[functionBudget]int{budgets[0]: 1, budgets[1]: 0}))
+		})
+	})
+})
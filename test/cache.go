@@ -0,0 +1 @@
+../cache.go
\ No newline at end of file
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("cache", func() {
+	Describe("cacheDirectory", func() {
+		It("uses the user cache directory", func() {
+			withCacheDir(func(dir string) {
+				Expect(cacheDirectory("a", "b")).To(HavePrefix(dir))
+				Expect(cacheDirectory("a", "b")).To(HaveSuffix(filepath.Join("go-testcov", "a", "b")))
+			})
+		})
+	})
+
+	Describe("cacheFile", func() {
+		It("uses a file per key", func() {
+			Expect(cacheFile("baselines", "a")).ToNot(Equal(cacheFile("baselines", "b")))
+			Expect(filepath.Dir(cacheFile("baselines", "a"))).To(Equal(cacheDirectory("baselines")))
+		})
+	})
+
+	Describe("runCache", func() {
+		It("removes the cache directory, including read-only build caches", func() {
+			withCacheDir(func(dir string) {
+				readOnly := cacheDirectory("worktrees", "base", "go-build", "00")
+				noError(os.MkdirAll(readOnly, 0700))
+				writeFile(filepath.Join(readOnly, "a"), "")
+				noError(os.Chmod(readOnly, 0500))
+				expectCommand(
+					func() int { return run([]string{"cache", "clean"}) },
+					[]interface{}{0, "", "removed " + cacheDirectory() + "\n"},
+				)
+				Expect(cacheDirectory()).ToNot(BeAnExistingFile())
+			})
+		})
+
+		It("prints the cache directory", func() {
+			withCacheDir(func(dir string) {
+				expectCommand(func() int { return run([]string{"cache", "dir"}) }, []interface{}{0, cacheDirectory() + "\n", ""})
+			})
+		})
+
+		It("shows usage", func() {
+			expectCommand(func() int { return run([]string{"cache"}) }, []interface{}{2, "", "Usage: go-testcov cache clean|dir\n"})
+		})
+	})
+})
@@ -0,0 +1 @@
+../checkstyle.go
\ No newline at end of file
@@ -0,0 +1,31 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("checkstyle", func() {
+	Describe("formatCheckstyle", func() {
+		It("groups findings with a location by file", func() {
+			Expect(formatCheckstyle(runResult{
+				findings: []finding{
+					{Section: Section{Path: "a.go", Line: 1, Column: 2, EndLine: 3, EndColumn: 4}, Severity: "error", Code: codeNewUntestedSection, Message: "new untested section introduced (2 current vs 0 configured)"},
+					{Section: Section{Path: "a.go", Line: 5, Column: 2, EndLine: 5, EndColumn: 9}, Severity: "error", Code: codeNewUntestedSection, Message: "new untested section introduced (2 current vs 0 configured)"},
+					staleBudgetFinding,
+					lowTotalCoverageFinding,
+				},
+			})).To(Equal(`<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="a.go">
+    <error line="1" column="2" severity="error" message="new untested section introduced (2 current vs 0 configured)" source="go-testcov.NEW_UNTESTED_SECTION"></error>
+    <error line="5" column="2" severity="error" message="new untested section introduced (2 current vs 0 configured)" source="go-testcov.NEW_UNTESTED_SECTION"></error>
+  </file>
+  <file name="b.go">
+    <error line="1" severity="warning" message="less untested sections (0 current vs 1 configured), decrement configured untested?" source="go-testcov.STALE_BUDGET"></error>
+  </file>
+</checkstyle>
+`))
+		})
+	})
+})
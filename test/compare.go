@@ -0,0 +1 @@
+../compare.go
\ No newline at end of file
@@ -0,0 +1,81 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+)
+
+var _ = Describe("compare", func() {
+	Describe("runCompareRefs", func() {
+		// fake go copies the coverage of the checked out ref to where it was asked to write coverage
+		withRefs := func(fn func()) {
+			withFakeGo("for last; do :; done; cp fixture.out \"$last\"", func() {
+				withoutEnv("GOPATH", func() {
+					gitCommand("init", "-q")
+					writeFile("foo.go", "package foo\na()\nb()\n")
+					writeFile("fixture.out", "mode: set\nfoo.go:2.1,2.5 1 1\nfoo.go:3.1,3.5 1 0\n")
+					gitCommand("add", "foo.go", "fixture.out")
+					gitCommand("commit", "-q", "-m", "base")
+
+					// b() moved down but is the same untested code, c() is new
+					writeFile("foo.go", "package foo\n\na()\nb()\nc()\n")
+					writeFile("bar.go", "package foo\nd()\n")
+					writeFile("fixture.out", "mode: set\nfoo.go:3.1,3.5 1 1\nfoo.go:4.1,4.5 1 0\nfoo.go:5.1,5.5 1 0\nbar.go:2.1,2.5 1 1\n")
+					gitCommand("add", "foo.go", "bar.go", "fixture.out")
+					gitCommand("commit", "-q", "-m", "head")
+					fn()
+				})
+			})
+		}
+
+		It("compares coverage of two refs", func() {
+			withRefs(func() {
+				expectCommand(
+					func() int { return run([]string{"compare-refs", "HEAD~1", "HEAD", "./..."}) },
+					[]interface{}{
+						0,
+						"",
+						"coverage HEAD~1 -> HEAD: 50.0% -> 50.0% (+0.0)\n" +
+							"bar.go new -> 100.0%\n" +
+							"foo.go 50.0% -> 33.3% (-16.7)\n" +
+							"new untested sections in HEAD:\nfoo.go:5.1,5.5\n",
+					},
+				)
+			})
+		})
+
+		It("does not report sections that are ignored", func() {
+			withRefs(func() {
+				writeFile("foo.go", "package foo\n\na()\nb()\nc() // untested section\n")
+				gitCommand("commit", "-q", "-a", "-m", "ignore")
+				expectCommand(
+					func() int { return run([]string{"compare-refs", "HEAD~2", "HEAD"}) },
+					[]interface{}{
+						0,
+						"",
+						"coverage HEAD~2 -> HEAD: 50.0% -> 50.0% (+0.0)\nbar.go new -> 100.0%\nfoo.go 50.0% -> 33.3% (-16.7)\n",
+					},
+				)
+			})
+		})
+
+		It("stops when tests fail", func() {
+			withFakeGo("exit 3", func() {
+				gitCommand("init", "-q")
+				writeFile("foo.go", "package foo\n")
+				gitCommand("add", "foo.go")
+				gitCommand("commit", "-q", "-m", "base")
+				expectCommand(
+					func() int { return run([]string{"compare-refs", "HEAD", "HEAD"}) },
+					[]interface{}{3, "", ""},
+				)
+			})
+		})
+
+		It("shows usage without refs", func() {
+			expectCommand(
+				func() int { return run([]string{"compare-refs", "main"}) },
+				[]interface{}{2, "", "Usage: go-testcov compare-refs BASE HEAD [go test arguments]\n"},
+			)
+		})
+	})
+})
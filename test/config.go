@@ -0,0 +1 @@
+../config.go
\ No newline at end of file
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("config", func() {
+	Describe("parseConfig", func() {
+		It("turns values, lists and flags into options", func() {
+			args, goTestArgs, err := parseConfig(".go-testcov.yml", "# policy\nmin-coverage: 85 # percent\n\nsplit-lines: true\nverbose: false\n"+
+				"format:\n  - json:testcov.json\n  - \"sarif:testcov.sarif\"\nforce-check: [a.go, 'b.go']\nargs: [./..., -race]\n")
+			noError(err)
+			Expect(args).To(Equal([]string{"--min-coverage=85", "--split-lines", "--format=json:testcov.json", "--format=sarif:testcov.sarif", "--force-check=a.go", "--force-check=b.go"}))
+			Expect(goTestArgs).To(Equal([]string{"./...", "-race"}))
+		})
+
+		It("keeps separators inside quotes and braces", func() {
+			args, _, err := parseConfig(".go-testcov.yml",
+				"budget-pattern: [a{1,2}/*.go, \"b, c.go\"] # comment\nexclude: \"x #y.go\" # comment\nforce-check: [it's.go] # comment\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"--budget-pattern=a{1,2}/*.go", "--budget-pattern=b, c.go", "--exclude=x #y.go", "--force-check=it's.go"}))
+		})
+
+		It("fails on unknown options", func() {
+			_, _, err := parseConfig(".go-testcov.yml", "\nnope: 1\n")
+			Expect(err).To(MatchError(".go-testcov.yml:2: unknown option nope"))
+		})
+
+		It("fails on flags that are not true or false", func() {
+			_, _, err := parseConfig(".go-testcov.yml", "verbose: yes\n")
+			Expect(err).To(MatchError(".go-testcov.yml:1: verbose is a flag, expected true or false but got yes"))
+		})
+
+		It("fails on lines it does not understand", func() {
+			_, _, err := parseConfig(".go-testcov.yml", "- a.go\n")
+			Expect(err).To(MatchError(".go-testcov.yml:1: expected \"option: value\" but got \"- a.go\""))
+			_, _, err = parseConfig(".go-testcov.yml", "min-coverage: 85\n  nested: a\n")
+			Expect(err).To(MatchError(".go-testcov.yml:2: expected \"option: value\" but got \"nested: a\""))
+		})
+
+		It("turns nested maps into key=value options", func() {
+			args, _, err := parseConfig(".go-testcov.yml", "allowance:\n  internal/legacy/**: 40 untested sections\n  \"cmd/*.go\": 2\n")
+			noError(err)
+			Expect(args).To(Equal([]string{"--allowance=internal/legacy/**=40 untested sections", "--allowance=cmd/*.go=2"}))
+		})
+	})
+
+	Describe("parseOptions", func() {
+		It("uses the config at the module root before the command line", func() {
+			withTempDir(func(dir string) {
+				writeFile(dir+"/go.mod", "module example.com/m\n")
+				writeFile(dir+"/.go-testcov.yml", "min-coverage: 85\nprecision: 2\nargs: [./...]\n")
+				noError(os.Mkdir(dir+"/pkg", 0700))
+				chDir(dir+"/pkg", func() {
+					opts, rest, err := parseOptions([]string{"--min-coverage=90"})
+					noError(err)
+					Expect(opts.minCoverage).To(Equal(90.0))
+					Expect(opts.precision).To(Equal(2))
+					Expect(rest).To(Equal([]string{"./..."}))
+
+					_, rest, err = parseOptions([]string{"-run", "Foo"})
+					noError(err)
+					Expect(rest).To(Equal([]string{"-run", "Foo"}))
+				})
+			})
+		})
+
+		It("reads helper packages", func() {
+			inTempDir(func() {
+				writeFile(".go-testcov.yml", "helpers: [./testutil/..., ./fakes]\n")
+				opts, _, err := parseOptions([]string{"--helpers=./mocks"})
+				noError(err)
+				Expect(opts.helpers).To(Equal([]string{"./testutil/...", "./fakes", "./mocks"}))
+			})
+		})
+
+		It("fails on invalid configs", func() {
+			inTempDir(func() {
+				writeFile(".go-testcov.yml", "min-coverage: lots\n")
+				_, _, err := parseOptions([]string{})
+				Expect(err).To(MatchError(".go-testcov.yml: invalid percentage lots, expected a number between 0 and 100"))
+			})
+		})
+	})
+})
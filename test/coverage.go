@@ -0,0 +1 @@
+../coverage.go
\ No newline at end of file
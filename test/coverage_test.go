@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("coverage", func() {
+	Describe("eachProfileLine", func() {
+		It("yields block lines without the mode line and empty lines", func() {
+			long := "foo/" + strings.Repeat("a", 100000) + ".go:1.2,1.3 1 0"
+			withTempFile("\nmode: set\nfoo.go:1.2,1.3 1 0\n\n"+long+"\n", func(file *os.File) {
+				lines := []string{}
+				eachProfileLine(file.Name(), func(line string) { lines = append(lines, line) })
+				Expect(lines).To(Equal([]string{"foo.go:1.2,1.3 1 0", long}))
+			})
+		})
+	})
+
+	Describe("statementCoverage", func() {
+		It("counts nothing for empty", func() {
+			withTempFile("", func(file *os.File) {
+				statements, covered := statementCoverage(file.Name(), options{})
+				Expect([]int{statements, covered}).To(Equal([]int{0, 0}))
+			})
+		})
+
+		It("counts statements of covered and uncovered blocks", func() {
+			withTempFile("mode: set\nfoo.go:1.2,3.4 3 1\nfoo.go:4.2,5.4 2 0\n", func(file *os.File) {
+				statements, covered := statementCoverage(file.Name(), options{})
+				Expect([]int{statements, covered}).To(Equal([]int{5, 3}))
+			})
+		})
+
+		It("merges blocks that are listed multiple times", func() {
+			withTempFile("mode: set\nfoo.go:1.2,3.4 3 0\nfoo.go:1.2,3.4 3 1\nfoo.go:4.2,5.4 2 0\nfoo.go:4.2,5.4 2 0\n", func(file *os.File) {
+				statements, covered := statementCoverage(file.Name(), options{})
+				Expect([]int{statements, covered}).To(Equal([]int{5, 3}))
+			})
+		})
+
+		It("skips generated files", func() {
+			withTempFile("mode: set\nfoo.go:1.2,3.4 3 1\nfoo_generated.go:4.2,5.4 2 0\n", func(file *os.File) {
+				statements, covered := statementCoverage(file.Name(), options{})
+				Expect([]int{statements, covered}).To(Equal([]int{3, 3}))
+			})
+		})
+
+		It("counts generated files that are forced to be checked", func() {
+			withTempFile("mode: set\nfoo.go:1.2,3.4 3 1\nfoo_generated.go:4.2,5.4 2 0\n", func(file *os.File) {
+				statements, covered := statementCoverage(file.Name(), options{forceCheck: []string{"foo_generated.go"}})
+				Expect([]int{statements, covered}).To(Equal([]int{5, 3}))
+			})
+		})
+	})
+
+	Describe("statementCoverageByPath", func() {
+		It("groups statements by path", func() {
+			withTempFile("mode: set\nfoo.go:1.2,3.4 3 1\nbar.go:4.2,5.4 2 0\nfoo.go:4.2,5.4 2 0\n", func(file *os.File) {
+				Expect(statementCoverageByPath(file.Name(), options{})).To(Equal(map[string]fileCoverage{
+					"foo.go": {statements: 5, covered: 3},
+					"bar.go": {statements: 2, covered: 0},
+				}))
+			})
+		})
+	})
+
+	Describe("checkFileCoverage", func() {
+		It("finds files below their required coverage", func() {
+			withoutEnv("GOPATH", func() {
+				inTempDir(func() {
+					writeFile("current.out", "mode: set\nfoo.go:1.2,3.4 1 1\nfoo.go:4.2,5.4 3 0\nlegacy/bar.go:4.2,5.4 1 1\nlegacy/bar.go:6.2,7.4 4 0\nbaz.go:1.2,3.4 1 1\n")
+					wd, err := os.Getwd()
+					noError(err)
+					opts := options{minFileCoverage: 50, precision: 1, fileCoverageThresholds: []fileCoverageThreshold{{"legacy/*.go", 20}}}
+					var findings []finding
+					stderr := captureStderr(func() { findings = checkFileCoverage("current.out", nil, wd, opts) })
+					Expect(stderr).To(Equal("foo.go has 25.0% coverage, below the required 50.0% (--min-file-coverage)\n"))
+					Expect(findings).To(Equal([]finding{{
+						Section:  Section{Path: "foo.go", Line: 1, Column: 1},
+						Severity: "error", Code: codeLowFileCoverage, Message: "foo.go has 25.0% coverage, below the required 50.0%",
+					}}))
+
+					Expect(checkFileCoverage("current.out", map[string]bool{wd + "/baz.go": true}, wd, opts)).To(BeEmpty())
+				})
+			})
+		})
+	})
+
+	Describe("printCoverageDelta", func() {
+		It("shows percentages of changed files before and after", func() {
+			withoutEnv("GOPATH", func() {
+				inTempDir(func() {
+					writeFile("base.out", "mode: set\nfoo.go:1.2,3.4 1 1\nfoo.go:4.2,5.4 1 0\nbar.go:4.2,5.4 1 0\n")
+					writeFile("current.out", "mode: set\nfoo.go:1.2,3.4 1 1\nfoo.go:4.2,5.4 1 1\nbar.go:4.2,5.4 1 0\nnew.go:1.2,3.4 1 0\n")
+					expectCommand(
+						func() int {
+							printCoverageDelta("current.out", map[string]bool{"/wd/foo.go": true, "/wd/new.go": true}, "/wd", options{baseline: "base.out", precision: 1})
+							return 0
+						},
+						[]interface{}{0, "", "coverage of changed files (baseline -> current):\nfoo.go 50.0% -> 100.0% (+50.0)\nnew.go new -> 0.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("prints nothing when no covered file changed", func() {
+			withoutEnv("GOPATH", func() {
+				inTempDir(func() {
+					writeFile("base.out", "mode: set\nfoo.go:1.2,3.4 1 1\n")
+					expectCommand(
+						func() int {
+							printCoverageDelta("base.out", map[string]bool{}, "/wd", options{baseline: "base.out", precision: 1})
+							return 0
+						},
+						[]interface{}{0, "", ""},
+					)
+				})
+			})
+		})
+	})
+
+	Describe("coveragePercent", func() {
+		It("calculates the percentage", func() {
+			Expect(coveragePercent(8, 2)).To(Equal(25.0))
+		})
+
+		It("is fully covered when there is nothing to cover", func() {
+			Expect(coveragePercent(0, 0)).To(Equal(100.0))
+		})
+	})
+
+	Describe("formatPercent", func() {
+		It("formats with the given decimals", func() {
+			Expect(formatPercent(81.25, 1)).To(Equal("81.2%"))
+			Expect(formatPercent(81.25, 0)).To(Equal("81%"))
+			Expect(formatPercent(100, 2)).To(Equal("100.00%"))
+		})
+
+		It("formats changes with a sign", func() {
+			Expect(formatPercentChange(1.5, 1)).To(Equal("+1.5"))
+			Expect(formatPercentChange(-0.25, 2)).To(Equal("-0.25"))
+		})
+	})
+
+	Describe("roundPercent", func() {
+		It("rounds to the given decimals", func() {
+			Expect(roundPercent(74.96, 1)).To(Equal(75.0))
+			Expect(roundPercent(74.96, 0)).To(Equal(75.0))
+			Expect(roundPercent(66.666, 2)).To(Equal(66.67))
+		})
+	})
+
+	Describe("newFilesWithoutTests", func() {
+		It("finds unbudgeted files the baseline does not have", func() {
+			withTempFile("mode: set\nold.go:1.2,1.3 1 1\n", func(file *os.File) {
+				files := map[string]unbudgetedFile{
+					"old.go": {displayPath: "old.go", readPath: "old.go", untested: 1, severity: "error"},
+					"new.go": {displayPath: "new.go", readPath: "new.go", untested: 2, severity: "warning"},
+				}
+				var findings []finding
+				stderr := captureStderr(func() { findings = newFilesWithoutTests(file.Name(), files, options{}) })
+				Expect(stderr).To(Equal("new.go is a new file without tests (2 untested sections and no budget), add tests for it\n"))
+				Expect(findings).To(Equal([]finding{{
+					Section:  Section{Path: "new.go", Line: 1, Column: 1},
+					Severity: "warning", Code: codeNewFileWithoutTests, Message: "new file without tests (2 untested sections and no budget)",
+				}}))
+			})
+		})
+	})
+})
@@ -0,0 +1 @@
+../deps.go
\ No newline at end of file
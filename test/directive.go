@@ -0,0 +1 @@
+../directive.go
\ No newline at end of file
@@ -0,0 +1,136 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("directive", func() {
+	Describe("parseDirective", func() {
+		expectDirective := func(line string, expected directive) {
+			found, ok, err := parseDirective(line)
+			ExpectWithOffset(1, err).To(BeNil())
+			ExpectWithOffset(1, ok).To(BeTrue())
+			ExpectWithOffset(1, found).To(Equal(expected))
+		}
+
+		expectNoDirective := func(line string) {
+			_, ok, err := parseDirective(line)
+			ExpectWithOffset(1, err).To(BeNil())
+			ExpectWithOffset(1, ok).To(BeFalse())
+		}
+
+		expectError := func(line string, message string) {
+			_, ok, err := parseDirective(line)
+			ExpectWithOffset(1, ok).To(BeFalse())
+			ExpectWithOffset(1, err).To(MatchError(message))
+		}
+
+		It("finds nothing in code and unrelated comments", func() {
+			expectNoDirective("foo()")
+			expectNoDirective("foo() // bar")
+			expectNoDirective("untested section")
+		})
+
+		It("finds nolint comments", func() {
+			expectDirective("foo() //nolint:testcov", directive{})
+			expectDirective("//nolint:errcheck,testcov // reason", directive{ownLine: true})
+			expectDirective("foo() //nolint:gocov", directive{})
+			expectNoDirective("foo() //nolint:errcheck")
+			expectNoDirective("foo() //nolint")
+			expectNoDirective("foo() // nolint:testcov is not machine readable")
+		})
+
+		It("finds nolint comments with configured names", func() {
+			opts, _, err := parseOptions([]string{"--nolint=coverage,cov"})
+			noError(err)
+			defer useOptions(opts)()
+			expectDirective("foo() //nolint:cov", directive{})
+			expectNoDirective("foo() //nolint:testcov")
+		})
+
+		It("finds coverage:ignore comments", func() {
+			expectDirective("foo() //coverage:ignore", directive{})
+			expectDirective("// coverage:ignore, only on windows", directive{ownLine: true})
+			expectNoDirective("foo() // coverage:ignored")
+		})
+
+		It("finds ignores with configured markers", func() {
+			opts, _, err := parseOptions([]string{"--ignore-marker=coverage:ignore", "--ignore-marker=no-cover"})
+			noError(err)
+			defer useOptions(opts)()
+			expectDirective("foo() // coverage:ignore", directive{})
+			expectDirective("//coverage:ignore, only on windows", directive{ownLine: true})
+			expectDirective("// no-cover because reasons", directive{ownLine: true})
+			expectDirective("// untested section", directive{ownLine: true})
+			expectNoDirective("foo() // coverage:ignored")
+			_, _, err = parseOptions([]string{"--ignore-marker= "})
+			Expect(err).To(MatchError("invalid ignore marker, expected the comment text like coverage:ignore"))
+		})
+
+		It("finds budgets with a configured pattern", func() {
+			opts, _, err := parseOptions([]string{`--budget-pattern=coverage-allowance:\s*(\S+)`})
+			noError(err)
+			defer useOptions(opts)()
+			expectDirective("// coverage-allowance: 3", directive{budget: true, count: 3, ownLine: true})
+			expectDirective("// untested sections: 2", directive{budget: true, count: 2, ownLine: true})
+			expectError("// coverage-allowance: many", "expected --budget-pattern to capture a number but got \"many\"")
+		})
+
+		It("ignores prose", func() {
+			expectNoDirective("// untested sectionless")
+			expectNoDirective("// no untested sections here")
+		})
+
+		It("parses ignores", func() {
+			expectDirective("foo() // untested section", directive{})
+			expectDirective("foo() // TODO untested section, because reasons", directive{})
+			expectDirective("\t// untested section", directive{ownLine: true})
+			expectDirective("// untested section because reasons", directive{ownLine: true})
+		})
+
+		It("parses budgets", func() {
+			expectDirective("// untested sections: 12", directive{budget: true, count: 12, ownLine: true})
+			expectDirective("package foo // untested sections:3 because", directive{budget: true, count: 3})
+			expectDirective("// untested sections (package): 12", directive{budget: true, packageBudget: true, count: 12, ownLine: true})
+		})
+
+		It("parses scopes", func() {
+			expectDirective("} else { // untested section: else", directive{scope: "else"})
+			expectDirective("// untested section: function, glue code", directive{scope: "function", ownLine: true})
+			expectDirective("// untested section: next 3 blocks", directive{scope: "next", count: 3, ownLine: true})
+			expectDirective("// untested section:next 1 block", directive{scope: "next", count: 1, ownLine: true})
+		})
+
+		It("finds regions", func() {
+			expectDirective("// untested section: begin", directive{scope: "begin", ownLine: true})
+			expectDirective("// untested section: end, platform glue", directive{scope: "end", ownLine: true})
+		})
+
+		It("fails on malformed budgets", func() {
+			expectError("// untested sections: many", "expected a number after \"untested sections:\"")
+			expectError("// untested sections (package):", "expected a number after \"untested sections (package):\"")
+		})
+
+		It("fails on malformed scopes", func() {
+			expectError("// untested section:", "expected \"untested section:\" to be followed by function, next N blocks, begin, end or one of if, else, for, range, switch, case, default, select, func, go, defer")
+			expectError("// untested section: nope", "expected \"untested section:\" to be followed by function, next N blocks, begin, end or one of if, else, for, range, switch, case, default, select, func, go, defer")
+			expectError("// untested section: next few blocks", "expected \"next N blocks\" but got \"next few blocks\"")
+			expectError("// untested section: next 3", "expected \"next N blocks\" but got \"next 3\"")
+		})
+	})
+
+	Describe("parseCriticalDirective", func() {
+		It("finds file and package scope", func() {
+			Expect(parseCriticalDirective("//testcov:critical")).To(Equal("file"))
+			Expect(parseCriticalDirective("\t//testcov:critical money handling")).To(Equal("file"))
+			Expect(parseCriticalDirective("//testcov:critical package")).To(Equal("package"))
+		})
+
+		It("ignores everything else", func() {
+			Expect(parseCriticalDirective("// testcov:critical")).To(Equal(""))
+			Expect(parseCriticalDirective("foo() //testcov:critical")).To(Equal(""))
+			Expect(parseCriticalDirective("//testcov:criticality")).To(Equal(""))
+		})
+	})
+})
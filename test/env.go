@@ -0,0 +1 @@
+../env.go
\ No newline at end of file
@@ -0,0 +1,59 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("env", func() {
+	Describe("interpolateEnv", func() {
+		It("replaces references with their value", func() {
+			withEnv("TESTCOV_A", "85", func() {
+				Expect(interpolateEnv("${TESTCOV_A}% of ${TESTCOV_A}")).To(Equal("85% of 85"))
+			})
+		})
+
+		It("uses defaults when the variable is unset or empty", func() {
+			withoutEnv("TESTCOV_A", func() {
+				Expect(interpolateEnv("${TESTCOV_A:-60}")).To(Equal("60"))
+			})
+			withEnv("TESTCOV_A", "", func() {
+				Expect(interpolateEnv("${TESTCOV_A:-60}")).To(Equal("60"))
+				Expect(interpolateEnv("a${TESTCOV_A}b")).To(Equal("ab"))
+			})
+		})
+
+		It("leaves other dollars alone", func() {
+			Expect(interpolateEnv("^x$ $HOME ${1}")).To(Equal("^x$ $HOME ${1}"))
+		})
+
+		It("fails on unset variables without default", func() {
+			withoutEnv("TESTCOV_A", func() {
+				_, err := interpolateEnv("${TESTCOV_A}")
+				Expect(err).To(MatchError("environment variable TESTCOV_A is not set, set it or give a default like ${TESTCOV_A:-value}"))
+			})
+		})
+	})
+
+	Describe("environmentArgs", func() {
+		It("turns GO_TESTCOV_ variables into options", func() {
+			withEnv("GO_TESTCOV_MIN_COVERAGE", "85", func() {
+				withEnv("GO_TESTCOV_SPLIT_LINES", "true", func() {
+					withEnv("GO_TESTCOV_VERBOSE", "false", func() {
+						args, names, err := environmentArgs()
+						noError(err)
+						Expect(args).To(Equal([]string{"--split-lines", "--min-coverage=85"}))
+						Expect(names).To(Equal([]string{"GO_TESTCOV_SPLIT_LINES", "GO_TESTCOV_MIN_COVERAGE"}))
+					})
+				})
+			})
+		})
+
+		It("fails on flags that are not true or false", func() {
+			withEnv("GO_TESTCOV_VERBOSE", "1", func() {
+				_, _, err := environmentArgs()
+				Expect(err).To(MatchError("GO_TESTCOV_VERBOSE: verbose is a flag, expected true or false but got 1"))
+			})
+		})
+	})
+})
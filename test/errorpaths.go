@@ -0,0 +1 @@
+../errorpaths.go
\ No newline at end of file
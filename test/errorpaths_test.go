@@ -0,0 +1,53 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("errorpaths", func() {
+	content := "package foo\n\nfunc a() error {\n\tif x {\n\t\treturn err\n\t}\n\tif y {\n\t\treturn nil\n\t}\n" +
+		"\treturn fmt.Errorf(\"a\")\n}\n\nfunc b() (int, error) {\n\treturn 0, io.ErrUnexpectedEOF\n}\n"
+
+	Describe("parseSourceFile", func() {
+		It("finds returns of errors", func() {
+			Expect(parseSourceFile("foo.go", content).errorReturns).To(Equal([]int{5, 10, 14}))
+		})
+	})
+
+	Describe("findUntestedErrorPaths", func() {
+		It("finds error returns inside the sections", func() {
+			source := parseSourceFile("foo.go", content)
+			sections := []Section{{Path: "foo.go", Line: 4, Column: 7, EndLine: 6, EndColumn: 3, Statements: 1}, {Path: "foo.go", Line: 7, Column: 7, EndLine: 9, EndColumn: 3, Statements: 1}}
+			Expect(findUntestedErrorPaths("foo.go", "/foo.go", sections, source)).To(Equal([]untestedErrorPath{{"foo.go", "/foo.go", 5, "return err"}}))
+		})
+	})
+
+	Describe("reportUntestedErrorPaths", func() {
+		paths := []untestedErrorPath{{"foo.go", "/foo.go", 5, "return err"}}
+
+		It("fails when there are more than allowed", func() {
+			stderr := captureStderr(func() {
+				findings, failed := reportUntestedErrorPaths(paths, 0)
+				Expect(failed).To(BeTrue())
+				Expect(findings).To(Equal([]finding{{
+					Section:  Section{Path: "/foo.go", Line: 5, Column: 1},
+					Severity: "error", Code: codeUntestedErrorPath, Message: "untested error path (1 current vs 0 allowed by --max-untested-error-paths)",
+				}}))
+			})
+			Expect(stderr).To(Equal("untested error paths (1 current vs 0 allowed by --max-untested-error-paths):\nfoo.go:5 return err\n"))
+		})
+
+		It("only warns within the threshold", func() {
+			captureStderr(func() {
+				findings, failed := reportUntestedErrorPaths(paths, 1)
+				Expect(failed).To(BeFalse())
+				Expect(findings[0].Severity).To(Equal("warning"))
+			})
+		})
+
+		It("prints nothing without error paths", func() {
+			Expect(captureStderr(func() { reportUntestedErrorPaths(nil, 0) })).To(Equal(""))
+		})
+	})
+})
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("events", func() {
+	withEventStream := func(fn func()) string {
+		var buffer bytes.Buffer
+		eventStream = &buffer
+		defer func() { eventStream = nil }()
+		withFakeClock(fn)
+		return buffer.String()
+	}
+
+	Describe("emitEvent", func() {
+		It("does nothing without --events", func() {
+			emitEvent(event{Event: "start"})
+		})
+	})
+
+	Describe("packageEventWriter", func() {
+		It("emits an event per finished package", func() {
+			Expect(withEventStream(func() {
+				writer := &packageEventWriter{}
+				_, _ = writer.Write([]byte("=== RUN TestA\n--- FAIL: TestA\nFAIL\nFAIL\texample.com/m/a\t0.1s\nok  \texample.com/m/b\t0"))
+				_, _ = writer.Write([]byte(".2s\n?   \texample.com/m/c\t[no test files]\n"))
+			})).To(Equal(`{"time":"1970-01-01T00:00:01Z","event":"package","package":"example.com/m/a","status":"FAIL"}
+{"time":"1970-01-01T00:00:02Z","event":"package","package":"example.com/m/b","status":"ok"}
+{"time":"1970-01-01T00:00:03Z","event":"package","package":"example.com/m/c","status":"skip"}
+`))
+		})
+	})
+
+	Describe("emitResultEvents", func() {
+		It("leaves out the coverage when go test failed", func() {
+			Expect(withEventStream(func() {
+				emitResultEvents(runResult{exitCode: 2, coverage: -1})
+			})).To(Equal(`{"time":"1970-01-01T00:00:01Z","event":"verdict","status":"FAIL"}` + "\n"))
+		})
+	})
+})
@@ -0,0 +1 @@
+../experimental.go
\ No newline at end of file
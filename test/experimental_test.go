@@ -0,0 +1,31 @@
+package main
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("experimental", func() {
+	Describe("experimentalUntil", func() {
+		It("is not experimental without marker", func() {
+			_, ok := experimentalUntil("foo.go", parseSourceFile("foo.go", "package foo\n"), options{experimentalDays: 30})
+			Expect(ok).To(BeFalse())
+		})
+
+		It("is experimental during the grace period", func() {
+			inTempDir(func() {
+				gitCommand("init", "-q")
+				writeFile("foo.go", "package foo\n//testcov:experimental\n")
+				source := parseSourceFile("foo.go", readFile("foo.go"))
+				until, ok := experimentalUntil("foo.go", source, options{experimentalDays: 30})
+				Expect(ok).To(BeTrue())
+				Expect(until).To(BeTemporally("~", time.Now().AddDate(0, 0, 30), time.Minute))
+
+				_, ok = experimentalUntil("foo.go", source, options{experimentalDays: 0})
+				Expect(ok).To(BeFalse())
+			})
+		})
+	})
+})
@@ -0,0 +1 @@
+../export.go
\ No newline at end of file
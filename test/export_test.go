@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("export", func() {
+	Describe("runExport", func() {
+		It("prints the diff with coverage markers", func() {
+			withFakeGo("for last; do :; done; printf 'mode: set\\nfoo.go:3.1,3.5 1 1\\nfoo.go:4.1,4.5 1 0\\n' > \"$last\"", func() {
+				withoutEnv("GOPATH", func() {
+					gitCommand("init", "-q")
+					writeFile("foo.go", "package foo\n\n")
+					gitCommand("add", "foo.go")
+					gitCommand("commit", "-q", "-m", "initial")
+					writeFile("foo.go", "package foo\n\na()\nb()\n")
+					exitCode := -1
+					stdout, stderr := captureAll(func() {
+						exitCode = run([]string{"export", "--format=unidiff-overlay", "--diff-base=HEAD", "./..."})
+					})
+					Expect([]interface{}{exitCode, stderr}).To(Equal([]interface{}{0, ""}))
+					Expect(stdout).To(HavePrefix("  diff --git a/foo.go b/foo.go\n"))
+					Expect(stdout).To(HaveSuffix("  @@ -1,2 +1,4 @@\n   package foo\n   \n+ +a()\n- +b()\n"))
+				})
+			})
+		})
+
+		It("prints debt density as csv", func() {
+			withFakeGo("for last; do :; done; printf 'mode: set\\nfoo.go:3.1,3.5 1 1\\nfoo.go:4.1,4.5 2 0\\nbar.go:3.1,3.5 1 1\\n' > \"$last\"", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo.go", "package foo\n\na()\nb()\n")
+					writeFile("bar.go", "package bar\n\na()\n")
+					expectCommand(
+						func() int { return run([]string{"export", "--format=heatmap-csv", "./..."}) },
+						[]interface{}{0, "path,lines,statements,untested,density\nfoo.go,4,3,2,50.00\nbar.go,3,1,0,0.00\n", ""},
+					)
+				})
+			})
+		})
+
+		It("prints debt density as json", func() {
+			withFakeGo("for last; do :; done; printf 'mode: set\\nfoo.go:3.1,3.5 1 0\\nmissing.go:3.1,3.5 1 0\\n' > \"$last\"", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo.go", "package foo\n\na()\n")
+					expectCommand(
+						func() int { return run([]string{"export", "--format=heatmap-json", "./..."}) },
+						[]interface{}{0, "[\n  {\n    \"path\": \"foo.go\",\n    \"lines\": 3,\n    \"statements\": 1,\n    \"untested\": 1,\n    \"density\": 33.33\n  }\n]\n", ""},
+					)
+				})
+			})
+		})
+
+		It("shows usage without format", func() {
+			expectCommand(
+				func() int { return run([]string{"export", "--diff-base=main"}) },
+				[]interface{}{2, "", "Usage: go-testcov export --format=unidiff-overlay --diff-base=REVISION [go test arguments]\n" +
+					"       go-testcov export --format=heatmap-csv|heatmap-json [go test arguments]\n"},
+			)
+		})
+	})
+
+	Describe("coverageOverlay", func() {
+		It("marks added lines by coverage and leaves everything else alone", func() {
+			diff := "--- a/foo.go\n+++ b/foo.go\n@@ -3,3 +10,3 @@ func a() {\n a()\n-b()\n+c()\n+d()\n+// comment\n"
+			covered := map[string]map[int]bool{"foo.go": {11: true, 12: false}}
+			Expect(coverageOverlay(diff, covered)).To(Equal(
+				"  --- a/foo.go\n  +++ b/foo.go\n  @@ -3,3 +10,3 @@ func a() {\n   a()\n  -b()\n+ +c()\n- +d()\n  +// comment\n",
+			))
+		})
+	})
+
+	Describe("lineHits", func() {
+		It("counts the most hits of any block on each line", func() {
+			withTempFile("mode: count\nfoo.go:1.1,2.5 1 0\nfoo.go:2.6,3.5 1 4\nfoo.go:3.6,3.9 1 2\n", func(file *os.File) {
+				Expect(lineHits(file.Name(), "/wd", options{})).To(Equal(
+					map[string]map[int]int{"foo.go": {1: 0, 2: 4, 3: 4}},
+				))
+			})
+		})
+	})
+
+	Describe("lineCoverage", func() {
+		It("marks lines covered when any block on them was covered", func() {
+			withTempFile("mode: set\nfoo.go:1.1,2.5 1 0\nfoo.go:2.6,3.5 1 1\n", func(file *os.File) {
+				Expect(lineCoverage(file.Name(), "/wd", options{})).To(Equal(
+					map[string]map[int]bool{"foo.go": {1: false, 2: true, 3: true}},
+				))
+			})
+		})
+	})
+})
@@ -0,0 +1 @@
+../generated.go
\ No newline at end of file
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("generated", func() {
+	Describe("hasGeneratedHeader", func() {
+		It("finds the header above the package clause", func() {
+			withoutEnv("GOPATH", func() {
+				inTempDir(func() {
+					writeFile("a.go", "// Copyright\n\n// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage a\n")
+					writeFile("b.go", "package b\n\n// Code generated by protoc-gen-go. DO NOT EDIT.\n")
+					writeFile("c.go", "// Code generated by hand, please edit.\npackage c\n")
+					Expect(hasGeneratedHeader("a.go")).To(BeTrue())
+					Expect(hasGeneratedHeader("b.go")).To(BeFalse())
+					Expect(hasGeneratedHeader("c.go")).To(BeFalse())
+					Expect(hasGeneratedHeader("missing.go")).To(BeFalse())
+				})
+			})
+		})
+
+		It("reads each file once per run in the directory the run started in", func() {
+			withoutEnv("GOPATH", func() {
+				inTempDir(func() {
+					writeFile("a.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage a\n")
+					restore := useOptions(options{})
+					Expect(hasGeneratedHeader("a.go")).To(BeTrue())
+					writeFile("a.go", "package a\n")
+					Expect(hasGeneratedHeader("a.go")).To(BeTrue())
+					noError(os.MkdirAll("sub", 0700))
+					chDir("sub", func() { Expect(hasGeneratedHeader("a.go")).To(BeTrue()) })
+					restore()
+					Expect(hasGeneratedHeader("a.go")).To(BeFalse())
+				})
+			})
+		})
+	})
+})
@@ -0,0 +1 @@
+../github.go
\ No newline at end of file
@@ -0,0 +1,30 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("github", func() {
+	Describe("formatGithub", func() {
+		It("has a workflow command per finding", func() {
+			Expect(formatGithub(runResult{
+				findings: []finding{
+					untestedSectionFinding,
+					staleBudgetFinding,
+					lowTotalCoverageFinding,
+				},
+			})).To(Equal(
+				"::error file=a.go,line=1,col=2,endLine=3,endColumn=4,title=go-testcov NEW_UNTESTED_SECTION::new untested section introduced (1 current vs 0 configured)\n" +
+					"::warning file=b.go,line=1,title=go-testcov STALE_BUDGET::less untested sections (0 current vs 1 configured), decrement configured untested?\n" +
+					"::error title=go-testcov LOW_TOTAL_COVERAGE::total coverage 50.0%25 is below the required 75.0%25\n",
+			))
+		})
+
+		It("escapes properties and messages", func() {
+			Expect(formatGithub(runResult{findings: []finding{{Section: Section{Path: "a,b:c.go", Line: 1}, Severity: "error", Code: "X", Message: "100%\nsure"}}})).To(Equal(
+				"::error file=a%2Cb%3Ac.go,line=1,title=go-testcov X::100%25%0Asure\n",
+			))
+		})
+	})
+})
@@ -0,0 +1 @@
+../gitlab.go
\ No newline at end of file
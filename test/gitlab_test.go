@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("gitlab", func() {
+	Describe("formatGitlab", func() {
+		It("has an issue per finding with a location", func() {
+			untested := untestedSectionFinding
+			untested.Function = "run"
+			Expect(formatGitlab(runResult{
+				findings: []finding{
+					untested,
+					staleBudgetFinding,
+					lowTotalCoverageFinding,
+				},
+			})).To(Equal(`[
+  {
+    "description": "new untested section introduced (1 current vs 0 configured)",
+    "check_name": "NEW_UNTESTED_SECTION",
+    "fingerprint": "` + sha256Hex("NEW_UNTESTED_SECTION a.go run 0") + `",
+    "severity": "major",
+    "location": {
+      "path": "a.go",
+      "lines": {
+        "begin": 1,
+        "end": 3
+      }
+    }
+  },
+  {
+    "description": "less untested sections (0 current vs 1 configured), decrement configured untested?",
+    "check_name": "STALE_BUDGET",
+    "fingerprint": "` + sha256Hex("STALE_BUDGET b.go  0") + `",
+    "severity": "minor",
+    "location": {
+      "path": "b.go",
+      "lines": {
+        "begin": 1
+      }
+    }
+  }
+]
+`))
+		})
+
+		It("keeps fingerprints when code above moves", func() {
+			fingerprints := func(lines ...int) (found []string) {
+				result := runResult{}
+				for _, line := range lines {
+					result.findings = append(result.findings, finding{Section: Section{Path: "a.go", Line: line, Function: "run"}, Severity: "error", Code: codeNewUntestedSection})
+				}
+				var issues []gitlabIssue
+				noError(json.Unmarshal([]byte(formatGitlab(result)), &issues))
+				for _, issue := range issues {
+					found = append(found, issue.Fingerprint)
+				}
+				return found
+			}
+			Expect(fingerprints(3, 7)).To(Equal(fingerprints(13, 17)))
+			Expect(fingerprints(3, 7)).To(Equal([]string{sha256Hex("NEW_UNTESTED_SECTION a.go run 0"), sha256Hex("NEW_UNTESTED_SECTION a.go run 1")}))
+		})
+
+		It("is an empty list without findings", func() {
+			Expect(formatGitlab(runResult{})).To(Equal("[]\n"))
+		})
+	})
+})
+
+func sha256Hex(content string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+}
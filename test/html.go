@@ -0,0 +1 @@
+../html.go
\ No newline at end of file
@@ -0,0 +1,44 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("html", func() {
+	Describe("formatHtml", func() {
+		It("shows the files, findings and annotated source", func() {
+			inTempDir(func() {
+				writeFile("a.go", "package a\n\nfunc b() {\n\tc()\n}\n")
+				content := formatHtml(runResult{
+					exitCode:  1,
+					coverage:  50,
+					precision: 2,
+					findings:  []finding{{Section: Section{Path: "a.go", Line: 4}, Severity: "error", Code: codeNewUntestedSection, Message: "new untested section introduced"}},
+					files:     []fileResult{{Path: "a.go", Untested: 1, Configured: 0, Scope: "file", Status: "failed"}},
+					lines:     map[string]map[int]int{"a.go": {3: 1, 4: 0}, "missing.go": {1: 1}},
+				})
+				Expect(content).To(ContainSubstring("<h1>go-testcov FAIL</h1>\n<p>coverage 50.00%</p>"))
+				Expect(content).To(ContainSubstring(`<tr class="failed"><td><a href="#a.go">a.go</a></td><td>1</td><td>0</td><td>file</td><td>failed</td></tr>`))
+				Expect(content).To(ContainSubstring(`<li class="error">a.go:4: NEW_UNTESTED_SECTION new untested section introduced</li>`))
+				Expect(content).To(ContainSubstring("<pre>\n" +
+					"<span class=\"\"><span class=\"line\">    1</span> package a</span>\n" +
+					"<span class=\"\"><span class=\"line\">    2</span> </span>\n" +
+					"<span class=\"covered\"><span class=\"line\">    3</span> func b() {</span>\n" +
+					"<span class=\"untested\"><span class=\"line\">    4</span> \tc()</span>\n" +
+					"<span class=\"\"><span class=\"line\">    5</span> }</span>\n" +
+					"</pre>"))
+				Expect(content).ToNot(ContainSubstring("missing.go</h2>"))
+			})
+		})
+
+		It("escapes source", func() {
+			inTempDir(func() {
+				writeFile("a.go", "a := b < c\n")
+				content := formatHtml(runResult{coverage: -1, lines: map[string]map[int]int{"a.go": {1: 1}}})
+				Expect(content).To(ContainSubstring("<h1>go-testcov PASS</h1>\n<p>coverage unknown</p>"))
+				Expect(content).To(ContainSubstring("a := b &lt; c"))
+			})
+		})
+	})
+})
@@ -0,0 +1 @@
+../ignore.go
\ No newline at end of file
@@ -0,0 +1,82 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ignore", func() {
+	Describe("inlineIgnores", func() {
+		sections := []Section{
+			NewSection("foo.go:4.2,4.5 1 0"),
+			NewSection("foo.go:5.2,5.5 1 0"),
+			NewSection("foo.go:6.2,6.5 1 0"),
+			NewSection("foo.go:9.2,9.5 1 0"),
+		}
+
+		It("ignores nothing without comments", func() {
+			source := parseSourceFile("foo.go", "package foo\n\nfunc a() {\n\ta()\n\ta()\n\ta()\n}\nfunc b() {\n\tb()\n}\n")
+			Expect(inlineIgnores(sections, source)).To(Equal(map[Section]int{}))
+		})
+
+		It("ignores sections with nolint comments", func() {
+			source := parseSourceFile("foo.go", "package foo\n\nfunc a() {\n\ta() //nolint:testcov\n\ta()\n\ta()\n}\nfunc b() {\n\tb()\n}\n")
+			Expect(inlineIgnores(sections, source)).To(Equal(map[Section]int{sections[0]: 4}))
+		})
+
+		It("ignores all sections of the function", func() {
+			source := parseSourceFile("foo.go", "package foo\n\nfunc a() { // untested section: function\n\ta()\n\ta()\n\ta()\n}\nfunc b() {\n\tb()\n}\n")
+			Expect(inlineIgnores(sections, source)).To(Equal(map[Section]int{sections[0]: 3, sections[1]: 3, sections[2]: 3}))
+		})
+
+		It("ignores all sections of the function from its doc comment", func() {
+			source := parseSourceFile("foo.go", "package foo\n\n// untested section: function\nfunc a() {\n\ta()\n\ta()\n}\nfunc b() {\n\tb()\n}\n")
+			Expect(inlineIgnores(sections[:3], source)).To(Equal(map[Section]int{sections[0]: 3, sections[1]: 3, sections[2]: 3}))
+		})
+
+		It("ignores all sections of the function from an untested section comment in its doc comment", func() {
+			source := parseSourceFile("foo.go", "package foo\n\n// a is glue, untested section\nfunc a() {\n\ta()\n\ta()\n}\nfunc b() {\n\tb()\n}\n")
+			sections := []Section{NewSection("foo.go:4.10,5.5 1 0"), NewSection("foo.go:6.2,6.5 1 0"), NewSection("foo.go:9.2,9.5 1 0")}
+			Expect(inlineIgnores(sections, source)).To(Equal(map[Section]int{sections[0]: 3, sections[1]: 3}))
+		})
+
+		It("ignores the next sections", func() {
+			source := parseSourceFile("foo.go", "package foo\n\nfunc a() {\n\ta() // untested section: next 2 blocks\n\ta()\n\ta()\n}\nfunc b() {\n\tb()\n}\n")
+			Expect(inlineIgnores(sections, source)).To(Equal(map[Section]int{sections[0]: 4, sections[1]: 4}))
+		})
+
+		It("ignores all sections between begin and end", func() {
+			source := parseSourceFile("foo.go", "package foo\n\nfunc a() {\n\ta()\n\t// untested section: begin\n\ta()\n}\n// untested section: end\nfunc b() {\n\tb()\n}\n")
+			Expect(inlineIgnores(sections, source)).To(Equal(map[Section]int{sections[1]: 5, sections[2]: 5}))
+		})
+
+		It("does not count sections that are already ignored towards the next sections", func() {
+			source := parseSourceFile("foo.go", "package foo\n\nfunc a() {\n\t// untested section: next 2 blocks\n\ta() // untested section\n\ta()\n\ta()\n\ta()\n}\n")
+			sections := []Section{
+				NewSection("foo.go:5.2,5.5 1 0"),
+				NewSection("foo.go:6.2,6.5 1 0"),
+				NewSection("foo.go:7.2,7.5 1 0"),
+				NewSection("foo.go:8.2,8.5 1 0"),
+			}
+			Expect(inlineIgnores(sections, source)).To(Equal(map[Section]int{sections[0]: 5, sections[1]: 4, sections[2]: 4}))
+		})
+	})
+
+	Describe("sectionOpener", func() {
+		source := sourceFile{lines: []string{"func a() {", "\tif x { a() } else { b() }", "\tb()", "}"}}
+
+		It("finds the code before the section on the same line", func() {
+			Expect(sectionOpener(Section{Line: 2, Column: 9}, source)).To(Equal("\tif x { "))
+			Expect(sectionOpener(Section{Line: 2, Column: 22}, source)).To(Equal(" else { "))
+		})
+
+		It("uses the header when the section starts on its own line", func() {
+			source := sourceFile{lines: []string{"func a(", "\tx int,", ") {", "\tb()", "}"}, headerStarts: map[int]int{4: 1}}
+			Expect(sectionOpener(Section{Line: 4, Column: 2}, source)).To(Equal("func a(\n\tx int,\n) {"))
+		})
+
+		It("is empty when the section is outside of the file", func() {
+			Expect(sectionOpener(Section{Line: 5, Column: 2}, source)).To(Equal(""))
+		})
+	})
+})
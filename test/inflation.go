@@ -0,0 +1 @@
+../inflation.go
\ No newline at end of file
@@ -0,0 +1,108 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"os"
+)
+
+var _ = Describe("inflation", func() {
+	Describe("checkBudgetIncrease", func() {
+		It("finds increased budgets and added ignores", func() {
+			inTempDir(func() {
+				gitCommand("init", "-q")
+				writeFile("a.go", "// untested sections: 1\na() // untested section\n")
+				writeFile("b.go", "// untested sections: 3\n")
+				gitCommand("add", ".")
+				gitCommand("commit", "-q", "-m", "initial")
+				writeFile("a.go", "// untested sections: 2\n\na() // untested section\nb() // untested section\n")
+				writeFile("b.go", "// untested sections: 2\n")
+				writeFile("c.go", "// untested sections (package): 1\n")
+				writeFile("c_test.go", "// untested sections: 1\n")
+
+				var findings []finding
+				stderr := captureStderr(func() {
+					findings = checkBudgetIncrease("HEAD", git{}, []string{"c_test.go", "c.go", "b.go", "a.go", "deleted.go"}, false, nil)
+				})
+				Expect(stderr).To(Equal(
+					"a.go:1: increases untested sections from 1 to 2 (--forbid-budget-increase)\n" +
+						"a.go:4: adds an untested section comment (--forbid-budget-increase)\n" +
+						"c.go:1: increases untested sections from 0 to 1 (--forbid-budget-increase)\n",
+				))
+				Expect(findings).To(Equal([]finding{
+					{Section: Section{Path: "a.go", Line: 1, Column: 1}, Severity: "error", Code: codeBudgetIncrease, Message: "increases untested sections from 1 to 2"},
+					{Section: Section{Path: "a.go", Line: 4, Column: 1}, Severity: "error", Code: codeBudgetIncrease, Message: "adds an untested section comment"},
+					{Section: Section{Path: "c.go", Line: 1, Column: 1}, Severity: "error", Code: codeBudgetIncrease, Message: "increases untested sections from 0 to 1"},
+				}))
+			})
+		})
+
+		It("only warns when allowed", func() {
+			inTempDir(func() {
+				gitCommand("init", "-q")
+				gitCommand("commit", "-q", "--allow-empty", "-m", "initial")
+				writeFile("a.go", "a() // untested section\n")
+				var findings []finding
+				stderr := captureStderr(func() {
+					findings = checkBudgetIncrease("HEAD", git{}, []string{"a.go"}, true, nil)
+				})
+				Expect(stderr).To(Equal("a.go:1: adds an untested section comment (allowed with --allow-budget-increase)\n"))
+				Expect(findings[0].Severity).To(Equal("warning"))
+			})
+		})
+	})
+
+	Describe("checkNewIgnores", func() {
+		It("finds added ignores but not increased budgets", func() {
+			inTempDir(func() {
+				gitCommand("init", "-q")
+				writeFile("a.go", "// untested sections: 1\n")
+				gitCommand("add", ".")
+				gitCommand("commit", "-q", "-m", "initial")
+				writeFile("a.go", "// untested sections: 2\na() // untested section\n")
+				var findings []finding
+				stderr := captureStderr(func() {
+					findings = checkNewIgnores("HEAD", git{}, []string{"a.go"}, false, nil)
+				})
+				Expect(stderr).To(Equal("a.go:2: adds an untested section comment (--forbid-new-ignores)\n"))
+				Expect(findings).To(Equal([]finding{
+					{Section: Section{Path: "a.go", Line: 2, Column: 1}, Severity: "error", Code: codeBudgetIncrease, Message: "adds an untested section comment"},
+				}))
+
+				stderr = captureStderr(func() {
+					findings = checkNewIgnores("HEAD", git{}, []string{"a.go"}, true, nil)
+				})
+				Expect(stderr).To(Equal("a.go:2: adds an untested section comment (allowed with --allow-new-ignores)\n"))
+				Expect(findings[0].Severity).To(Equal("warning"))
+			})
+		})
+
+		It("allows ignores in allowlisted files", func() {
+			inTempDir(func() {
+				gitCommand("init", "-q")
+				gitCommand("commit", "-q", "--allow-empty", "-m", "initial")
+				noError(os.Mkdir("legacy", 0700))
+				writeFile("legacy/a.go", "a() // untested section\n")
+				Expect(checkNewIgnores("HEAD", git{}, []string{"legacy/a.go"}, false, []string{"legacy/*.go"})).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("parseIgnoreAllowlist", func() {
+		It("parses globs and skips comments", func() {
+			inTempDir(func() {
+				writeFile("allowlist", "# generated clients\n\nclient/*.go\n legacy/*.go \n")
+				globs, err := parseIgnoreAllowlist("allowlist")
+				noError(err)
+				Expect(globs).To(Equal([]string{"client/*.go", "legacy/*.go"}))
+			})
+		})
+
+		It("fails on missing files", func() {
+			inTempDir(func() {
+				_, err := parseIgnoreAllowlist("allowlist")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
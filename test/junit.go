@@ -0,0 +1 @@
+../junit.go
\ No newline at end of file
@@ -0,0 +1,35 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("junit", func() {
+	Describe("formatJunit", func() {
+		It("has a test case per file and run failure", func() {
+			Expect(formatJunit(runResult{
+				findings: []finding{
+					untestedSectionFinding,
+					lowTotalCoverageFinding,
+				},
+				files: []fileResult{
+					{Path: "a.go", Untested: 1, Configured: 0, Scope: "file", Status: "failed"},
+					{Path: "b.go", Untested: 1, Configured: 1, Scope: "file", Status: "ok"},
+				},
+			})).To(Equal(`<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="go-testcov" tests="3" failures="2">
+    <testcase classname="go-testcov" name="a.go">
+      <failure message="1 untested sections but 0 configured">a.go:1.2,3.4: new untested section introduced (1 current vs 0 configured)</failure>
+    </testcase>
+    <testcase classname="go-testcov" name="b.go"></testcase>
+    <testcase classname="go-testcov" name="LOW_TOTAL_COVERAGE">
+      <failure message="total coverage 50.0% is below the required 75.0%"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`))
+		})
+	})
+})
@@ -0,0 +1 @@
+../lcov.go
\ No newline at end of file
@@ -0,0 +1,20 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("lcov", func() {
+	Describe("formatLcov", func() {
+		It("has a record with the hits of each line per file", func() {
+			Expect(formatLcov(runResult{
+				lines: map[string]map[int]int{"b.go": {3: 1}, "a.go": {5: 0, 2: 3}},
+			})).To(Equal("TN:\nSF:a.go\nDA:2,3\nDA:5,0\nLF:2\nLH:1\nend_of_record\nTN:\nSF:b.go\nDA:3,1\nLF:1\nLH:1\nend_of_record\n"))
+		})
+
+		It("is empty without lines", func() {
+			Expect(formatLcov(runResult{})).To(Equal(""))
+		})
+	})
+})
@@ -0,0 +1 @@
+../lint.go
\ No newline at end of file
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("lint", func() {
+	Describe("lintInlineIgnores", func() {
+		It("accepts well placed comments", func() {
+			inTempDir(func() {
+				writeFile("foo.go", "package foo\nfunc foo() {\n\t// untested section\n\tbar() // untested section\n}\n")
+				Expect(lintInlineIgnores("foo.go")).To(BeNil())
+			})
+		})
+
+		It("flags comments inside of strings", func() {
+			inTempDir(func() {
+				writeFile("foo.go", "package foo\nvar a = \"// untested section\"\nvar b = `\n// untested section`\n")
+				Expect(lintInlineIgnores("foo.go")).To(Equal([]string{
+					"2:9: untested section comment is inside a string literal",
+					"3:9: untested section comment is inside a string literal",
+				}))
+			})
+		})
+
+		It("flags comments after brace only lines", func() {
+			inTempDir(func() {
+				writeFile("foo.go", "package foo\nfunc foo() {\n\tif a {\n\t} // untested section\n}\n")
+				Expect(lintInlineIgnores("foo.go")).To(Equal([]string{
+					"4:4: untested section comment trails a line with only a brace, put it inside the block it should ignore",
+				}))
+			})
+		})
+
+		It("flags nolint comments like untested section comments", func() {
+			inTempDir(func() {
+				writeFile("foo_test.go", "package foo\n//nolint:testcov\n")
+				Expect(lintInlineIgnores("foo_test.go")).To(Equal([]string{
+					"2:1: untested section comment is in a _test.go file, which never has coverage",
+				}))
+			})
+		})
+
+		It("flags comments in test files", func() {
+			inTempDir(func() {
+				writeFile("foo_test.go", "package foo\n// untested section\n")
+				Expect(lintInlineIgnores("foo_test.go")).To(Equal([]string{
+					"2:1: untested section comment is in a _test.go file, which never has coverage",
+				}))
+			})
+		})
+	})
+
+	Describe("printInlineIgnoreProblems", func() {
+		It("prints problems of go files in the given directories", func() {
+			inTempDir(func() {
+				noError(os.MkdirAll("pkg/nested", 0700))
+				writeFile("pkg/foo.go", "package foo\n")
+				writeFile("pkg/foo_test.go", "package foo\n// untested section\n")
+				writeFile("pkg/README", "// untested section\n")
+				expectCommand(
+					func() int {
+						printInlineIgnoreProblems(map[string]string{"display": "pkg"})
+						return 0
+					},
+					[]interface{}{0, "", "display/foo_test.go:2:1: untested section comment is in a _test.go file, which never has coverage\n"},
+				)
+			})
+		})
+	})
+})
@@ -27,7 +27,7 @@ var _ = Describe("go-testcov", func() {
 							main()
 							return exitCode
 						},
-						[]interface{}{0, "go test some arg -coverprofile coverage.out\n", ""},
+						[]interface{}{0, "go test some arg -coverprofile coverage.out\n", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
 					)
 				})
 			})
@@ -53,17 +53,17 @@ var _ = Describe("go-testcov", func() {
 				writeFile("foo", "")
 				expectCommand(
 					runGoTestWithCoverage,
-					[]interface{}{0, "go test hello world -coverprofile coverage.out\n", ""},
+					[]interface{}{0, "go test hello world -coverprofile coverage.out\n", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
 				)
 			})
 		})
 
-		It("fails without adding noise", func() {
+		It("fails with only a summary", func() {
 			withFakeGo("touch coverage.out\nexit 15", func() {
 				writeFile("foo", "")
 				expectCommand(
 					runGoTestWithCoverage,
-					[]interface{}{15, "", ""},
+					[]interface{}{15, "", "go-testcov: FAIL go_test_exit=15\n"},
 				)
 			})
 		})
@@ -73,7 +73,7 @@ var _ = Describe("go-testcov", func() {
 				writeFile("foo", "")
 				expectCommand(
 					runGoTestWithCoverage,
-					[]interface{}{0, "", ""},
+					[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
 				)
 			})
 		})
@@ -85,7 +85,7 @@ var _ = Describe("go-testcov", func() {
 					writeFile("coverage.out", "head\ntest 0")
 					expectCommand(
 						runGoTestWithCoverage,
-						[]interface{}{0, "", ""},
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
 					)
 				})
 			})
@@ -97,19 +97,537 @@ var _ = Describe("go-testcov", func() {
 					writeFile(joinPath(goPath, "src", "foo"), "")
 					expectCommand(
 						runGoTestWithCoverage,
-						[]interface{}{1, "", "foo new untested sections introduced (1 current vs 0 configured)\nfoo:1.2,1.3\n"},
+						[]interface{}{1, "", "foo new untested sections introduced (1 current vs 0 configured)\nfoo:1.2,1.3\ngo-testcov: FAIL new_untested=1 files=1 coverage=100.0%\n"},
 					)
 				})
 			})
 		})
 
+		It("writes lcov tracefiles", func() {
+			withFakeGo("echo 'mode: count' > coverage.out; echo foo:1.2,2.3 1 0 >> coverage.out; echo foo:3.2,3.5 1 2 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "// untested sections: 1\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--output=lcov=coverage.lcov"}) },
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=50.0%\n"},
+					)
+					Expect(readFile("coverage.lcov")).To(Equal("TN:\nSF:foo\nDA:1,0\nDA:2,0\nDA:3,2\nLF:3\nLH:1\nend_of_record\n"))
+				})
+			})
+		})
+
+		It("streams events", func() {
+			withFakeGo("echo 'mode: set' > coverage.out; echo foo:1.2,1.3 1 0 >> coverage.out; printf 'ok  \\tfoo\\t0.1s\\n'", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "")
+					withFakeClock(func() {
+						expectCommand(
+							func() int { return runGoTestAndCheckCoverage([]string{"--events=events.ndjson", "./..."}) },
+							[]interface{}{1, "ok  \tfoo\t0.1s\n", "foo new untested sections introduced (1 current vs 0 configured)\nfoo:1.2,1.3\ngo-testcov: FAIL new_untested=1 files=1 coverage=0.0%\n"},
+						)
+					})
+					Expect(readFile("events.ndjson")).To(Equal(`{"time":"1970-01-01T00:00:01Z","event":"start","args":["./..."]}
+{"time":"1970-01-01T00:00:03Z","event":"package","package":"foo","status":"ok"}
+{"time":"1970-01-01T00:00:11Z","event":"section","path":"foo","line":1,"column":2,"endLine":1,"endColumn":3}
+{"time":"1970-01-01T00:00:12Z","event":"file","path":"foo","untested":1,"configured":0,"status":"failed"}
+{"time":"1970-01-01T00:00:13Z","event":"verdict","status":"FAIL","coverage":0}
+`))
+				})
+			})
+		})
+
+		It("folds the coverage of subprocesses into the profile", func() {
+			script := `case "$1" in
+test) echo 'mode: set' > coverage.out; echo foo:1.2,1.3 1 0 >> coverage.out; echo foo:2.2,2.3 1 0 >> coverage.out; touch "$GO_TESTCOV_COVERDIR/covcounters";;
+tool) for arg in "$@"; do case "$arg" in -o=*) printf 'mode: set\nfoo:1.2,1.3 1 1\n' > "${arg#-o=}";; esac; done;;
+esac`
+			withFakeGo(script, func() {
+				withoutEnv("GOPATH", func() {
+					withoutEnv("GOCOVERDIR", func() {
+						withoutEnv("GO_TESTCOV_COVERDIR", func() {
+							writeFile("foo", "")
+							expectCommand(
+								func() int { return runGoTestAndCheckCoverage([]string{"--subprocess-coverage"}) },
+								[]interface{}{1, "", "foo new untested sections introduced (1 current vs 0 configured)\nfoo:2.2,2.3\ngo-testcov: FAIL new_untested=1 files=1 coverage=50.0%\n"},
+							)
+							_, found := os.LookupEnv("GO_TESTCOV_COVERDIR")
+							Expect(found).To(BeFalse())
+						})
+					})
+				})
+			})
+		})
+
+		It("folds the coverage of real instrumented binaries the tests run", func() {
+			if !goVersionAtLeast(20) {
+				Skip("building binaries with -cover needs go 1.20")
+			}
+			withoutEnv("GOCOVERDIR", func() {
+				withoutEnv("GO_TESTCOV_COVERDIR", func() {
+					inTempDir(func() {
+						writeFile("go.mod", "module example.com/sub\n\ngo 1.20\n")
+						noError(os.Mkdir("hello", 0700))
+						writeFile("hello/main.go", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n")
+						writeFile("hello/main_test.go", `package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinary(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "hello")
+	if output, err := exec.Command("go", "build", "-cover", "-o", binary, ".").CombinedOutput(); err != nil {
+		t.Fatal(string(output))
+	}
+	command := exec.Command(binary)
+	if directory := os.Getenv("GO_TESTCOV_COVERDIR"); directory != "" {
+		command.Env = append(os.Environ(), "GOCOVERDIR="+directory)
+	}
+	if output, err := command.Output(); err != nil || string(output) != "hi\n" {
+		t.Fatal(string(output), err)
+	}
+}
+`)
+						exitCode := -1
+						stderr := captureStderr(func() {
+							captureStdout(func() { exitCode = runGoTestAndCheckCoverage([]string{"--subprocess-coverage", "-count=1", "./..."}) })
+						})
+						Expect(stderr).To(ContainSubstring("go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"))
+						Expect(exitCode).To(Equal(0))
+
+						// counters written somewhere go-testcov does not read are not folded in
+						noError(os.Mkdir("elsewhere", 0700))
+						wd, err := os.Getwd()
+						noError(err)
+						withEnv("GO_TESTCOV_COVERDIR", joinPath(wd, "elsewhere"), func() {
+							stderr = captureStderr(func() {
+								captureStdout(func() { exitCode = runGoTestAndCheckCoverage([]string{"-count=1", "./..."}) })
+							})
+						})
+						Expect(stderr).To(ContainSubstring("hello/main.go new untested sections introduced"))
+						Expect(exitCode).To(Equal(1))
+					})
+				})
+			})
+		})
+
+		It("fails when subprocess coverage can not be converted", func() {
+			withFakeGo(`if [ "$1" = test ]; then echo 'mode: set' > coverage.out; touch "$GO_TESTCOV_COVERDIR/covcounters"; else echo broken; exit 3; fi`, func() {
+				expectCommand(
+					func() int { return runGoTestAndCheckCoverage([]string{"--subprocess-coverage"}) },
+					[]interface{}{3, "", "converting subprocess coverage failed:\nbroken\ngo-testcov: FAIL go_test_exit=3\n"},
+				)
+			})
+		})
+
+		It("runs the tests through gotestsum", func() {
+			withFakeGo("exit 1", func() {
+				withFakeCommand("gotestsum", "echo gotestsum \"$@\"; echo header > coverage.out; echo foo:1.2,1.3 1 0 >> coverage.out", func() {
+					withoutEnv("GOPATH", func() {
+						writeFile("foo", "// untested sections: 1\n")
+						expectCommand(
+							func() int { return runGoTestAndCheckCoverage([]string{"--via-gotestsum", "./..."}) },
+							[]interface{}{0, "gotestsum -- ./... -coverprofile coverage.out\n", "go-testcov: PASS new_untested=0 files=0 coverage=0.0%\n"},
+						)
+					})
+				})
+			})
+		})
+
+		It("adds the coverage verdict to go test -json output", func() {
+			withFakeGo("echo '{\"Action\":\"pass\",\"Package\":\"foo\"}'; echo header > coverage.out; echo foo:1.2,1.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "")
+					stdout := captureStdout(func() { runGoTestAndCheckCoverage([]string{"-json"}) })
+					Expect(stdout).To(HavePrefix("{\"Action\":\"pass\",\"Package\":\"foo\"}\n"))
+					Expect(stdout).To(ContainSubstring("\"Output\":\"foo:1: new untested section introduced (1 current vs 0 configured)\\n\""))
+					Expect(stdout).To(MatchRegexp(`"Action":"fail","Package":"go-testcov","Elapsed":[\d.]+}\n$`))
+				})
+			})
+		})
+
+		It("writes findings for editors", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 0 >> coverage.out; echo bar:1.2,1.3 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "")
+					writeFile("bar", "// untested sections: 2\n// untested section: nope\n")
+					expectCommand(
+						func() (exitCode int) {
+							withFakeClock(func() {
+								exitCode = runGoTestAndCheckCoverage([]string{"--ide-report=report.json", "--quickfix=report.qf"})
+							})
+							return
+						},
+						[]interface{}{
+							1,
+							"",
+							"bar:2: invalid directive: expected \"untested section:\" to be followed by function, next N blocks, begin, end or one of if, else, for, range, switch, case, default, select, func, go, defer\n" +
+								"foo new untested sections introduced (1 current vs 0 configured)\nfoo:1.2,1.3\n" +
+								"warnings (1):\nbar has less untested sections (1 current vs 2 configured), decrement configured untested? configured on: bar:1\n" +
+								"go-testcov: FAIL new_untested=1 files=1 coverage=100.0%\n",
+						},
+					)
+					Expect(readFile("report.json")).To(Equal(`{
+  "version": 1,
+  "coverage": 100,
+  "statements": 0,
+  "coveredStatements": 0,
+  "findings": [
+    {
+      "path": "bar",
+      "line": 1,
+      "column": 1,
+      "endLine": 0,
+      "endColumn": 0,
+      "severity": "warning",
+      "code": "STALE_BUDGET",
+      "message": "less untested sections (1 current vs 2 configured), decrement configured untested?"
+    },
+    {
+      "path": "bar",
+      "line": 2,
+      "column": 1,
+      "endLine": 0,
+      "endColumn": 0,
+      "severity": "error",
+      "code": "INVALID_DIRECTIVE",
+      "message": "invalid directive: expected \"untested section:\" to be followed by function, next N blocks, begin, end or one of if, else, for, range, switch, case, default, select, func, go, defer"
+    },
+    {
+      "path": "foo",
+      "line": 1,
+      "column": 2,
+      "endLine": 1,
+      "endColumn": 3,
+      "severity": "error",
+      "code": "NEW_UNTESTED_SECTION",
+      "message": "new untested section introduced (1 current vs 0 configured)"
+    }
+  ],
+  "files": [
+    {
+      "path": "bar",
+      "untested": 1,
+      "configured": 2,
+      "scope": "file",
+      "status": "stale"
+    },
+    {
+      "path": "foo",
+      "untested": 1,
+      "configured": 0,
+      "scope": "file",
+      "status": "failed"
+    }
+  ],
+  "skipped": [],
+  "timings": [
+    {
+      "phase": "go test",
+      "seconds": 1
+    },
+    {
+      "phase": "profile parsing",
+      "seconds": 1
+    },
+    {
+      "phase": "source scanning",
+      "seconds": 1
+    },
+    {
+      "phase": "reporting",
+      "seconds": 1
+    }
+  ]
+}
+`))
+					Expect(readFile("report.qf")).To(HavePrefix("bar:1:1: warning: less untested sections"))
+				})
+			})
+		})
+
+		Describe("with --read-only", func() {
+			goToProfile := "while [ $# -gt 0 ]; do case $1 in -covermode) mode=$2;; -coverprofile) out=$2;; esac; shift; done; " +
+				"echo \"mode: ${mode:-set}\" > $out; echo foo:1.2,1.3 1 0 >> $out"
+
+			It("does not write into the current directory", func() {
+				withFakeGo(goToProfile, func() {
+					withoutEnv("GOPATH", func() {
+						writeFile("foo", "// untested sections: 1\n")
+						before := directoryEntries(".")
+						expectCommand(
+							func() int { return runGoTestAndCheckCoverage([]string{"--read-only", "--modes=set,atomic", "-cover"}) },
+							[]interface{}{0, "", "go test -covermode=set\ngo test -covermode=atomic\ngo-testcov: PASS new_untested=0 files=0 coverage=0.0%\n"},
+						)
+						Expect(directoryEntries(".")).To(Equal(before))
+					})
+				})
+			})
+
+			It("does not update the readme", func() {
+				inTempDir(func() {
+					writeFile("README.md", "old")
+					expectCommand(
+						func() int { return run([]string{"badge", "--update-readme", "--read-only"}) },
+						[]interface{}{2, "", "--update-readme writes the readme, which --read-only forbids\n"},
+					)
+					Expect(readFile("README.md")).To(Equal("old"))
+				})
+			})
+		})
+
+		Describe("with multiple covermodes", func() {
+			// fake go covers foo:1 only in atomic mode
+			goInModes := "while [ $# -gt 0 ]; do case $1 in -covermode) mode=$2;; -coverprofile) out=$2;; esac; shift; done; " +
+				"echo \"mode: $mode\" > $out; if [ $mode = atomic ]; then echo foo:1.2,1.3 1 1 >> $out; else echo foo:1.2,1.3 1 0 >> $out; fi; " +
+				"echo foo:2.2,2.3 1 0 >> $out; [ $mode != count ]"
+
+			It("merges the coverage of all runs", func() {
+				withFakeGo(goInModes, func() {
+					withoutEnv("GOPATH", func() {
+						writeFile("foo", "// untested sections: 1\n")
+						expectCommand(
+							func() int { return runGoTestAndCheckCoverage([]string{"--modes=set,atomic", "-race"}) },
+							[]interface{}{0, "", "go test -covermode=set\ngo test -covermode=atomic\ngo-testcov: PASS new_untested=0 files=0 coverage=50.0%\n"},
+						)
+						Expect("coverage.set.out").ToNot(BeAnExistingFile())
+						Expect("coverage.atomic.out").ToNot(BeAnExistingFile())
+					})
+				})
+			})
+
+			It("fails when a run fails", func() {
+				withFakeGo(goInModes, func() {
+					withoutEnv("GOPATH", func() {
+						writeFile("foo", "// untested sections: 1\n")
+						expectCommand(
+							func() int { return runGoTestAndCheckCoverage([]string{"--modes=count,atomic"}) },
+							[]interface{}{1, "", "go test -covermode=count\ngo-testcov: FAIL go_test_exit=1\n"},
+						)
+					})
+				})
+			})
+		})
+
+		It("shares package budgets between the files of a package", func() {
+			withFakeGo("echo header > coverage.out; echo a.go:1.2,1.3 1 0 >> coverage.out; echo b.go:1.2,1.3 1 0 >> coverage.out; echo c.go:2.2,2.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("doc.go", "// untested sections (package): 2\n")
+					writeFile("a.go", "a()\n")
+					writeFile("b.go", "b()\n")
+					writeFile("c.go", "// untested sections: 1\nc()\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{}) },
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=0.0%\n"},
+					)
+
+					writeFile("doc.go", "// untested sections (package): 1\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{}) },
+						[]interface{}{
+							1,
+							"",
+							"a.go new untested sections introduced (2 current vs 1 configured for the package)\na.go:1.2,1.3\n" +
+								"b.go new untested sections introduced (2 current vs 1 configured for the package)\nb.go:1.2,1.3\n" +
+								"go-testcov: FAIL new_untested=2 files=2 coverage=0.0%\n",
+						},
+					)
+
+					writeFile("doc.go", "// untested sections (package): 3\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{}) },
+						[]interface{}{
+							0,
+							"",
+							"warnings (1):\npackage . has less untested sections (2 current vs 3 configured for the package), decrement configured untested? configured on: doc.go:1\n" +
+								"go-testcov: PASS new_untested=0 files=0 coverage=0.0%\n",
+						},
+					)
+				})
+			})
+		})
+
+		It("counts untested statements against budgets with --count-statements", func() {
+			withFakeGo("echo header > coverage.out; echo a.go:1.2,1.3 3 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("a.go", "// untested sections: 1\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--count-statements"}) },
+						[]interface{}{1, "", "a.go new untested sections introduced (3 current vs 1 configured)\na.go:1.2,1.3\ngo-testcov: FAIL new_untested=1 files=1 coverage=0.0%\n"},
+					)
+					writeFile("a.go", "// untested sections: 3\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--count-statements"}) },
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=0.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("shares allowances between the files matching their glob", func() {
+			withFakeGo("echo header > coverage.out; echo legacy/a.go:1.2,1.3 1 0 >> coverage.out; echo legacy/b/c.go:1.2,1.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					noError(os.MkdirAll("legacy/b", 0700))
+					writeFile("legacy/a.go", "a()\n")
+					writeFile("legacy/b/c.go", "c()\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--allowance=legacy/**=2"}) },
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=0.0%\n"},
+					)
+
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--allowance=legacy/**=1"}) },
+						[]interface{}{
+							1,
+							"",
+							"legacy/a.go new untested sections introduced (2 current vs 1 allowed for legacy/**)\nlegacy/a.go:1.2,1.3\n" +
+								"legacy/b/c.go new untested sections introduced (2 current vs 1 allowed for legacy/**)\nlegacy/b/c.go:1.2,1.3\n" +
+								"go-testcov: FAIL new_untested=2 files=2 coverage=0.0%\n",
+						},
+					)
+
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--allowance=legacy/**=3"}) },
+						[]interface{}{
+							0,
+							"",
+							"warnings (1):\nfiles matching legacy/** have less untested sections (2 current vs 3 allowed for legacy/**), decrement the allowance? configured on: --allowance=legacy/**=3\n" +
+								"go-testcov: PASS new_untested=0 files=0 coverage=0.0%\n",
+						},
+					)
+				})
+			})
+		})
+
+		It("allows untested sections in functions with a budget", func() {
+			withFakeGo("echo header > coverage.out; echo foo.go:4.2,4.3 1 0 >> coverage.out; echo foo.go:8.2,8.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo.go", "package foo\n\nfunc (s *Server) a() {\n\tb()\n}\n\nfunc c() {\n\td()\n}\n")
+					writeFile("budgets", "foo.go#(*Server).a: 1\nfoo.go#c: 2\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--budgets=budgets"}) },
+						[]interface{}{
+							0,
+							"",
+							"warnings (1):\nfoo.go#c has less untested sections (1 current vs 2 configured), decrement configured untested? configured on: budgets:2\n" +
+								"go-testcov: PASS new_untested=0 files=0 coverage=0.0%\n",
+						},
+					)
+				})
+			})
+		})
+
+		It("fails when the budgets file is invalid", func() {
+			inTempDir(func() {
+				writeFile("budgets", "nope\n")
+				expectCommand(
+					func() int { return runGoTestAndCheckCoverage([]string{"--budgets=budgets"}) },
+					[]interface{}{2, "", "budgets:1: expected \"path#function: count\" but got \"nope\"\n"},
+				)
+			})
+		})
+
+		It("allows more untested sections with --override", func() {
+			withFakeGo("echo header > coverage.out; echo foo.go:1.2,1.3 1 0 >> coverage.out; echo foo.go:2.2,2.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo.go", "// untested sections: 1\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--override=foo.go=3", "--override=bar.go=1"}) },
+						[]interface{}{
+							0,
+							"",
+							"OVERRIDE: foo.go allows 3 untested sections instead of 1 because of --override, remove it after the emergency\n" +
+								"warnings (1):\n--override=bar.go=1 is not needed, bar.go has no untested sections or was not checked\n" +
+								"go-testcov: PASS new_untested=0 files=0 coverage=0.0% overrides=1\n",
+						},
+					)
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--override=./foo.go=0"}) },
+						[]interface{}{
+							1,
+							"",
+							"OVERRIDE: foo.go allows 0 untested sections instead of 1 because of --override, remove it after the emergency\n" +
+								"foo.go new untested sections introduced (2 current vs 0 allowed by --override)\nfoo.go:1.2,1.3\nfoo.go:2.2,2.3\n" +
+								"go-testcov: FAIL new_untested=2 files=1 coverage=0.0% overrides=1\n",
+						},
+					)
+				})
+			})
+		})
+
+		It("does not allow ignores or budgets in critical code", func() {
+			withFakeGo("echo header > coverage.out; echo foo.go:3.2,3.3 1 0 >> coverage.out; echo bar/bar.go:1.2,1.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo.go", "//testcov:critical\n// untested sections: 1\nfoo() // untested section\n")
+					noError(os.MkdirAll("bar", 0700))
+					writeFile("bar/bar.go", "a() // untested section\n")
+					writeFile("bar/doc.go", "//testcov:critical package\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{}) },
+						[]interface{}{
+							1,
+							"",
+							"bar/bar.go new untested sections introduced (1 current vs 0 allowed by //testcov:critical)\nbar/bar.go:1.2,1.3\n" +
+								"foo.go new untested sections introduced (1 current vs 0 allowed by //testcov:critical)\nfoo.go:3.2,3.3\n" +
+								"go-testcov: FAIL new_untested=2 files=2 coverage=0.0%\n",
+						},
+					)
+				})
+			})
+		})
+
+		It("only warns about experimental code during its grace period", func() {
+			withFakeGo("echo header > coverage.out; echo foo.go:3.2,3.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					gitCommand("init", "-q")
+					writeFile("foo.go", "package foo\n//testcov:experimental\nfoo()\n")
+					withFakeClock(func() {
+						expectCommand(
+							func() int { return runGoTestAndCheckCoverage([]string{"--format=json:report.json"}) },
+							[]interface{}{
+								0,
+								"",
+								"foo.go new untested sections introduced (1 current vs 0 configured)\nfoo.go:3.2,3.3\n" +
+									"foo.go is experimental (//testcov:experimental), not failing until 1970-01-31\n" +
+									"go-testcov: PASS new_untested=0 files=0 coverage=0.0%\n",
+							},
+						)
+					})
+					Expect(readFile("report.json")).To(ContainSubstring(`"suppression": "experimental until 1970-01-31"`))
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--experimental-days=0"}) },
+						[]interface{}{1, "", "foo.go new untested sections introduced (1 current vs 0 configured)\nfoo.go:3.2,3.3\ngo-testcov: FAIL new_untested=1 files=1 coverage=0.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("reports each line of untested sections with --split-lines", func() {
+			withFakeGo("echo header > coverage.out; echo foo.go:2.2,5.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo.go", "if a {\n\tb()\n\n\tc()\n}\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--split-lines", "--quickfix=report.qf"}) },
+						[]interface{}{1, "", "foo.go new untested sections introduced (1 current vs 0 configured)\nfoo.go:2: b()\nfoo.go:4: c()\ngo-testcov: FAIL new_untested=1 files=1 coverage=0.0%\n"},
+					)
+					Expect(readFile("report.qf")).To(Equal(
+						"foo.go:2:1: untested line of section 2.2,5.3 (1 current vs 0 configured)\n" +
+							"foo.go:4:1: untested line of section 2.2,5.3 (1 current vs 0 configured)\n",
+					))
+				})
+			})
+		})
+
 		It("does not show generated files when failing", func() {
 			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 0 >> coverage.out; echo generated.go:1.21.3 0 >> coverage.out", func() {
 				writeFile("foo", "")
 				writeFile("generated.go", "")
 				expectCommand(
 					runGoTestWithCoverage,
-					[]interface{}{1, "", "foo new untested sections introduced (1 current vs 0 configured)\nfoo:1.2,1.3\n"},
+					[]interface{}{1, "", "foo new untested sections introduced (1 current vs 0 configured)\nfoo:1.2,1.3\ngo-testcov: FAIL new_untested=1 files=1 coverage=100.0%\n"},
 				)
 			})
 		})
@@ -119,18 +637,84 @@ var _ = Describe("go-testcov", func() {
 				writeFile("generated.go", "test est")
 				expectCommand(
 					runGoTestWithCoverage,
-					[]interface{}{0, "", ""},
+					[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
 				)
 			})
 		})
 
+		It("ignores files matching additional --generated patterns", func() {
+			withFakeGo("echo header > coverage.out; echo github.com/foo/bar/pkg/a_mock.go:1.2,1.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--generated=_mock\\.go$"}) },
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("ignores files with a generated code header", func() {
+			withFakeGo("echo header > coverage.out; echo github.com/foo/bar/pkg/a.go:3.2,3.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					noError(os.MkdirAll("pkg", 0700))
+					writeFile("pkg/a.go", "// Code generated by stringer. DO NOT EDIT.\npackage pkg\na()\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{}) },
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
+					)
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--force-check=pkg/a.go"}) },
+						[]interface{}{1, "", "pkg/a.go new untested sections introduced (1 current vs 0 configured)\npkg/a.go:3.2,3.3\ngo-testcov: FAIL new_untested=1 files=1 coverage=0.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("checks generated files that are forced to be checked", func() {
+			withFakeGo("echo header > coverage.out; echo github.com/foo/bar/pkg/generated.go:1.2,1.3 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					noError(os.MkdirAll("pkg", 0700))
+					writeFile("pkg/generated.go", "test est")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--force-check=pkg/gen*.go"}) },
+						[]interface{}{1, "", "pkg/generated.go new untested sections introduced (1 current vs 0 configured)\npkg/generated.go:1.2,1.3\ngo-testcov: FAIL new_untested=1 files=1 coverage=100.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("skips excluded files", func() {
+			withFakeGo("echo header > coverage.out; echo github.com/foo/bar/mocks/a.go:1.2,1.3 1 0 >> coverage.out; echo github.com/foo/bar/pkg/b_gen.go:1.2,1.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--exclude=mocks/**,**/*_gen.go"}) },
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("only checks --files", func() {
+			withFakeGo("echo header > coverage.out; echo github.com/foo/bar/pkg/a.go:1.2,1.3 1 0 >> coverage.out; echo github.com/foo/bar/pkg/b.go:1.2,1.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					noError(os.MkdirAll("pkg", 0700))
+					writeFile("pkg/a.go", "a()\n")
+					writeFile("pkg/b.go", "b()\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--files=./pkg/b.go"}) },
+						[]interface{}{1, "", "pkg/b.go new untested sections introduced (1 current vs 0 configured)\npkg/b.go:1.2,1.3\ngo-testcov: FAIL new_untested=1 files=1 coverage=0.0%\n"},
+					)
+				})
+			})
+		})
+
 		It("fails when configured untested is below actual untested", func() {
 			withFakeGo("echo header > coverage.out; echo foo:2.2,2.3 0 >> coverage.out; echo foo:1.2,1.3 0 >> coverage.out", func() {
 				withFakeGoPath(func(goPath string) {
 					writeFile(joinPath(goPath, "src", "foo"), "// untested sections: 1\n")
 					expectCommand(
 						runGoTestWithCoverage,
-						[]interface{}{1, "", "foo new untested sections introduced (2 current vs 1 configured)\nfoo:1.2,1.3\nfoo:2.2,2.3\n"},
+						[]interface{}{1, "", "foo new untested sections introduced (2 current vs 1 configured)\nfoo:1.2,1.3\nfoo:2.2,2.3\ngo-testcov: FAIL new_untested=2 files=1 coverage=100.0%\n"},
 					)
 				})
 			})
@@ -140,7 +724,7 @@ var _ = Describe("go-testcov", func() {
 			withFailingTestInGoPath(func() {
 				expectCommand(
 					runGoTestWithCoverage,
-					[]interface{}{1, "", "foo2.go new untested sections introduced (1 current vs 0 configured)\nfoo2.go:1.2,1.3\n"},
+					[]interface{}{1, "", "foo2.go new untested sections introduced (1 current vs 0 configured)\nfoo2.go:1.2,1.3\ngo-testcov: FAIL new_untested=1 files=1 coverage=100.0%\n"},
 				)
 			})
 		})
@@ -152,7 +736,7 @@ var _ = Describe("go-testcov", func() {
 				chDir(other, func() {
 					expectCommand(
 						runGoTestWithCoverage,
-						[]interface{}{1, "", "foo.com/bar/baz/foo2.go new untested sections introduced (1 current vs 0 configured)\nfoo.com/bar/baz/foo2.go:1.2,1.3\n"},
+						[]interface{}{1, "", "foo.com/bar/baz/foo2.go new untested sections introduced (1 current vs 0 configured)\nfoo.com/bar/baz/foo2.go:1.2,1.3\ngo-testcov: FAIL new_untested=1 files=1 coverage=100.0%\n"},
 					)
 				})
 			})
@@ -165,7 +749,7 @@ var _ = Describe("go-testcov", func() {
 					writeFile(joinPath(goPath, "src", "bar"), "")
 					expectCommand(
 						runGoTestWithCoverage,
-						[]interface{}{1, "", "bar new untested sections introduced (1 current vs 0 configured)\nbar:1.2,1.3\nfoo new untested sections introduced (2 current vs 1 configured)\nfoo:1.2,1.3\nfoo:2.2,2.3\n"},
+						[]interface{}{1, "", "bar new untested sections introduced (1 current vs 0 configured)\nbar:1.2,1.3\nfoo new untested sections introduced (2 current vs 1 configured)\nfoo:1.2,1.3\nfoo:2.2,2.3\ngo-testcov: FAIL new_untested=3 files=2 coverage=100.0%\n"},
 					)
 				})
 			})
@@ -178,7 +762,7 @@ var _ = Describe("go-testcov", func() {
 					writeFile(joinPath(goPath, "src", "bar"), "")
 					expectCommand(
 						runGoTestWithCoverage,
-						[]interface{}{1, "", "foo new untested sections introduced (2 current vs 1 configured)\nfoo:1.2,1.3\nfoo:2.2,2.3\n"},
+						[]interface{}{1, "", "foo new untested sections introduced (2 current vs 1 configured)\nfoo:1.2,1.3\nfoo:2.2,2.3\ngo-testcov: FAIL new_untested=2 files=1 coverage=100.0%\n"},
 					)
 				})
 			})
@@ -190,7 +774,7 @@ var _ = Describe("go-testcov", func() {
 					writeFile(joinPath(goPath, "src", "foo"), "// untested sections: 2\n\n")
 					expectCommand(
 						runGoTestWithCoverage,
-						[]interface{}{0, "", ""},
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
 					)
 				})
 			})
@@ -205,20 +789,68 @@ var _ = Describe("go-testcov", func() {
 						[]interface{}{
 							0,
 							"",
-							"foo has less untested sections (1 current vs 2 configured), decrement configured untested?\nconfigured on: " + joinPath(goPath, "src", "foo") + ":1",
+							"warnings (1):\nfoo has less untested sections (1 current vs 2 configured), decrement configured untested? configured on: " + joinPath(goPath, "src", "foo") + ":1\n" +
+								"go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n",
+						},
+					)
+				})
+			})
+		})
+
+		It("explains why sections were ignored", func() {
+			withFakeGo("echo header > coverage.out; echo foo:3.2,3.3 0 >> coverage.out; echo foo:2.2,2.3 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "// untested sections: 1\nfoo// untested section\nbar\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--explain-ignores"}) },
+						[]interface{}{
+							0,
+							"",
+							"foo:2.2,2.3 ignored by inline comment on line 2\nfoo:3.2,3.3 counted against untested sections: 1 configured on foo:1\n" +
+								"go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n",
+						},
+					)
+				})
+			})
+		})
+
+		It("does not explain sections as within budget when they exceed it", func() {
+			withFakeGo("echo header > coverage.out; echo foo:3.2,3.3 0 >> coverage.out; echo foo:2.2,2.3 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "\nfoo// untested section\nbar\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--explain-ignores"}) },
+						[]interface{}{
+							1,
+							"",
+							"foo:2.2,2.3 ignored by inline comment on line 2\nfoo new untested sections introduced (1 current vs 0 configured)\nfoo:3.2,3.3\n" +
+								"go-testcov: FAIL new_untested=1 files=1 coverage=100.0%\n",
 						},
 					)
 				})
 			})
 		})
 
+		It("lints inline ignores in directories with untested sections", func() {
+			withFakeGo("echo header > coverage.out; echo foo.go:1.2,1.3 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo.go", "package foo // untested sections: 1\n")
+					writeFile("foo_test.go", "package foo\n// untested section\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--lint-ignores"}) },
+						[]interface{}{0, "", "foo_test.go:2:1: untested section comment is in a _test.go file, which never has coverage\ngo-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
+					)
+				})
+			})
+		})
+
 		It("passes when configured via inline comments", func() {
 			withFakeGo("echo header > coverage.out; echo foo:1.2,3.0 0 >> coverage.out", func() {
 				withFakeGoPath(func(goPath string) {
 					writeFile(joinPath(goPath, "src", "foo"), "func main(){\n// untested section\n}")
 					expectCommand(
 						runGoTestWithCoverage,
-						[]interface{}{0, "", ""},
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
 					)
 				})
 			})
@@ -230,7 +862,84 @@ var _ = Describe("go-testcov", func() {
 					writeFile(joinPath(goPath, "src", "foo"), "\t// untested section\nfunc main(){\n\n}")
 					expectCommand(
 						runGoTestWithCoverage,
-						[]interface{}{0, "", ""},
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("passes when inline comments are on multi-line headers or opening braces of gofmt-ed code", func() {
+			withFakeGo("echo header > coverage.out; for l in 15.2,16.1 21.3,26.1 32.3,35.1 44.3,45.1 51.3,51.11; do echo shapes.go:$l 1 0 >> coverage.out; done", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("shapes.go", multiLineShapes)
+					expectCommand(
+						runGoTestWithCoverage,
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=0.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("passes when a qualified inline comment marks the matching section on a shared line", func() {
+			withFakeGo("echo header > coverage.out; echo one.go:7.22,7.27 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("one.go", oneLineIfElse+" // untested section: else\n}\n")
+					expectCommand(
+						runGoTestWithCoverage,
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=0.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("fails when a qualified inline comment marks a different section on a shared line", func() {
+			withFakeGo("echo header > coverage.out; echo one.go:7.22,7.27 1 0 >> coverage.out; echo one.go:7.9,7.14 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("one.go", oneLineIfElse+" // untested section: if\n}\n")
+					expectCommand(
+						runGoTestWithCoverage,
+						[]interface{}{1, "", "one.go new untested sections introduced (1 current vs 0 configured)\none.go:7.22,7.27\ngo-testcov: FAIL new_untested=1 files=1 coverage=0.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("passes when a scoped inline comment marks all sections of a function", func() {
+			withFakeGo("echo header > coverage.out; echo foo.go:4.2,4.5 1 0 >> coverage.out; echo foo.go:5.2,5.5 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo.go", "package foo\n\nfunc a() { // untested section: function\n\ta()\n\ta()\n}\n")
+					expectCommand(
+						runGoTestWithCoverage,
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=0.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("fails on malformed directives", func() {
+			withFakeGo("echo header > coverage.out; echo foo.go:4.2,4.5 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo.go", "package foo // untested sections: 1\n\nfunc a() {\n\ta() // untested section: nope\n}\n")
+					expectCommand(
+						runGoTestWithCoverage,
+						[]interface{}{
+							1,
+							"",
+							"foo.go:4: invalid directive: expected \"untested section:\" to be followed by function, next N blocks, begin, end or one of if, else, for, range, switch, case, default, select, func, go, defer\n" +
+								"go-testcov: FAIL new_untested=0 files=0 coverage=0.0%\n",
+						},
+					)
+				})
+			})
+		})
+
+		It("does not fail when the file is shorter than the section", func() {
+			withFakeGo("echo header > coverage.out; echo foo:2.2,3.3 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "// untested section")
+					expectCommand(
+						runGoTestWithCoverage,
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
 					)
 				})
 			})
@@ -245,7 +954,8 @@ var _ = Describe("go-testcov", func() {
 						[]interface{}{
 							0,
 							"",
-							"foo has less untested sections (2 current vs 3 configured), decrement configured untested?\nconfigured on: " + joinPath(goPath, "src", "foo") + ":1",
+							"warnings (1):\nfoo has less untested sections (2 current vs 3 configured), decrement configured untested? configured on: " + joinPath(goPath, "src", "foo") + ":1\n" +
+								"go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n",
 						},
 					)
 				})
@@ -258,7 +968,7 @@ var _ = Describe("go-testcov", func() {
 					writeFile("baz.go", "// untested sections: 3\n")
 					expectCommand(
 						runGoTestWithCoverage,
-						[]interface{}{0, "", "baz.go has less untested sections (2 current vs 3 configured), decrement configured untested?\nconfigured on: baz.go:1"},
+						[]interface{}{0, "", "warnings (1):\nbaz.go has less untested sections (2 current vs 3 configured), decrement configured untested? configured on: baz.go:1\ngo-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
 					)
 				})
 			})
@@ -270,7 +980,7 @@ var _ = Describe("go-testcov", func() {
 					writeFile("baz.go", "// untested sections: 3\n")
 					expectCommand(
 						runGoTestWithCoverage,
-						[]interface{}{0, "", "baz.go has less untested sections (2 current vs 3 configured), decrement configured untested?\nconfigured on: baz.go:1"},
+						[]interface{}{0, "", "warnings (1):\nbaz.go has less untested sections (2 current vs 3 configured), decrement configured untested? configured on: baz.go:1\ngo-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
 					)
 				})
 			})
@@ -282,7 +992,457 @@ var _ = Describe("go-testcov", func() {
 					writeFile("baz.go", "// untested sections: 3\n")
 					expectCommand(
 						runGoTestWithCoverage,
-						[]interface{}{0, "", "baz.go has less untested sections (2 current vs 3 configured), decrement configured untested?\nconfigured on: baz.go:1"},
+						[]interface{}{0, "", "warnings (1):\nbaz.go has less untested sections (2 current vs 3 configured), decrement configured untested? configured on: baz.go:1\ngo-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("only checks files changed since the given revision in diff mode", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 0 >> coverage.out; echo bar:1.2,1.3 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					gitCommand("init", "-q")
+					writeFile("foo", "")
+					writeFile("bar", "")
+					gitCommand("add", "foo", "bar")
+					gitCommand("commit", "-q", "-m", "initial")
+					writeFile("foo", "changed")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--diff=HEAD", "."}) },
+						[]interface{}{1, "", "foo new untested sections introduced (1 current vs 0 configured)\nfoo:1.2,1.3\ngo-testcov: FAIL new_untested=1 files=1 coverage=100.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("shows coverage changes of changed files in diff mode with a baseline", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 1 1 >> coverage.out; echo foo:2.2,2.3 1 0 >> coverage.out; echo bar:1.2,1.3 1 1 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					gitCommand("init", "-q")
+					writeFile("foo", "// untested sections: 1\n")
+					writeFile("bar", "")
+					gitCommand("add", "foo", "bar")
+					gitCommand("commit", "-q", "-m", "initial")
+					writeFile("foo", "// untested sections: 1\nchanged\n")
+					writeFile("base.out", "header\nfoo:1.2,1.3 1 1\nbar:1.2,1.3 1 0\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--diff=HEAD", "--baseline=base.out"}) },
+						[]interface{}{0, "", "coverage of changed files (baseline -> current):\nfoo 100.0% -> 50.0% (-50.0)\ngo-testcov: PASS new_untested=0 files=0 coverage=66.7%\n"},
+					)
+				})
+			})
+		})
+
+		It("fails when budgets increase with --forbid-budget-increase", func() {
+			withFakeGo("echo header > coverage.out; echo foo.go:2.2,2.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					gitCommand("init", "-q")
+					writeFile("foo.go", "// untested sections: 0\n")
+					gitCommand("add", "foo.go")
+					gitCommand("commit", "-q", "-m", "initial")
+					writeFile("foo.go", "// untested sections: 1\nfoo()\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--diff=HEAD", "--forbid-budget-increase"}) },
+						[]interface{}{1, "", "foo.go:1: increases untested sections from 0 to 1 (--forbid-budget-increase)\ngo-testcov: FAIL new_untested=0 files=0 coverage=0.0%\n"},
+					)
+					expectCommand(
+						func() int {
+							return runGoTestAndCheckCoverage([]string{"--diff=HEAD", "--forbid-budget-increase", "--allow-budget-increase"})
+						},
+						[]interface{}{0, "", "foo.go:1: increases untested sections from 0 to 1 (allowed with --allow-budget-increase)\ngo-testcov: PASS new_untested=0 files=0 coverage=0.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("needs --diff for --forbid-budget-increase", func() {
+			expectCommand(
+				func() int { return runGoTestAndCheckCoverage([]string{"--forbid-budget-increase"}) },
+				[]interface{}{2, "", "--forbid-budget-increase needs --diff to know what changed\n"},
+			)
+		})
+
+		It("fails on new ignores with --forbid-new-ignores", func() {
+			withFakeGo("echo header > coverage.out; echo foo.go:2.2,2.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					gitCommand("init", "-q")
+					writeFile("foo.go", "// untested sections: 0\n")
+					gitCommand("add", "foo.go")
+					gitCommand("commit", "-q", "-m", "initial")
+					writeFile("foo.go", "\nfoo() // untested section\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--diff=HEAD", "--forbid-new-ignores"}) },
+						[]interface{}{1, "", "foo.go:2: adds an untested section comment (--forbid-new-ignores)\ngo-testcov: FAIL new_untested=0 files=0 coverage=0.0%\n"},
+					)
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--forbid-new-ignores"}) },
+						[]interface{}{2, "", "--forbid-new-ignores needs --diff to know what changed\n"},
+					)
+				})
+			})
+		})
+
+		It("points out new files without tests or budget when there is a baseline", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 1 0 >> coverage.out; echo bar:1.2,1.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					gitCommand("init", "-q")
+					writeFile("foo", "")
+					gitCommand("add", "foo")
+					gitCommand("commit", "-q", "-m", "initial")
+					writeFile("foo", "changed\n")
+					writeFile("bar", "")
+					gitCommand("add", "bar")
+					writeFile("base.out", "header\nfoo:1.2,1.3 1 1\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--diff=HEAD", "--baseline=base.out"}) },
+						[]interface{}{
+							1,
+							"",
+							"bar new untested sections introduced (1 current vs 0 configured)\nbar:1.2,1.3\n" +
+								"foo new untested sections introduced (1 current vs 0 configured)\nfoo:1.2,1.3\n" +
+								"coverage of changed files (baseline -> current):\n" +
+								"bar new -> 0.0%\nfoo 100.0% -> 0.0% (-100.0)\n" +
+								"bar is a new file without tests (1 untested sections and no budget), add tests for it\n" +
+								"go-testcov: FAIL new_untested=2 files=2 coverage=0.0%\n",
+						},
+					)
+				})
+			})
+		})
+
+		It("lists sections that became covered since the baseline with --show-resolved", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 1 1 >> coverage.out; echo foo:2.2,2.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					gitCommand("init", "-q")
+					writeFile("foo", "// untested sections: 2\n")
+					gitCommand("add", "foo")
+					gitCommand("commit", "-q", "-m", "initial")
+					writeFile("foo", "// untested sections: 2\nchanged\n")
+					writeFile("base.out", "header\nfoo:1.2,1.3 1 0\nfoo:2.2,2.3 1 0\n")
+					expectCommand(
+						func() int {
+							return runGoTestAndCheckCoverage([]string{"--diff=HEAD", "--baseline=base.out", "--show-resolved"})
+						},
+						[]interface{}{
+							0,
+							"",
+							"coverage of changed files (baseline -> current):\nfoo 0.0% -> 50.0% (+50.0)\n" +
+								"foo resolved untested sections since the baseline\nfoo:1.2,1.3\n" +
+								"warnings (1):\nfoo has less untested sections (1 current vs 2 configured), decrement configured untested? configured on: foo:1\n" +
+								"go-testcov: PASS new_untested=0 files=0 coverage=50.0%\n",
+						},
+					)
+				})
+			})
+		})
+
+		It("uses baseline coverage of the nearest ancestor that has it", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 1 1 >> coverage.out; echo foo:2.2,2.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					gitCommand("init", "-q")
+					writeFile("foo", "// untested sections: 1\n")
+					gitCommand("add", "foo")
+					gitCommand("commit", "-q", "-m", "initial")
+					gitCommand("commit", "-q", "--allow-empty", "-m", "no coverage uploaded yet")
+					writeFile("foo", "// untested sections: 1\nchanged\n")
+					noError(os.MkdirAll("coverage/HEAD", 0700))
+					writeFile("coverage/HEAD/"+commandOutput("git", "rev-parse", "HEAD~1")[:40]+".out", "header\nfoo:1.2,1.3 1 1\n")
+					expectCommand(
+						func() int {
+							return runGoTestAndCheckCoverage([]string{"--diff=HEAD", "--baseline=coverage/{branch}/{sha}.out"})
+						},
+						[]interface{}{0, "", "coverage of changed files (baseline -> current):\nfoo 100.0% -> 50.0% (-50.0)\ngo-testcov: PASS new_untested=0 files=0 coverage=50.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("skips coverage changes when no ancestor has baseline coverage", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 1 1 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					gitCommand("init", "-q")
+					writeFile("foo", "")
+					gitCommand("add", "foo")
+					gitCommand("commit", "-q", "-m", "initial")
+					writeFile("foo", "changed")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--diff=HEAD", "--baseline=coverage/{sha}.out"}) },
+						[]interface{}{0, "", "no baseline coverage found for HEAD or its last 50 ancestors, not showing coverage changes\ngo-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("fails with the error when the baseline can not be downloaded", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 1 1 >> coverage.out", func() {
+				withFakeCommand("gsutil", "echo 'AccessDeniedException: 403' >&2; exit 1", func() {
+					withoutEnv("GOPATH", func() {
+						gitCommand("init", "-q")
+						writeFile("foo", "")
+						gitCommand("add", "foo")
+						gitCommand("commit", "-q", "-m", "initial")
+						expectCommand(
+							func() int {
+								return runGoTestAndCheckCoverage([]string{"--diff=HEAD", "--baseline=gs://bucket/{branch}.out"})
+							},
+							[]interface{}{2, "", "downloading baseline gs://bucket/HEAD.out with gsutil failed with exit code 1:\nAccessDeniedException: 403\n\ngo-testcov: FAIL new_untested=0 files=0 coverage=100.0%\n"},
+						)
+					})
+				})
+			})
+		})
+
+		It("reports skipped files and why when verbose", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 0 >> coverage.out; echo bar:1.2,1.3 0 >> coverage.out; echo generated.go:1.2,1.3 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					gitCommand("init", "-q")
+					writeFile("foo", "")
+					writeFile("bar", "")
+					writeFile("generated.go", "")
+					gitCommand("add", "foo", "bar", "generated.go")
+					gitCommand("commit", "-q", "-m", "initial")
+					writeFile("foo", "changed")
+					writeFile("generated.go", "changed")
+					expectCommand(
+						func() (exitCode int) {
+							withFakeClock(func() {
+								exitCode = runGoTestAndCheckCoverage([]string{"--diff=HEAD", "--verbose", "--format=json:report.json"})
+							})
+							return
+						},
+						[]interface{}{
+							1,
+							"",
+							"bar skipped: not changed since HEAD\nfoo new untested sections introduced (1 current vs 0 configured)\nfoo:1.2,1.3\n" +
+								"generated.go skipped: matches generated file pattern /*generated.*\\.go$\n" +
+								"timings: go test 1.00s, profile parsing 1.00s, source scanning 1.00s, reporting 1.00s\n" +
+								"go-testcov: FAIL new_untested=1 files=1 coverage=100.0%\n",
+						},
+					)
+					Expect(readFile("report.json")).To(ContainSubstring(`"skipped": [
+    {
+      "path": "bar",
+      "reason": "not changed since HEAD"
+    },
+    {
+      "path": "generated.go",
+      "reason": "matches generated file pattern /*generated.*\\.go$"
+    }
+  ],`))
+				})
+			})
+		})
+
+		It("fails when diff mode cannot find a repository", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--diff=HEAD", "."}) },
+						[]interface{}{2, "", "Could not find a git or hg repository for --diff, use --vcs to select one\ngo-testcov: FAIL new_untested=0 files=0 coverage=100.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("fails on invalid options", func() {
+			expectCommand(
+				func() int { return runGoTestAndCheckCoverage([]string{"--vcs=svn"}) },
+				[]interface{}{2, "", "unknown version control system svn, supported are git, hg\n"},
+			)
+		})
+
+		It("passes when total coverage is above --min-coverage", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 3 1 >> coverage.out; echo foo:2.2,2.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "// untested sections: 1\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--min-coverage=75"}) },
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=75.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("reports the percentage gate separately when it fails", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 1 1 >> coverage.out; echo foo:2.2,2.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "// untested sections: 1\n")
+					expectCommand(
+						func() int {
+							return runGoTestAndCheckCoverage([]string{"--min-coverage=75", "--ide-report=report.json"})
+						},
+						[]interface{}{1, "", "total coverage 50.0% is below the required 75.0% (--min-coverage)\ngo-testcov: FAIL new_untested=0 files=0 coverage=50.0%\n"},
+					)
+					Expect(readFile("report.json")).To(ContainSubstring(`"code": "LOW_TOTAL_COVERAGE",
+      "message": "total coverage 50.0% is below the required 75.0%"`))
+				})
+			})
+		})
+
+		It("lists untested error paths with --max-untested-error-paths", func() {
+			withFakeGo("echo header > coverage.out; echo foo.go:4.9,6.3 1 0 >> coverage.out; echo foo.go:7.9,9.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo.go", "package foo\n\nfunc a() error {\n\tif x {\n\t\treturn err\n\t}\n\tif y {\n\t\treturn nil\n\t}\n\treturn nil\n}\n")
+					writeFile("doc.go", "// untested sections (package): 2\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--max-untested-error-paths=0"}) },
+						[]interface{}{1, "", "untested error paths (1 current vs 0 allowed by --max-untested-error-paths):\nfoo.go:5 return err\n" +
+							"go-testcov: FAIL new_untested=0 files=0 coverage=0.0%\n"},
+					)
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--max-untested-error-paths=1"}) },
+						[]interface{}{0, "", "untested error paths (1 current vs 1 allowed by --max-untested-error-paths):\nfoo.go:5 return err\n" +
+							"go-testcov: PASS new_untested=0 files=0 coverage=0.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("fails when a file is below --min-file-coverage", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 1 1 >> coverage.out; echo foo:2.2,2.3 3 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "// untested sections: 1\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--min-file-coverage=25"}) },
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=25.0%\n"},
+					)
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--min-file-coverage=30"}) },
+						[]interface{}{1, "", "foo has 25.0% coverage, below the required 30.0% (--min-file-coverage)\ngo-testcov: FAIL new_untested=0 files=0 coverage=25.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("requires the coverage --target asks for today", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 1 1 >> coverage.out; echo foo:2.2,2.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "// untested sections: 1\n")
+					expectCommand(
+						func() int {
+							return runGoTestAndCheckCoverage([]string{"--min-coverage=40", "--target=75% by 2020-01-01 from 2019-01-01"})
+						},
+						[]interface{}{1, "", "total coverage 50.0% is below the required 75.0% (--target 75% by 2020-01-01)\ngo-testcov: FAIL new_untested=0 files=0 coverage=50.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("compares --min-coverage with the percentage as shown with --precision", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 2 1 >> coverage.out; echo foo:2.2,2.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "// untested sections: 1\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--min-coverage=66.7"}) },
+						[]interface{}{0, "", "go-testcov: PASS new_untested=0 files=0 coverage=66.7%\n"},
+					)
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--min-coverage=66.7", "--precision=2"}) },
+						[]interface{}{1, "", "total coverage 66.67% is below the required 66.70% (--min-coverage)\ngo-testcov: FAIL new_untested=0 files=0 coverage=66.67%\n"},
+					)
+				})
+			})
+		})
+
+		It("covers helper packages with the tests of the other packages", func() {
+			withFakeGo("touch coverage.out\necho go \"$@\"", func() {
+				expectCommand(
+					func() int {
+						return runGoTestAndCheckCoverage([]string{"--helpers=./testutil/...", "-v", "./pkg/...", "./cmd"})
+					},
+					[]interface{}{0, "go test -coverpkg=./pkg/...,./cmd,./testutil/... -v ./pkg/... ./cmd -coverprofile coverage.out\n", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
+				)
+				expectCommand(
+					func() int {
+						return runGoTestAndCheckCoverage([]string{"--helpers=./testutil/...", "-coverpkg", "./pkg/..."})
+					},
+					[]interface{}{0, "go test -coverpkg ./pkg/...,./testutil/... -coverprofile coverage.out\n", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
+				)
+				expectCommand(
+					func() int { return runGoTestAndCheckCoverage([]string{"--helpers=./testutil/..."}) },
+					[]interface{}{0, "go test -coverpkg=.,./testutil/... -coverprofile coverage.out\n", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
+				)
+			})
+		})
+
+		It("fails on malformed profile lines", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 1 1 >> coverage.out; echo foo:2.2 1 0 >> coverage.out", func() {
+				writeFile("foo", "\n\n")
+				var result runResult
+				stdout, stderr := captureAll(func() { result = goTestAndCheckCoverage([]string{}, options{precision: 1}) })
+				Expect(stdout).To(Equal(""))
+				Expect(stderr).To(Equal("malformed coverage profile line \"foo:2.2 1 0\"\n"))
+				Expect(result.exitCode).To(Equal(1))
+				Expect(result.findings).To(Equal([]finding{{Severity: "error", Code: codeProfileParseError, Message: "malformed coverage profile line \"foo:2.2 1 0\""}}))
+				Expect(result.coverage).To(Equal(100.0))
+			})
+		})
+
+		It("ignores malformed profile lines of files that are not checked", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 1 1 >> coverage.out; echo mocks/foo.go:2.2 1 0 >> coverage.out", func() {
+				writeFile("foo", "")
+				var result runResult
+				stdout, stderr := captureAll(func() {
+					result = goTestAndCheckCoverage([]string{}, options{precision: 1, exclude: []string{"mocks/**"}})
+				})
+				Expect(stdout).To(Equal(""))
+				Expect(stderr).To(Equal(""))
+				Expect(result.exitCode).To(Equal(0))
+				Expect(result.findings).To(BeEmpty())
+			})
+		})
+
+		It("checks the coverage of each package a package imports with --deps-min-coverage", func() {
+			// lib is the gate's own package when the gate is ./lib
+			goWithDeps := "if [ $1 = list ]; then echo \"$@\" >&2; lib=true; if [ $5 = ./lib ]; then lib=false; fi; " +
+				"printf \"example.com/org/m/server false\\nexample.com/org/m/lib $lib\\ngithub.com/other/dep true\\n\"; exit; fi; " +
+				"printf 'mode: set\\nexample.com/org/m/server/main.go:1.2,1.3 1 1\\nexample.com/org/m/lib/lib.go:1.2,1.3 1 1\\n" +
+				"example.com/org/m/lib/lib.go:2.2,2.3 1 0\\nexample.com/org/m/other/other.go:1.2,1.3 1 0\\n' > coverage.out"
+			withFakeGo(goWithDeps, func() {
+				withoutEnv("GOPATH", func() {
+					noError(os.MkdirAll("lib", 0700))
+					noError(os.MkdirAll("other", 0700))
+					writeFile("lib/lib.go", "// untested sections: 1\n")
+					writeFile("other/other.go", "// untested sections: 1\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--deps-min-coverage=./server=75"}) },
+						[]interface{}{
+							1,
+							"",
+							"list -deps -f {{if not .Standard}}{{.ImportPath}} {{.DepOnly}}{{end}} ./server\n" +
+								"package example.com/org/m/lib imported by ./server has 50.0% coverage, below the required 75.0% (--deps-min-coverage)\n" +
+								"go-testcov: FAIL new_untested=0 files=0 coverage=50.0%\n",
+						},
+					)
+					expectCommand(
+						func() int {
+							return runGoTestAndCheckCoverage([]string{"--deps-min-coverage=./lib=75", "--format=json:report.json"})
+						},
+						[]interface{}{
+							1,
+							"",
+							"list -deps -f {{if not .Standard}}{{.ImportPath}} {{.DepOnly}}{{end}} ./lib\n" +
+								"package example.com/org/m/lib has 50.0% coverage, below the required 75.0% (--deps-min-coverage)\n" +
+								"go-testcov: FAIL new_untested=0 files=0 coverage=50.0%\n",
+						},
+					)
+					Expect(readFile("report.json")).To(ContainSubstring(`"code": "LOW_PACKAGE_COVERAGE"`))
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--deps-min-coverage=./server=50"}) },
+						[]interface{}{0, "", "list -deps -f {{if not .Standard}}{{.ImportPath}} {{.DepOnly}}{{end}} ./server\ngo-testcov: PASS new_untested=0 files=0 coverage=50.0%\n"},
+					)
+				})
+			})
+		})
+
+		It("reports both gates when both fail", func() {
+			withFakeGo("echo header > coverage.out; echo foo:1.2,1.3 1 1 >> coverage.out; echo foo:2.2,2.3 1 0 >> coverage.out", func() {
+				withoutEnv("GOPATH", func() {
+					writeFile("foo", "\n\n")
+					expectCommand(
+						func() int { return runGoTestAndCheckCoverage([]string{"--min-coverage=75"}) },
+						[]interface{}{1, "", "foo new untested sections introduced (1 current vs 0 configured)\nfoo:2.2,2.3\ntotal coverage 50.0% is below the required 75.0% (--min-coverage)\ngo-testcov: FAIL new_untested=1 files=1 coverage=50.0%\n"},
 					)
 				})
 			})
@@ -292,7 +1452,7 @@ var _ = Describe("go-testcov", func() {
 			withFakeGo("touch coverage.out\necho 1", func() {
 				expectCommand(
 					runGoTestWithCoverage,
-					[]interface{}{0, "1\n", ""},
+					[]interface{}{0, "1\n", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
 				)
 				_, err := os.Stat("coverage.out")
 				Expect(err).ToNot(BeNil())
@@ -303,7 +1463,7 @@ var _ = Describe("go-testcov", func() {
 			withFakeGo("touch coverage.out\necho 1", func() {
 				expectCommand(
 					func() int { return runGoTestAndCheckCoverage([]string{"hello", "world", "-cover"}) },
-					[]interface{}{0, "1\n", ""},
+					[]interface{}{0, "1\n", "go-testcov: PASS new_untested=0 files=0 coverage=100.0%\n"},
 				)
 				_, err := os.Stat("coverage.out")
 				Expect(err).To(BeNil())
@@ -320,7 +1480,7 @@ var _ = Describe("go-testcov", func() {
 
 		It("shows untested", func() {
 			withTempFile("mode: set\nfoo/pkg.go:1.2,3.4 1 0\n", func(file *os.File) {
-				Expect(untestedSections(file.Name())).To(Equal([]Section{{"foo/pkg.go", 1, 2, 3, 4, 100002}}))
+				Expect(untestedSections(file.Name())).To(Equal([]Section{{Path: "foo/pkg.go", Line: 1, Column: 2, EndLine: 3, EndColumn: 4, Statements: 1, sortValue: 100002}}))
 			})
 		})
 
@@ -330,6 +1490,12 @@ var _ = Describe("go-testcov", func() {
 			})
 		})
 
+		It("does not show sections that another test binary covered", func() {
+			withTempFile("mode: set\nfoo/pkg.go:1.2,3.4 1 0\nfoo/pkg.go:4.2,5.4 1 0\nfoo/pkg.go:1.2,3.4 1 1\nfoo/pkg.go:4.2,5.4 1 0\n", func(file *os.File) {
+				Expect(untestedSections(file.Name())).To(Equal([]Section{{Path: "foo/pkg.go", Line: 4, Column: 2, EndLine: 5, EndColumn: 4, Statements: 1, sortValue: 400002}}))
+			})
+		})
+
 		It("does not show covered even if coverage ends in 0", func() {
 			withTempFile("mode: set\nfoo/pkg.go:1.2,3.4 1 10\n", func(file *os.File) {
 				Expect(untestedSections(file.Name())).To(Equal([]Section{}))
@@ -366,3 +1532,63 @@ var _ = Describe("go-testcov", func() {
 		})
 	})
 })
+
+// real world shapes with the sections go test reports for them
+var multiLineShapes = `package shapes
+
+import "fmt"
+
+type Config struct {
+	Name string
+	Size int
+}
+
+// untested section
+func LongSignature(
+	name string,
+	size int,
+) *Config {
+	return &Config{Name: name, Size: size}
+}
+
+func MultiLineCondition(a, b bool) error {
+	if a &&
+		b { // untested section
+		return fmt.Errorf(
+			"both %v %v",
+			a,
+			b,
+		)
+	}
+	return nil
+}
+
+func StructLiteral(fail bool) *Config {
+	if fail {
+		return &Config{
+			Name: "fail", // untested section
+			Size: 1,
+		}
+	}
+	return nil
+}
+
+func Else(a bool) int {
+	if a {
+		return 1
+	} else { // untested section
+		return 2
+	}
+}
+
+func Switch(a int) int {
+	switch a {
+	case 1: // untested section
+		return 1
+	}
+	return 0
+}
+`
+
+// one-liner with an if and an else section on line 7 (7.9,7.14 and 7.22,7.27), continue the line to add comments
+var oneLineIfElse = "package shapes\n\nfunc a() {}\nfunc b() {}\n\nfunc OneLine(x bool) {\n\tif x { a() } else { b() }"
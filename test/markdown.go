@@ -0,0 +1 @@
+../markdown.go
\ No newline at end of file
@@ -0,0 +1,34 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("markdown", func() {
+	Describe("formatMarkdown", func() {
+		It("has a row per checked file and lists failures without a file", func() {
+			Expect(formatMarkdown(runResult{
+				exitCode:  1,
+				coverage:  75.25,
+				precision: 2,
+				files: []fileResult{
+					{Path: "a|b.go", Untested: 3, Configured: 1, Scope: "file", Status: "failed"},
+					{Path: "c.go", Untested: 1, Configured: 2, Scope: "file", Status: "stale"},
+				},
+				findings: []finding{
+					{Section: Section{Path: "a|b.go", Line: 1}, Severity: "error", Code: codeNewUntestedSection, Message: "new untested section introduced"},
+					{Severity: "error", Code: codeLowTotalCoverage, Message: "coverage 75.0% is below --min-coverage=80.0%"},
+				},
+			})).To(Equal("### go-testcov FAIL\n\nCoverage: 75.25%\n\n" +
+				"| File | New untested | Untested | Configured | Status |\n| --- | ---: | ---: | ---: | --- |\n" +
+				"| a\\|b.go | 2 | 3 | 1 | failed |\n" +
+				"| c.go | 0 | 1 | 2 | stale |\n\n" +
+				"- **LOW_TOTAL_COVERAGE** coverage 75.0% is below --min-coverage=80.0%\n"))
+		})
+
+		It("only has the verdict when nothing was checked", func() {
+			Expect(formatMarkdown(runResult{coverage: -1})).To(Equal("### go-testcov PASS\n\n"))
+		})
+	})
+})
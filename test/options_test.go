@@ -0,0 +1,303 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("options", func() {
+	Describe("parseOptions", func() {
+		It("passes unknown arguments to go test", func() {
+			opts, rest, err := parseOptions([]string{"./...", "-run", "Foo", "--count=1"})
+			Expect(err).To(BeNil())
+			Expect(opts).To(Equal(options{experimentalDays: 30, precision: 1, warnings: "summary"}))
+			Expect(rest).To(Equal([]string{"./...", "-run", "Foo", "--count=1"}))
+		})
+
+		It("extracts go-testcov options", func() {
+			opts, rest, err := parseOptions([]string{"--diff=main", ".", "--vcs=hg"})
+			Expect(err).To(BeNil())
+			Expect(opts).To(Equal(options{diff: "main", vcs: "hg", experimentalDays: 30, precision: 1, warnings: "summary"}))
+			Expect(rest).To(Equal([]string{"."}))
+		})
+
+		It("does not treat options without a value as go-testcov options", func() {
+			opts, rest, err := parseOptions([]string{"--diff"})
+			Expect(err).To(BeNil())
+			Expect(opts).To(Equal(options{experimentalDays: 30, precision: 1, warnings: "summary"}))
+			Expect(rest).To(Equal([]string{"--diff"}))
+		})
+
+		It("parses flags", func() {
+			opts, rest, err := parseOptions([]string{"--explain-ignores", "."})
+			Expect(err).To(BeNil())
+			Expect(opts).To(Equal(options{explainIgnores: true, experimentalDays: 30, precision: 1, warnings: "summary"}))
+			Expect(rest).To(Equal([]string{"."}))
+		})
+
+		It("does not treat flags with a value as go-testcov options", func() {
+			_, rest, err := parseOptions([]string{"--explain-ignores=1"})
+			Expect(err).To(BeNil())
+			Expect(rest).To(Equal([]string{"--explain-ignores=1"}))
+		})
+
+		It("parses percentages", func() {
+			opts, _, err := parseOptions([]string{"--min-coverage=85.5%"})
+			Expect(err).To(BeNil())
+			Expect(opts.minCoverage).To(Equal(85.5))
+		})
+
+		It("fails on invalid percentages", func() {
+			_, _, err := parseOptions([]string{"--min-coverage=101"})
+			Expect(err).To(MatchError("invalid percentage 101, expected a number between 0 and 100"))
+		})
+
+		It("fails on percentages with a locale specific decimal separator", func() {
+			_, _, err := parseOptions([]string{"--min-coverage=85,5"})
+			Expect(err).To(MatchError("invalid percentage 85,5, expected a number between 0 and 100"))
+			_, _, err = parseOptions([]string{"--min-coverage=NaN"})
+			Expect(err).To(MatchError("invalid percentage NaN, expected a number between 0 and 100"))
+		})
+
+		It("parses overrides", func() {
+			opts, _, err := parseOptions([]string{"--override=pkg/a=b.go=5", "--override=./c.go=0"})
+			Expect(err).To(BeNil())
+			Expect(opts.overrides).To(Equal(map[string]int{"pkg/a=b.go": 5, "c.go": 0}))
+		})
+
+		It("parses exclude", func() {
+			opts, _, err := parseOptions([]string{"--exclude=mocks/**,**/*_gen.go", "--exclude=a.go"})
+			noError(err)
+			Expect(opts.exclude).To(Equal([]string{"mocks/**", "**/*_gen.go", "a.go"}))
+		})
+
+		It("parses generated patterns", func() {
+			opts, _, err := parseOptions([]string{"--generated=_mock\\.go$", "--generated=\\.pb\\.go$"})
+			noError(err)
+			Expect(opts.generated).To(HaveLen(2))
+			Expect(opts.generated[1].String()).To(Equal("\\.pb\\.go$"))
+			_, _, err = parseOptions([]string{"--generated=("})
+			Expect(err).To(MatchError("invalid generated file pattern (: error parsing regexp: missing closing ): `(`"))
+		})
+
+		It("parses files", func() {
+			opts, _, err := parseOptions([]string{"--files=./pkg/a.go,pkg/*_test.go"})
+			noError(err)
+			Expect(opts.files).To(Equal([]string{"pkg/a.go", "pkg/*_test.go"}))
+		})
+
+		It("parses min-file-coverage", func() {
+			opts, _, err := parseOptions([]string{"--min-file-coverage=60", "--min-file-coverage=legacy/*.go=20%"})
+			noError(err)
+			Expect(opts.minFileCoverage).To(Equal(60.0))
+			Expect(opts.fileCoverageThresholds).To(Equal([]fileCoverageThreshold{{"legacy/*.go", 20}}))
+			_, _, err = parseOptions([]string{"--min-file-coverage=a.go=lots"})
+			Expect(err).To(MatchError("invalid percentage lots, expected a number between 0 and 100"))
+		})
+
+		It("parses max-untested-error-paths", func() {
+			opts, _, err := parseOptions([]string{"--max-untested-error-paths=3"})
+			noError(err)
+			Expect(opts.errorPaths).To(BeTrue())
+			Expect(opts.maxUntestedErrorPaths).To(Equal(3))
+			_, _, err = parseOptions([]string{"--max-untested-error-paths=-1"})
+			Expect(err).To(MatchError("invalid max-untested-error-paths -1, expected a number"))
+		})
+
+		It("parses allowances", func() {
+			opts, _, err := parseOptions([]string{"--allowance=internal/legacy/**=40 untested sections", "--allowance=cmd/*.go=2"})
+			noError(err)
+			Expect(opts.allowances).To(Equal([]pathAllowance{{"internal/legacy/**", 40}, {"cmd/*.go", 2}}))
+			_, _, err = parseOptions([]string{"--allowance=cmd/*.go"})
+			Expect(err).To(MatchError("invalid allowance cmd/*.go, expected glob=count"))
+		})
+
+		It("fails on invalid overrides", func() {
+			_, _, err := parseOptions([]string{"--override=a.go"})
+			Expect(err).To(MatchError("invalid override a.go, expected path=count"))
+			_, _, err = parseOptions([]string{"--override=a.go=many"})
+			Expect(err).To(MatchError("invalid override a.go=many, expected path=count"))
+		})
+
+		It("fails on invalid budget patterns", func() {
+			_, _, err := parseOptions([]string{"--budget-pattern=allowance: \\d+"})
+			Expect(err).To(MatchError("invalid budget pattern allowance: \\d+: expected one group that captures the count"))
+			_, _, err = parseOptions([]string{"--budget-pattern=("})
+			Expect(err).To(MatchError("invalid budget pattern (: error parsing regexp: missing closing ): `(`"))
+		})
+
+		It("runs go through the go wrapper", func() {
+			opts, _, err := parseOptions([]string{"--go-wrapper=docker run --rm golang"})
+			Expect(err).To(BeNil())
+			Expect(opts.goWrapper).To(Equal([]string{"docker", "run", "--rm", "golang"}))
+			Expect(runner).To(Equal(execRunner{}))
+		})
+
+		It("shows absolute paths", func() {
+			opts, _, err := parseOptions([]string{"--absolute-paths"})
+			noError(err)
+			Expect(opts.absolutePaths).To(BeTrue())
+			Expect(absolutePaths).To(BeFalse())
+		})
+
+		It("fails on empty go wrappers", func() {
+			_, _, err := parseOptions([]string{"--go-wrapper= "})
+			Expect(err).To(MatchError("--go-wrapper needs a command to run go with"))
+		})
+
+		It("parses dependency coverage", func() {
+			opts, _, err := parseOptions([]string{"--deps-min-coverage=./cmd/server=85", "--deps-min-coverage=./cmd/worker=70.5%"})
+			noError(err)
+			Expect(opts.dependencyGates).To(Equal([]dependencyGate{{"./cmd/server", 85}, {"./cmd/worker", 70.5}}))
+
+			_, _, err = parseOptions([]string{"--deps-min-coverage=./cmd/server"})
+			Expect(err).To(MatchError("invalid dependency coverage ./cmd/server, expected package=percent"))
+			_, _, err = parseOptions([]string{"--deps-min-coverage=./cmd/server=101"})
+			Expect(err).To(MatchError("invalid percentage 101, expected a number between 0 and 100"))
+		})
+
+		It("only checks the diff without thresholds in presubmit", func() {
+			opts, _, err := parseOptions([]string{"--stage=presubmit", "--diff=main", "--min-coverage=80", "--deps-min-coverage=./cmd=80"})
+			noError(err)
+			Expect(opts).To(Equal(options{stage: "presubmit", diff: "main", experimentalDays: 30, precision: 1, warnings: "summary"}))
+
+			_, _, err = parseOptions([]string{"--stage=presubmit"})
+			Expect(err).To(MatchError("--stage=presubmit needs --diff to know what changed"))
+		})
+
+		It("checks everything in postsubmit", func() {
+			opts, _, err := parseOptions([]string{"--stage=postsubmit", "--diff=main", "--baseline=b.out", "--forbid-budget-increase", "--min-coverage=80"})
+			noError(err)
+			Expect(opts).To(Equal(options{stage: "postsubmit", minCoverage: 80, experimentalDays: 30, precision: 1, warnings: "summary"}))
+		})
+
+		It("interpolates environment variables into values", func() {
+			withEnv("TESTCOV_MIN", "85", func() {
+				opts, _, err := parseOptions([]string{"--min-coverage=${TESTCOV_MIN:-60}"})
+				noError(err)
+				Expect(opts.minCoverage).To(Equal(85.0))
+			})
+			withoutEnv("TESTCOV_MIN", func() {
+				_, _, err := parseOptions([]string{"--min-coverage=${TESTCOV_MIN}"})
+				Expect(err).To(MatchError("--min-coverage=${TESTCOV_MIN}: environment variable TESTCOV_MIN is not set, set it or give a default like ${TESTCOV_MIN:-value}"))
+			})
+		})
+
+		It("reads options from the environment before the command line", func() {
+			withEnv("GO_TESTCOV_MIN_COVERAGE", "85", func() {
+				withEnv("GO_TESTCOV_PRECISION", "2", func() {
+					opts, _, err := parseOptions([]string{"--min-coverage=90"})
+					noError(err)
+					Expect(opts.minCoverage).To(Equal(90.0))
+					Expect(opts.precision).To(Equal(2))
+				})
+				withEnv("GO_TESTCOV_PRECISION", "many", func() {
+					_, _, err := parseOptions([]string{})
+					Expect(err).To(MatchError("GO_TESTCOV_PRECISION: invalid precision many, expected a number of decimals between 0 and 10"))
+				})
+			})
+		})
+
+		It("parses warning modes", func() {
+			opts, _, err := parseOptions([]string{"--warnings=off"})
+			noError(err)
+			Expect(opts.warnings).To(Equal("off"))
+			_, _, err = parseOptions([]string{"--warnings=some"})
+			Expect(err).To(MatchError("unknown warnings mode some, supported are summary, full, off"))
+		})
+
+		It("skips what the config says in each stage", func() {
+			inTempDir(func() {
+				writeFile(".go-testcov.yml", "presubmit-skip: [min-file-coverage]\n")
+				opts, _, err := parseOptions([]string{"--stage=presubmit", "--diff=main", "--min-coverage=80", "--min-file-coverage=50"})
+				noError(err)
+				Expect(opts.minCoverage).To(Equal(80.0))
+				Expect(opts.minFileCoverage).To(Equal(0.0))
+
+				withEnv("GO_TESTCOV_PRESUBMIT_SKIP", "min-coverage", func() {
+					opts, _, err = parseOptions([]string{"--stage=presubmit", "--diff=main", "--min-coverage=80", "--min-file-coverage=50"})
+					noError(err)
+					Expect(opts.minCoverage).To(Equal(0.0))
+					Expect(opts.minFileCoverage).To(Equal(0.0))
+				})
+
+				opts, _, err = parseOptions([]string{"--stage=postsubmit", "--postsubmit-skip=", "--diff=main"})
+				noError(err)
+				Expect(opts.diff).To(Equal("main"))
+
+				_, _, err = parseOptions([]string{"--presubmit-skip=verbose"})
+				Expect(err).To(MatchError(
+					"verbose can not be skipped, supported are baseline, deps-min-coverage, diff, forbid-budget-increase, forbid-new-ignores, " +
+						"min-coverage, min-file-coverage, show-resolved, target",
+				))
+			})
+		})
+
+		It("fails on unknown stages", func() {
+			_, _, err := parseOptions([]string{"--stage=nightly"})
+			Expect(err).To(MatchError("unknown stage nightly, supported are presubmit, postsubmit"))
+		})
+
+		It("parses precision", func() {
+			opts, _, err := parseOptions([]string{"--precision=0"})
+			Expect(err).To(BeNil())
+			Expect(opts.precision).To(Equal(0))
+		})
+
+		It("fails on invalid precision", func() {
+			_, _, err := parseOptions([]string{"--precision=-1"})
+			Expect(err).To(MatchError("invalid precision -1, expected a number of decimals between 0 and 10"))
+		})
+
+		It("fails on invalid values", func() {
+			_, _, err := parseOptions([]string{"--vcs=svn"})
+			Expect(err).To(MatchError("unknown version control system svn, supported are git, hg"))
+		})
+
+		It("parses experimental days", func() {
+			opts, _, err := parseOptions([]string{"--experimental-days=7"})
+			noError(err)
+			Expect(opts.experimentalDays).To(Equal(7))
+
+			_, _, err = parseOptions([]string{"--experimental-days=-1"})
+			Expect(err).To(MatchError("invalid number of days -1"))
+		})
+
+		It("parses report formats", func() {
+			opts, _, err := parseOptions([]string{"--format=json:a.json", "--format=quickfix", "--quickfix=b.qf", "--output=lcov=c.lcov", "--html=d.html", "--badge=e.svg"})
+			noError(err)
+			Expect(opts.reports).To(Equal([]reportDestination{{"json", "a.json"}, {"quickfix", ""}, {"quickfix", "b.qf"}, {"lcov", "c.lcov"}, {"html", "d.html"}, {"badge", "e.svg"}}))
+
+			_, _, err = parseOptions([]string{"--format=xml"})
+			Expect(err).To(MatchError("unknown format xml, supported are azure, badge, checkstyle, github, gitlab, html, json, junit, lcov, markdown, quickfix, rdjson, rdjsonl, sarif, shields, shields-untested, sonarqube, tap, teamcity"))
+			_, _, err = parseOptions([]string{"--output=lcov"})
+			Expect(err).To(MatchError("expected --output=FORMAT=FILE but got --output=lcov"))
+		})
+
+		It("parses covermodes", func() {
+			opts, _, err := parseOptions([]string{"--modes=set,atomic"})
+			noError(err)
+			Expect(opts.modes).To(Equal([]string{"set", "atomic"}))
+
+			_, _, err = parseOptions([]string{"--modes=set,race"})
+			Expect(err).To(MatchError("unknown covermode race, supported are set, count, atomic"))
+		})
+	})
+
+	Describe("useOptions", func() {
+		It("configures one run and restores the previous settings", func() {
+			opts, _, err := parseOptions([]string{"--go-wrapper=docker run golang", "--absolute-paths", "--nolint=mine"})
+			noError(err)
+			for i := 0; i < 2; i++ {
+				restore := useOptions(opts)
+				Expect(runner).To(Equal(goWrapperRunner{wrapper: opts.goWrapper, runner: execRunner{}}))
+				Expect(absolutePaths).To(BeTrue())
+				Expect(nolintNames).To(Equal([]string{"mine"}))
+				restore()
+			}
+			Expect(runner).To(Equal(execRunner{}))
+			Expect(absolutePaths).To(BeFalse())
+			Expect(nolintNames).To(Equal([]string{"testcov", "gocov"}))
+		})
+	})
+})
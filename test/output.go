@@ -0,0 +1 @@
+../output.go
\ No newline at end of file
@@ -0,0 +1,70 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("output", func() {
+	Describe("runGoTest", func() {
+		It("streams output without capturing", func() {
+			withFakeGo("echo ok", func() {
+				expectCommand(func() int { return runGoTest([]string{"test"}, options{}) }, []interface{}{0, "ok\n", ""})
+			})
+		})
+
+		It("hides output of passing tests", func() {
+			withFakeGo("echo ok; echo warning >&2", func() {
+				expectCommand(func() int { return runGoTest([]string{"test"}, options{captureOutput: true}) }, []interface{}{0, "", ""})
+			})
+		})
+
+		It("shows output of passing tests when asked to", func() {
+			withFakeGo("echo ok", func() {
+				expectCommand(
+					func() int { return runGoTest([]string{"test"}, options{captureOutput: true, alwaysShow: true}) },
+					[]interface{}{0, "ok\n", ""},
+				)
+			})
+		})
+
+		It("shows output and failed tests when tests fail", func() {
+			withFakeGo("printf -- '=== RUN   TestA\\n--- FAIL: TestA (0.00s)\\n    a_test.go:3: broken\\nFAIL\\n'; exit 1", func() {
+				expectCommand(
+					func() int { return runGoTest([]string{"test"}, options{captureOutput: true}) },
+					[]interface{}{
+						1,
+						"=== RUN   TestA\n--- FAIL: TestA (0.00s)\n    a_test.go:3: broken\nFAIL\n",
+						"failed tests:\n--- FAIL: TestA (0.00s)\n    a_test.go:3: broken\n",
+					},
+				)
+			})
+		})
+	})
+
+	Describe("extractTestFailures", func() {
+		It("finds failed tests, subtests and their messages", func() {
+			output := "=== RUN   TestA\n" +
+				"    a_test.go:3: log of passing test\n" +
+				"--- PASS: TestA (0.00s)\n" +
+				"    a_test.go:4: log of passing test\n" +
+				"--- FAIL: TestB (0.00s)\n" +
+				"    --- FAIL: TestB/sub (0.00s)\n" +
+				"        b_test.go:9: expected 1\n" +
+				"            got 2\n" +
+				"    --- PASS: TestB/other (0.00s)\n" +
+				"FAIL\n" +
+				"FAIL\texample.com/foo\t0.01s\n"
+			Expect(extractTestFailures(output)).To(Equal([]string{
+				"--- FAIL: TestB (0.00s)",
+				"    --- FAIL: TestB/sub (0.00s)",
+				"        b_test.go:9: expected 1",
+				"            got 2",
+			}))
+		})
+
+		It("finds nothing when the build failed", func() {
+			Expect(extractTestFailures("# example.com/foo\n./a.go:3:1: syntax error\nFAIL\n")).To(BeNil())
+		})
+	})
+})
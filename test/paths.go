@@ -0,0 +1 @@
+../paths.go
\ No newline at end of file
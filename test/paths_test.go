@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("paths", func() {
+	Describe("findGoModule", func() {
+		It("finds the nearest go.mod", func() {
+			withTempDir(func(dir string) {
+				writeFile(filepath.Join(dir, "go.mod"), "// comment\nmodule \"example.com/m\"\n\ngo 1.12\n")
+				nested := filepath.Join(dir, "pkg", "a")
+				noError(os.MkdirAll(nested, 0700))
+				Expect(findGoModule(nested)).To(Equal(&goModule{path: "example.com/m", root: dir}))
+			})
+		})
+
+		It("is nil outside of modules", func() {
+			withTempDir(func(dir string) {
+				Expect(findGoModule(dir)).To(BeNil())
+			})
+		})
+	})
+
+	Describe("normalizeCoveredPath", func() {
+		withModule := func(fn func(dir string)) {
+			withTempDir(func(dir string) {
+				writeFile(filepath.Join(dir, "go.mod"), "module example.com/m\n")
+				noError(os.MkdirAll(filepath.Join(dir, "pkg", "a"), 0700))
+				writeFile(filepath.Join(dir, "pkg", "a", "b.go"), "package a\n")
+				fn(dir)
+			})
+		}
+
+		It("shows paths relative to the module root from any directory", func() {
+			withModule(func(dir string) {
+				nested := filepath.Join(dir, "pkg", "a")
+				chDir(nested, func() {
+					displayPath, readPath := normalizeCoveredPath("example.com/m/pkg/a/b.go", nested)
+					Expect(displayPath).To(Equal("pkg/a/b.go"))
+					Expect(readPath).To(Equal("b.go"))
+				})
+				displayPath, readPath := normalizeCoveredPath("example.com/m/pkg/a/b.go", dir)
+				Expect(displayPath).To(Equal("pkg/a/b.go"))
+				Expect(readPath).To(Equal(filepath.Join("pkg", "a", "b.go")))
+			})
+		})
+
+		It("shows absolute paths with --absolute-paths", func() {
+			defer func(old bool) { absolutePaths = old }(absolutePaths)
+			absolutePaths = true
+			withModule(func(dir string) {
+				chDir(dir, func() {
+					displayPath, _ := normalizeCoveredPath("example.com/m/pkg/a/b.go", dir)
+					Expect(displayPath).To(Equal(filepath.ToSlash(filepath.Join(dir, "pkg", "a", "b.go"))))
+				})
+			})
+		})
+	})
+})
@@ -0,0 +1,116 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("report", func() {
+	Describe("writeReports", func() {
+		It("writes each format to its destination", func() {
+			inTempDir(func() {
+				findings := []finding{{Section: Section{Path: "b.go", Line: 1, Column: 1}, Message: "b"}, {Section: Section{Path: "a.go", Line: 1, Column: 1}, Message: "a"}}
+				reports := []reportDestination{{format: "quickfix", path: "report.qf"}, {format: "quickfix"}}
+				expectCommand(
+					func() int { writeReports(runResult{findings: findings}, options{reports: reports}); return 0 },
+					[]interface{}{0, "a.go:1:1: a\nb.go:1:1: b\n", ""},
+				)
+				Expect(readFile("report.qf")).To(Equal("a.go:1:1: a\nb.go:1:1: b\n"))
+			})
+		})
+
+		It("adds github annotations in github actions", func() {
+			withEnv("GITHUB_ACTIONS", "true", func() {
+				findings := []finding{{Section: Section{Path: "a.go", Line: 1, Column: 1}, Severity: "error", Code: codeNewUntestedSection, Message: "a"}}
+				expectCommand(
+					func() int { writeReports(runResult{findings: findings}, options{}); return 0 },
+					[]interface{}{0, "::error file=a.go,line=1,col=1,title=go-testcov NEW_UNTESTED_SECTION::a\n", ""},
+				)
+				expectCommand(
+					func() int {
+						writeReports(runResult{findings: findings}, options{reports: []reportDestination{{format: "github"}}})
+						return 0
+					},
+					[]interface{}{0, "::error file=a.go,line=1,col=1,title=go-testcov NEW_UNTESTED_SECTION::a\n", ""},
+				)
+			})
+		})
+
+		It("adds github annotations to stderr when stdout is taken", func() {
+			withEnv("GITHUB_ACTIONS", "true", func() {
+				findings := []finding{{Section: Section{Path: "a.go", Line: 1, Column: 1}, Severity: "error", Code: codeNewUntestedSection, Message: "a"}}
+				expectCommand(
+					func() int { writeReports(runResult{findings: findings}, options{goTestJSON: true}); return 0 },
+					[]interface{}{0, "", "::error file=a.go,line=1,col=1,title=go-testcov NEW_UNTESTED_SECTION::a\n"},
+				)
+				expectCommand(
+					func() int {
+						writeReports(runResult{findings: findings}, options{reports: []reportDestination{{format: "quickfix", path: "-"}}})
+						return 0
+					},
+					[]interface{}{0, "a.go:1:1: a\n", "::error file=a.go,line=1,col=1,title=go-testcov NEW_UNTESTED_SECTION::a\n"},
+				)
+			})
+		})
+	})
+
+	Describe("summaryLine", func() {
+		It("passes", func() {
+			Expect(summaryLine(runResult{findings: []finding{}, coverage: 81.44}, 1)).To(Equal("go-testcov: PASS new_untested=0 files=0 coverage=81.4%"))
+		})
+
+		It("counts failing untested sections and their files", func() {
+			Expect(summaryLine(runResult{exitCode: 1, coverage: 50, findings: []finding{
+				{Section: Section{Path: "a.go"}, Severity: "error", Code: codeNewUntestedSection, Message: "untested line of section 1.2,3.4 (1 current vs 0 configured)"},
+				{Section: Section{Path: "a.go"}, Severity: "error", Code: codeNewUntestedSection, Message: "untested line of section 1.2,3.4 (1 current vs 0 configured)"},
+				{Section: Section{Path: "b.go", Line: 1, Column: 2, EndLine: 1, EndColumn: 3}, Severity: "error", Code: codeNewUntestedSection, Message: "new untested section introduced (2 current vs 0 configured)"},
+				{Section: Section{Path: "b.go", Line: 2, Column: 2, EndLine: 2, EndColumn: 3}, Severity: "error", Code: codeNewUntestedSection, Message: "new untested section introduced (2 current vs 0 configured)"},
+				{Section: Section{Path: "c.go"}, Severity: "warning", Code: codeNewUntestedSection, Message: "new untested section introduced (1 current vs 0 configured)"},
+				{Section: Section{Path: "d.go"}, Severity: "warning", Code: codeStaleBudget, Message: "less"},
+			}}, 2)).To(Equal("go-testcov: FAIL new_untested=3 files=2 coverage=50.00%"))
+		})
+
+		It("reports failed tests without coverage", func() {
+			Expect(summaryLine(runResult{exitCode: 2, findings: []finding{}, coverage: -1}, 1)).To(Equal("go-testcov: FAIL go_test_exit=2"))
+		})
+	})
+
+	Describe("formatIdeReport", func() {
+		It("formats an empty report so editors clear old findings", func() {
+			Expect(formatIdeReport(runResult{findings: []finding{}, files: []fileResult{}, skipped: []skippedFile{}, phases: timings{{Phase: "go test", Seconds: 1.5}}})).To(Equal(
+				"{\n  \"version\": 1,\n  \"coverage\": 0,\n  \"statements\": 0,\n  \"coveredStatements\": 0,\n  \"findings\": [],\n  \"files\": [],\n  \"skipped\": [],\n  \"timings\": [\n    {\n      \"phase\": \"go test\",\n      \"seconds\": 1.5\n    }\n  ]\n}\n",
+			))
+		})
+
+		It("includes the result of each file", func() {
+			report := formatIdeReport(runResult{findings: []finding{}, files: []fileResult{{Path: "a.go", Untested: 2, Configured: 1, Scope: "file", Status: "failed"}}})
+			Expect(report).To(ContainSubstring(`"files": [
+    {
+      "path": "a.go",
+      "untested": 2,
+      "configured": 1,
+      "scope": "file",
+      "status": "failed"
+    }
+  ],`))
+		})
+	})
+
+	Describe("formatQuickfix", func() {
+		It("formats findings in vim errorformat", func() {
+			Expect(formatQuickfix(runResult{findings: []finding{
+				{Section: Section{Path: "a.go", Line: 1, Column: 2, EndLine: 3, EndColumn: 4}, Severity: "error", Message: "new"},
+				{Section: Section{Path: "b.go", Line: 3, Column: 1}, Severity: "warning", Message: "less"},
+				{Severity: "error", Code: codeLowTotalCoverage, Message: "low"},
+			}})).To(Equal("a.go:1:2: new\nb.go:3:1: warning: less\n"))
+		})
+	})
+
+	Describe("sortFindings", func() {
+		It("sorts by location", func() {
+			findings := []finding{{Section: Section{Path: "b", Line: 1}}, {Section: Section{Path: "a", Line: 2, Column: 3}}, {Section: Section{Path: "a", Line: 2, Column: 1}}, {Section: Section{Path: "a", Line: 1}}}
+			sortFindings(findings)
+			Expect(findings).To(Equal([]finding{{Section: Section{Path: "a", Line: 1}}, {Section: Section{Path: "a", Line: 2, Column: 1}}, {Section: Section{Path: "a", Line: 2, Column: 3}}, {Section: Section{Path: "b", Line: 1}}}))
+		})
+	})
+})
@@ -0,0 +1 @@
+../reviewdog.go
\ No newline at end of file
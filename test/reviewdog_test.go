@@ -0,0 +1,75 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("reviewdog", func() {
+	result := runResult{
+		findings: []finding{
+			untestedSectionFinding,
+			staleBudgetFinding,
+			lowTotalCoverageFinding,
+		},
+	}
+
+	Describe("formatRdjson", func() {
+		It("has a diagnostic per finding with a location", func() {
+			Expect(formatRdjson(result)).To(Equal(`{
+  "source": {
+    "name": "go-testcov",
+    "url": "https://github.com/grosser/go-testcov"
+  },
+  "severity": "ERROR",
+  "diagnostics": [
+    {
+      "message": "new untested section introduced (1 current vs 0 configured)",
+      "location": {
+        "path": "a.go",
+        "range": {
+          "start": {
+            "line": 1,
+            "column": 2
+          },
+          "end": {
+            "line": 3,
+            "column": 4
+          }
+        }
+      },
+      "severity": "ERROR",
+      "code": {
+        "value": "NEW_UNTESTED_SECTION"
+      }
+    },
+    {
+      "message": "less untested sections (0 current vs 1 configured), decrement configured untested?",
+      "location": {
+        "path": "b.go",
+        "range": {
+          "start": {
+            "line": 1
+          }
+        }
+      },
+      "severity": "WARNING",
+      "code": {
+        "value": "STALE_BUDGET"
+      }
+    }
+  ]
+}
+`))
+		})
+	})
+
+	Describe("formatRdjsonl", func() {
+		It("has a line with source per finding with a location", func() {
+			Expect(formatRdjsonl(result)).To(Equal(
+				`{"message":"new untested section introduced (1 current vs 0 configured)","location":{"path":"a.go","range":{"start":{"line":1,"column":2},"end":{"line":3,"column":4}}},"severity":"ERROR","source":{"name":"go-testcov","url":"https://github.com/grosser/go-testcov"},"code":{"value":"NEW_UNTESTED_SECTION"}}` + "\n" +
+					`{"message":"less untested sections (0 current vs 1 configured), decrement configured untested?","location":{"path":"b.go","range":{"start":{"line":1}}},"severity":"WARNING","source":{"name":"go-testcov","url":"https://github.com/grosser/go-testcov"},"code":{"value":"STALE_BUDGET"}}` + "\n",
+			))
+		})
+	})
+})
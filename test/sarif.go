@@ -0,0 +1 @@
+../sarif.go
\ No newline at end of file
@@ -0,0 +1,90 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("sarif", func() {
+	Describe("formatSarif", func() {
+		It("has a result per finding with a location", func() {
+			Expect(formatSarif(runResult{findings: []finding{
+				{Section: Section{Path: "pkg/a.go", Line: 1, Column: 2, EndLine: 3, EndColumn: 4}, Severity: "error", Code: codeNewUntestedSection, Message: "new"},
+				{Section: Section{Path: "b.go", Line: 1, Column: 1}, Severity: "warning", Code: codeStaleBudget, Message: "less"},
+				{Severity: "error", Code: codeLowTotalCoverage, Message: "low"},
+			}})).To(Equal(`{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "go-testcov",
+          "informationUri": "https://github.com/grosser/go-testcov",
+          "rules": [
+            {
+              "id": "NEW_UNTESTED_SECTION",
+              "shortDescription": {
+                "text": "More untested sections than configured"
+              }
+            },
+            {
+              "id": "STALE_BUDGET",
+              "shortDescription": {
+                "text": "Less untested sections than configured"
+              }
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "NEW_UNTESTED_SECTION",
+          "level": "error",
+          "message": {
+            "text": "new"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "pkg/a.go"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 2,
+                  "endLine": 3,
+                  "endColumn": 4
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "STALE_BUDGET",
+          "level": "warning",
+          "message": {
+            "text": "less"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "b.go"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 1
+                }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`))
+		})
+	})
+})
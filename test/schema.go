@@ -0,0 +1 @@
+../schema.go
\ No newline at end of file
@@ -0,0 +1,77 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("schema", func() {
+	Describe("reportSchema", func() {
+		It("matches the published schema", func() {
+			Expect(readFile("../report.schema.json")).To(Equal(formatReportSchema()), "run `go run . report schema > report.schema.json`")
+		})
+	})
+
+	Describe("validateReport", func() {
+		It("accepts the reports go-testcov writes", func() {
+			report := formatIdeReport(runResult{
+				findings: []finding{{Section: Section{Path: "a.go", Line: 1, Statements: 2, Function: "b"}, Severity: "error", Code: codeNewUntestedSection, Message: "a"}},
+				files:    []fileResult{{Path: "a.go", Untested: 1, Scope: "file", Status: "failed"}},
+				skipped:  []skippedFile{{Path: "b.go", Reason: "matches --exclude b.go"}},
+				phases:   timings{{Phase: "go test", Seconds: 1.5}},
+			})
+			Expect(validateReport([]byte(report))).To(BeEmpty())
+		})
+
+		It("finds missing fields and wrong types", func() {
+			Expect(validateReport([]byte(`{"version": 1, "coverage": 0, "statements": 0, "coveredStatements": 0, "findings": [{"path": 1}], "files": {}}`))).To(Equal([]string{
+				"report: missing skipped",
+				"report: missing timings",
+				"report.files: expected an array",
+				"report.findings[0]: missing code",
+				"report.findings[0]: missing column",
+				"report.findings[0]: missing endColumn",
+				"report.findings[0]: missing endLine",
+				"report.findings[0]: missing line",
+				"report.findings[0]: missing message",
+				"report.findings[0]: missing severity",
+				"report.findings[0].path: expected a string",
+			}))
+		})
+
+		It("rejects unknown codes", func() {
+			report := formatIdeReport(runResult{
+				findings: []finding{{Section: Section{Path: "a.go", Line: 1}, Severity: "error", Code: "NOPE", Message: "a"}}, files: []fileResult{}, skipped: []skippedFile{}, phases: timings{},
+			})
+			Expect(validateReport([]byte(report))).To(Equal([]string{"report.findings[0].code: unknown NOPE"}))
+		})
+
+		It("rejects other versions", func() {
+			Expect(validateReport([]byte(`{"version": 2}`))).To(Equal([]string{"version 2 is newer than this go-testcov understands, update go-testcov"}))
+			Expect(validateReport([]byte(`{"version": 0, "coverage": 0, "statements": 0, "coveredStatements": 0, "findings": [], "files": [], "skipped": [], "timings": []}`))).To(Equal([]string{"report.version: expected 1 but got 0"}))
+		})
+
+		It("rejects invalid json", func() {
+			Expect(validateReport([]byte(`{`))).To(Equal([]string{"unexpected end of JSON input"}))
+		})
+	})
+
+	Describe("runReport", func() {
+		It("validates files", func() {
+			inTempDir(func() {
+				writeFile("good.json", formatIdeReport(runResult{findings: []finding{}, files: []fileResult{}, skipped: []skippedFile{}, phases: timings{}}))
+				expectCommand(func() int { return runReport([]string{"validate", "good.json"}) }, []interface{}{0, "", "good.json is a valid version 1 report\n"})
+				writeFile("bad.json", `{"version": 1, "coverage": 0, "statements": 0, "coveredStatements": 0, "findings": [], "files": [], "skipped": []}`)
+				expectCommand(func() int { return runReport([]string{"validate", "bad.json"}) }, []interface{}{1, "", "bad.json is not a valid version 1 report:\nreport: missing timings\n"})
+			})
+		})
+
+		It("prints the schema", func() {
+			expectCommand(func() int { return runReport([]string{"schema"}) }, []interface{}{0, formatReportSchema(), ""})
+		})
+
+		It("shows usage", func() {
+			expectCommand(func() int { return runReport([]string{}) }, []interface{}{2, "", "Usage: go-testcov report schema|validate FILE\n"})
+		})
+	})
+})
@@ -0,0 +1,71 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("section", func() {
+	Describe("NewSection", func() {
+		It("parses statements and hits", func() {
+			section := NewSection("foo.go:4.7,8.3 2 5")
+			Expect(section.Statements).To(Equal(2))
+			Expect(section.Hits).To(Equal(5))
+		})
+
+		It("leaves statements empty when they are not listed", func() {
+			Expect(NewSection("foo.go:4.7,8.3 0").Statements).To(Equal(0))
+		})
+	})
+
+	Describe("function", func() {
+		source := parseSourceFile("foo.go", "package foo\n\nfunc a() {\n\tb()\n}\n\nvar c = 1\n")
+
+		It("finds the enclosing function", func() {
+			Expect(NewSection("foo.go:4.2,4.5 1 0").function(source)).To(Equal("a"))
+		})
+
+		It("is empty outside of functions", func() {
+			Expect(NewSection("foo.go:7.1,7.10 1 0").function(source)).To(Equal(""))
+		})
+	})
+
+	Describe("category", func() {
+		source := parseSourceFile("foo.go", "package foo\n\nfunc a() {\n\tif b { c() } else if d {\n\t\tc()\n\t}\n}\n")
+
+		It("is the keyword that opens the section", func() {
+			Expect(NewSection("foo.go:4.7,4.14 1 0").category(source)).To(Equal("if"))
+			Expect(NewSection("foo.go:4.25,6.3 1 0").category(source)).To(Equal("if"))
+			Expect(NewSection("foo.go:3.10,4.2 1 0").category(source)).To(Equal("func"))
+		})
+
+		It("is empty when no keyword opens the section", func() {
+			source := parseSourceFile("foo.go", "package foo\n\nfunc a() {\n\tb()\n\tc()\n}\n")
+			Expect(NewSection("foo.go:5.2,5.5 1 0").category(source)).To(Equal(""))
+		})
+	})
+
+	Describe("codeLines", func() {
+		source := parseSourceFile("foo.go", "package foo\n\nfunc a() {\n\tif b {\n\n\t\t// comment\n\t\tc()\n\t}\n}\n")
+
+		It("skips lines without code", func() {
+			Expect(NewSection("foo.go:4.7,8.3 1 0").codeLines(source)).To(Equal([]int{4, 7}))
+		})
+
+		It("keeps the first line when the file changed after the test run", func() {
+			Expect(NewSection("foo.go:50.1,51.2 1 0").codeLines(source)).To(Equal([]int{50}))
+		})
+	})
+
+	Describe("untestedCount", func() {
+		sections := []Section{{Path: "a.go", Line: 1, Column: 1, EndLine: 2, EndColumn: 1, Statements: 5}, {Path: "a.go", Line: 3, Column: 1, EndLine: 4, EndColumn: 1, Statements: 0}}
+
+		It("counts sections", func() {
+			Expect(untestedCount(sections, false)).To(Equal(2))
+		})
+
+		It("counts statements with --count-statements", func() {
+			Expect(untestedCount(sections, true)).To(Equal(6))
+		})
+	})
+})
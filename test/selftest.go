@@ -0,0 +1 @@
+../selftest.go
\ No newline at end of file
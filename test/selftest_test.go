@@ -0,0 +1,53 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("selftest", func() {
+	Describe("runSelftest", func() {
+		It("runs the miniature module through the whole pipeline", func() {
+			inTempDir(func() {
+				exitCode := -1
+				stdout, stderr := captureAll(func() {
+					exitCode = runSelftest([]string{"--format=json:testcov.json", "-count=1", "./pkg/..."})
+				})
+				Expect(exitCode).To(Equal(0))
+				Expect(stdout).To(ContainSubstring("verdict: FAIL new_untested=1 files=1 coverage=70.0%\n" +
+					"rules: NEW_UNTESTED_SECTION\n" +
+					"checked: budgeted.go (ok), ignored.go (ok), untested.go (failed)\n" +
+					"excluded: zz_generated.go (matches generated file pattern /*generated.*\\.go$)\n" +
+					"formats: json\n" +
+					"selftest: PASS\n"))
+				Expect(stderr).To(ContainSubstring("untested.go:5.3,6.1\n"))
+				Expect(directoryEntries(".")).To(BeEmpty())
+			})
+		})
+
+		It("fails when go test fails", func() {
+			withFakeGo("exit 1", func() {
+				expectCommand(
+					func() int { return runSelftest([]string{"--diff=main"}) },
+					[]interface{}{1, "", "selftest: --diff, --baseline and --deps-min-coverage are not exercised, they need the history and packages of your repo\nselftest: FAIL go test failed\n"},
+				)
+			})
+		})
+
+		It("keeps the values of go test flags", func() {
+			withFakeGo("echo go \"$@\"; exit 1", func() {
+				expectCommand(
+					func() int { return runSelftest([]string{"-timeout", "5m", "-run=X", "-v", "./pkg/..."}) },
+					[]interface{}{1, "go test -timeout 5m -run=X -v ./... -coverprofile coverage.out\n", "selftest: FAIL go test failed\n"},
+				)
+			})
+		})
+
+		It("fails on invalid options", func() {
+			expectCommand(
+				func() int { return runSelftest([]string{"--min-coverage=nope"}) },
+				[]interface{}{2, "", "invalid percentage nope, expected a number between 0 and 100\n"},
+			)
+		})
+	})
+})
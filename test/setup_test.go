@@ -7,14 +7,33 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestAwesome(t *testing.T) {
+	os.Unsetenv("GITHUB_ACTIONS") // tests expect the output without github annotations, also when ci runs them
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Example")
 }
 
+// findings the report format specs are written from, an untested section, a stale budget and a failure that has no file
+var (
+	untestedSectionFinding = finding{
+		Section:  Section{Path: "a.go", Line: 1, Column: 2, EndLine: 3, EndColumn: 4},
+		Severity: "error", Code: codeNewUntestedSection, Message: "new untested section introduced (1 current vs 0 configured)",
+	}
+	staleBudgetFinding = finding{
+		Section:  Section{Path: "b.go", Line: 1},
+		Severity: "warning", Code: codeStaleBudget, Message: "less untested sections (0 current vs 1 configured), decrement configured untested?",
+	}
+	lowTotalCoverageFinding = finding{Severity: "error", Code: codeLowTotalCoverage, Message: "total coverage 50.0% is below the required 75.0%"}
+)
+
 // using an expectation would hide the backtrace if it goes wrong
 func noError(err error) {
 	if err != nil {
@@ -68,6 +87,29 @@ func withoutEnv(key string, fn func()) {
 	fn()
 }
 
+// os.UserCacheDir uses XDG_CACHE_HOME on linux and HOME on mac
+func withCacheDir(fn func(dir string)) {
+	withTempDir(func(dir string) {
+		withEnv("XDG_CACHE_HOME", dir, func() {
+			withEnv("HOME", dir, func() {
+				fn(dir)
+			})
+		})
+	})
+}
+
+// every call to the clock is 1 second after the previous one
+func withFakeClock(fn func()) {
+	old := timeNow
+	now := time.Unix(0, 0)
+	timeNow = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	defer func() { timeNow = old }()
+	fn()
+}
+
 func inTempDir(fn func()) {
 	withTempDir(func(dir string) {
 		chDir(dir, fn)
@@ -97,6 +139,35 @@ func withFakeGo(content string, fn func()) {
 	})
 }
 
+// names of the files in a directory, to check that nothing was written into it
+func directoryEntries(directory string) (names []string) {
+	entries, err := ioutil.ReadDir(directory)
+	noError(err)
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return
+}
+
+// put an executable with the given script content into the PATH
+func withFakeCommand(name string, content string, fn func()) {
+	withTempDir(func(dir string) {
+		withEnv("PATH", dir+":"+os.Getenv("PATH"), func() {
+			writeFile(dir+"/"+name, "#!/bin/sh\n"+content)
+			fn()
+		})
+	})
+}
+
+// run git in the current directory without depending on the users git config
+func gitCommand(args ...string) {
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		panic(string(output))
+	}
+}
+
 // https://stackoverflow.com/questions/10473800/in-go-how-do-i-capture-stdout-of-a-function-into-a-string
 func captureStdout(fn func()) (captured string) {
 	old := os.Stdout // keep backup of the real
@@ -166,3 +237,14 @@ func withOsArgs(args []string, fn func()) {
 	fn()
 
 }
+
+// whether the go running the tests is at least go1.<minor>, development versions count as new enough
+func goVersionAtLeast(minor int) bool {
+	match := regexp.MustCompile(`^go1\.(\d+)`).FindStringSubmatch(runtime.Version())
+	if match == nil {
+		return true
+	}
+	found, err := strconv.Atoi(match[1])
+	noError(err)
+	return found >= minor
+}
@@ -0,0 +1 @@
+../shields.go
\ No newline at end of file
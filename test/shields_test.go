@@ -0,0 +1,31 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("shields", func() {
+	Describe("formatShields", func() {
+		It("shows the coverage", func() {
+			Expect(formatShields(runResult{coverage: 85.55, precision: 2})).To(Equal(`{"schemaVersion":1,"label":"coverage","message":"85.55%","color":"green"}` + "\n"))
+		})
+
+		It("is an error when go test failed", func() {
+			Expect(formatShields(runResult{coverage: -1})).To(Equal(`{"schemaVersion":1,"label":"coverage","message":"unknown","color":"lightgrey","isError":true}` + "\n"))
+		})
+	})
+
+	Describe("formatShieldsUntested", func() {
+		It("counts the untested sections of all files", func() {
+			files := []fileResult{{Path: "a.go", Untested: 2, Configured: 2}, {Path: "b.go", Untested: 1, Configured: 1}}
+			Expect(formatShieldsUntested(runResult{files: files})).To(Equal(`{"schemaVersion":1,"label":"untested sections","message":"3","color":"yellow"}` + "\n"))
+			Expect(formatShieldsUntested(runResult{exitCode: 1, files: files})).To(ContainSubstring(`"color":"red"`))
+			Expect(formatShieldsUntested(runResult{})).To(ContainSubstring(`"message":"0","color":"brightgreen"`))
+		})
+
+		It("is an error when go test failed", func() {
+			Expect(formatShieldsUntested(runResult{exitCode: 1, coverage: -1})).To(ContainSubstring(`"message":"unknown","color":"lightgrey","isError":true`))
+		})
+	})
+})
@@ -0,0 +1 @@
+../sonarqube.go
\ No newline at end of file
@@ -0,0 +1,26 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("sonarqube", func() {
+	Describe("formatSonarqube", func() {
+		It("lists whether each line is covered per file", func() {
+			Expect(formatSonarqube(runResult{
+				lines: map[string]map[int]int{"b.go": {3: 1}, "a.go": {5: 0, 2: 3}},
+			})).To(Equal(`<?xml version="1.0" encoding="UTF-8"?>
+<coverage version="1">
+  <file path="a.go">
+    <lineToCover lineNumber="2" covered="true"></lineToCover>
+    <lineToCover lineNumber="5" covered="false"></lineToCover>
+  </file>
+  <file path="b.go">
+    <lineToCover lineNumber="3" covered="true"></lineToCover>
+  </file>
+</coverage>
+`))
+		})
+	})
+})
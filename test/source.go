@@ -0,0 +1 @@
+../source.go
\ No newline at end of file
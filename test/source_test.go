@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("source", func() {
+	Describe("parseSourceFile", func() {
+		It("maps multi-line headers, opening braces and first statements to where the statement starts", func() {
+			Expect(parseSourceFile("shapes.go", multiLineShapes).headerStarts).To(Equal(map[int]int{
+				14: 11, 15: 11, // LongSignature
+				19: 18, 20: 19, 21: 19, // MultiLineCondition and its if
+				31: 30, 32: 31, // StructLiteral and its if
+				41: 40, 42: 41, 44: 43, // Else
+				49: 48, 51: 50, // Switch and its case
+			}))
+		})
+
+		It("finds functions including their doc comment", func() {
+			Expect(parseSourceFile("shapes.go", multiLineShapes).functions).To(Equal([][2]int{
+				{10, 16}, {18, 28}, {30, 38}, {40, 46}, {48, 54},
+			}))
+		})
+
+		It("finds functions by name", func() {
+			source := parseSourceFile("foo.go", "package foo\n\nfunc a() {}\n\nfunc (s Server) b() {}\n\n// c\nfunc (s *Server) c() {\n}\n")
+			Expect(source.functionNames).To(Equal(map[string][2]int{"a": {3, 3}, "Server.b": {5, 5}, "(*Server).c": {7, 9}}))
+		})
+
+		It("only has lines and directives for files that cannot be parsed", func() {
+			Expect(parseSourceFile("foo", "nope { // untested section\n")).To(Equal(sourceFile{
+				lines:           []string{"nope { // untested section", ""},
+				headerStarts:    map[int]int{},
+				directives:      map[int]directive{1: {}},
+				directiveErrors: map[int]error{},
+			}))
+		})
+
+		It("collects malformed directives", func() {
+			source := parseSourceFile("foo", "// untested section: nope\n")
+			Expect(source.directiveErrors).To(HaveKey(1))
+		})
+
+		It("collects regions without both ends", func() {
+			source := parseSourceFile("foo", "// untested section: begin\n// untested section: begin\n// untested section: end\n// untested section: end\n// untested section: begin\n")
+			Expect(source.directiveErrors).To(Equal(map[int]error{
+				1: errors.New("expected \"untested section: end\" before the next begin"),
+				4: errors.New("expected \"untested section: begin\" before end"),
+				5: errors.New("expected \"untested section: end\" after begin"),
+			}))
+			Expect(source.directiveLines()).To(Equal([]int{2, 3}))
+		})
+
+		It("finds critical markers", func() {
+			Expect(parseSourceFile("foo", "//testcov:critical\npackage foo\n").critical).To(Equal("file"))
+			Expect(parseSourceFile("foo", "//testcov:critical package\n//testcov:critical\n").critical).To(Equal("package"))
+			Expect(parseSourceFile("foo", "package foo\n").critical).To(Equal(""))
+		})
+	})
+
+	Describe("isCriticalPackage", func() {
+		It("finds package markers in go files", func() {
+			inTempDir(func() {
+				writeFile("a.go", "package foo\n//testcov:critical\n")
+				Expect(isCriticalPackage(".")).To(BeFalse())
+				writeFile("b_test.go", "//testcov:critical package\npackage foo\n")
+				Expect(isCriticalPackage(".")).To(BeFalse())
+				writeFile("doc.go", "//testcov:critical package\npackage foo\n")
+				Expect(isCriticalPackage(".")).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("findPackageBudget", func() {
+		It("finds package budgets in go files", func() {
+			inTempDir(func() {
+				writeFile("a.go", "package foo\n// untested sections: 1\n")
+				Expect(findPackageBudget(".")).To(Equal(packageBudget{}))
+				writeFile("a_test.go", "// untested sections (package): 2\n")
+				Expect(findPackageBudget(".")).To(Equal(packageBudget{}))
+				writeFile("doc.go", "package foo\n// untested sections (package): 3\n")
+				Expect(findPackageBudget(".")).To(Equal(packageBudget{count: 3, path: "doc.go", line: 2, found: true}))
+			})
+		})
+	})
+
+	Describe("configuredUntested", func() {
+		It("finds the first budget", func() {
+			count, lineNumber := parseSourceFile("foo", "a // untested section\n// untested sections: 3\n// untested sections: 4\n").configuredUntested()
+			Expect([]int{count, lineNumber}).To(Equal([]int{3, 2}))
+		})
+
+		It("ignores package budgets", func() {
+			count, lineNumber := parseSourceFile("foo", "// untested sections (package): 3\n").configuredUntested()
+			Expect([]int{count, lineNumber}).To(Equal([]int{0, 0}))
+		})
+
+		It("returns 0 without budget", func() {
+			count, lineNumber := parseSourceFile("foo", "").configuredUntested()
+			Expect([]int{count, lineNumber}).To(Equal([]int{0, 0}))
+		})
+	})
+
+	Describe("enclosingFunction", func() {
+		source := sourceFile{functions: [][2]int{{1, 10}, {3, 5}}}
+
+		It("finds the innermost function", func() {
+			function, found := source.enclosingFunction(4)
+			Expect(found).To(BeTrue())
+			Expect(function).To(Equal([2]int{3, 5}))
+		})
+
+		It("finds the outer function", func() {
+			function, found := source.enclosingFunction(6)
+			Expect(found).To(BeTrue())
+			Expect(function).To(Equal([2]int{1, 10}))
+		})
+
+		It("finds nothing outside of functions", func() {
+			_, found := source.enclosingFunction(11)
+			Expect(found).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1 @@
+../subprocess.go
\ No newline at end of file
@@ -0,0 +1 @@
+../svg.go
\ No newline at end of file
@@ -0,0 +1,31 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("svg", func() {
+	Describe("formatSvgBadge", func() {
+		It("renders the coverage with its color", func() {
+			Expect(formatSvgBadge(runResult{coverage: 85.55, precision: 1})).To(Equal(`<svg xmlns="http://www.w3.org/2000/svg" width="111" height="20" role="img" aria-label="coverage: 85.5%">
+<title>coverage: 85.5%</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="111" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="66" height="20" fill="#555"/><rect x="66" width="45" height="20" fill="#97ca00"/><rect width="111" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11"><text x="33" y="14">coverage</text><text x="88" y="14">85.5%</text></g>
+</svg>
+`))
+		})
+
+		It("shows the coverage with --precision decimals", func() {
+			Expect(formatSvgBadge(runResult{coverage: 85.55, precision: 2})).To(ContainSubstring("<title>coverage: 85.55%</title>"))
+		})
+
+		It("is grey when go test failed", func() {
+			content := formatSvgBadge(runResult{coverage: -1})
+			Expect(content).To(ContainSubstring(`fill="#9f9f9f"`))
+			Expect(content).To(ContainSubstring(">unknown</text>"))
+		})
+	})
+})
@@ -0,0 +1 @@
+../tap.go
\ No newline at end of file
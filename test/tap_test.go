@@ -0,0 +1,30 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("tap", func() {
+	Describe("formatTap", func() {
+		It("has a test point per file and run failure", func() {
+			Expect(formatTap(runResult{
+				findings: []finding{
+					untestedSectionFinding,
+					lowTotalCoverageFinding,
+				},
+				files: []fileResult{
+					{Path: "a.go", Untested: 1, Configured: 0, Scope: "file", Status: "failed"},
+					{Path: "b.go", Untested: 1, Configured: 1, Scope: "file", Status: "ok"},
+				},
+			})).To(Equal("TAP version 13\n1..3\n" +
+				"not ok 1 - a.go\n# 1 untested sections but 0 configured\n# a.go:1.2,3.4\n" +
+				"ok 2 - b.go\n" +
+				"not ok 3 - LOW_TOTAL_COVERAGE\n# total coverage 50.0% is below the required 75.0%\n"))
+		})
+
+		It("has no test points when nothing was checked", func() {
+			Expect(formatTap(runResult{})).To(Equal("TAP version 13\n1..0\n"))
+		})
+	})
+})
@@ -0,0 +1 @@
+../target.go
\ No newline at end of file
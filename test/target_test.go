@@ -0,0 +1,52 @@
+package main
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("target", func() {
+	Describe("parseCoverageTarget", func() {
+		It("parses the goal and its dates", func() {
+			target, err := parseCoverageTarget("90% by 2025-12-31 from 2025-01-01")
+			noError(err)
+			Expect(*target).To(Equal(coverageTarget{
+				percent: 90,
+				by:      time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+				from:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			}))
+			Expect(target.String()).To(Equal("90% by 2025-12-31"))
+		})
+
+		It("fails on invalid targets", func() {
+			_, err := parseCoverageTarget("90% by 2025-12-31")
+			Expect(err).To(MatchError("invalid target 90% by 2025-12-31, expected like 90% by 2025-12-31 from 2025-01-01"))
+			_, err = parseCoverageTarget("190% by 2025-12-31 from 2025-01-01")
+			Expect(err).To(MatchError("invalid percentage 190%, expected a number between 0 and 100"))
+			_, err = parseCoverageTarget("90% by 2025-13-31 from 2025-01-01")
+			Expect(err).To(MatchError("invalid target 90% by 2025-13-31 from 2025-01-01, expected like 90% by 2025-12-31 from 2025-01-01"))
+			_, err = parseCoverageTarget("90% by 2025-01-01 from 2025-12-31")
+			Expect(err).To(MatchError("invalid target 90% by 2025-01-01 from 2025-12-31, the from date needs to be before the by date"))
+		})
+	})
+
+	Describe("scheduledMinCoverage", func() {
+		target := coverageTarget{
+			percent: 90,
+			by:      time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC),
+			from:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+
+		It("ramps from the minimum to the goal", func() {
+			Expect(scheduledMinCoverage(target, 70, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))).To(Equal(70.0))
+			Expect(scheduledMinCoverage(target, 70, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))).To(Equal(80.0))
+			Expect(scheduledMinCoverage(target, 70, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))).To(Equal(90.0))
+		})
+
+		It("never lowers the minimum", func() {
+			Expect(scheduledMinCoverage(target, 95, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))).To(Equal(95.0))
+		})
+	})
+})
@@ -0,0 +1 @@
+../teamcity.go
\ No newline at end of file
@@ -0,0 +1,34 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("teamcity", func() {
+	Describe("formatTeamcity", func() {
+		It("reports coverage, inspections and the build problem", func() {
+			Expect(formatTeamcity(runResult{
+				exitCode: 1,
+				findings: []finding{
+					untestedSectionFinding,
+					staleBudgetFinding,
+					lowTotalCoverageFinding,
+				},
+				coverage:  50,
+				precision: 2,
+			})).To(Equal(
+				"##teamcity[buildStatisticValue key='CodeCoverageS' value='50.00']\n" +
+					"##teamcity[inspectionType id='NEW_UNTESTED_SECTION' name='NEW_UNTESTED_SECTION' category='go-testcov' description='More untested sections than configured']\n" +
+					"##teamcity[inspectionType id='STALE_BUDGET' name='STALE_BUDGET' category='go-testcov' description='Less untested sections than configured']\n" +
+					"##teamcity[inspection typeId='NEW_UNTESTED_SECTION' message='new untested section introduced (1 current vs 0 configured)' file='a.go' line='1' SEVERITY='ERROR']\n" +
+					"##teamcity[inspection typeId='STALE_BUDGET' message='less untested sections (0 current vs 1 configured), decrement configured untested?' file='b.go' line='1' SEVERITY='WARNING']\n" +
+					"##teamcity[buildProblem description='go-testcov: FAIL new_untested=1 files=1 coverage=50.00%' identity='go-testcov']\n",
+			))
+		})
+
+		It("escapes values", func() {
+			Expect(teamcityMessage("message", "text", "it's [a|b]\n")).To(Equal("##teamcity[message text='it|'s |[a||b|]|n']\n"))
+		})
+	})
+})
@@ -0,0 +1 @@
+../testjson.go
\ No newline at end of file
@@ -0,0 +1,34 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("testjson", func() {
+	Describe("coverageTestEvents", func() {
+		It("reports failures as output of a failed coverage test", func() {
+			withFakeClock(func() {
+				Expect(coverageTestEvents(runResult{
+					exitCode: 1,
+					findings: []finding{
+						untestedSectionFinding,
+						staleBudgetFinding,
+					},
+					phases:   timings{{Phase: "go test", Seconds: 1.5}},
+					coverage: 50,
+				}, 1)).To(Equal(
+					`{"Time":"1970-01-01T00:00:01Z","Action":"run","Package":"go-testcov","Test":"Coverage"}` + "\n" +
+						`{"Time":"1970-01-01T00:00:01Z","Action":"output","Package":"go-testcov","Test":"Coverage","Output":"a.go:1: new untested section introduced (1 current vs 0 configured)\n"}` + "\n" +
+						`{"Time":"1970-01-01T00:00:01Z","Action":"output","Package":"go-testcov","Test":"Coverage","Output":"go-testcov: FAIL new_untested=1 files=1 coverage=50.0%\n"}` + "\n" +
+						`{"Time":"1970-01-01T00:00:01Z","Action":"fail","Package":"go-testcov","Test":"Coverage","Elapsed":1.5}` + "\n" +
+						`{"Time":"1970-01-01T00:00:01Z","Action":"fail","Package":"go-testcov","Elapsed":1.5}` + "\n",
+				))
+			})
+		})
+
+		It("passes when the run passed", func() {
+			Expect(coverageTestEvents(runResult{findings: []finding{}, coverage: 100}, 1)).To(ContainSubstring(`"Action":"pass","Package":"go-testcov"}`))
+		})
+	})
+})
@@ -0,0 +1 @@
+../timing.go
\ No newline at end of file
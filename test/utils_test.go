@@ -3,6 +3,8 @@ package main
 import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io"
+	"io/ioutil"
 	"os"
 )
 
@@ -21,6 +23,32 @@ var _ = Describe("go-testcov", func() {
 		})
 	})
 
+	Describe("runCommandsConcurrently", func() {
+		It("prefixes whole lines and returns the exit code of each command", func() {
+			var exitCodes []int
+			stdout, stderr := captureAll(func() {
+				exitCodes = runCommandsConcurrently([]command{
+					{prefix: "a: ", name: "sh", args: []string{"-c", "printf 'one\\ntw'; sleep 0.1; printf 'o\\nthree'"}},
+					{prefix: "b: ", name: "sh", args: []string{"-c", "echo err >&2; exit 3"}},
+					{prefix: "c: ", name: "sh", args: []string{"-c", "exit 1"}},
+				})
+			})
+			Expect(exitCodes).To(Equal([]int{0, 3, 1}))
+			Expect(stdout).To(Equal("a: one\na: two\na: three\n"))
+			Expect(stderr).To(Equal("b: err\n"))
+		})
+
+		It("runs in the given directory", func() {
+			inTempDir(func() {
+				noError(os.Mkdir("nested", 0700))
+				stdout := captureStdout(func() {
+					runCommandsConcurrently([]command{{prefix: "> ", directory: "nested", name: "sh", args: []string{"-c", "basename $PWD"}}})
+				})
+				Expect(stdout).To(Equal("> nested\n"))
+			})
+		})
+	})
+
 	Describe("runCommand", func() {
 		It("runs the given command", func() {
 			exitCode := -1
@@ -52,4 +80,115 @@ var _ = Describe("go-testcov", func() {
 			Expect(stderr).To(Equal("Could not get exit code for failed program: wuuuut, [--nope]\n"))
 		})
 	})
+
+	Describe("commandOutput", func() {
+		It("returns stdout and streams stderr", func() {
+			output := ""
+			stderr := captureStderr(func() { output = commandOutput("sh", "-c", "echo out; echo err >&2") })
+			Expect(output).To(Equal("out\n"))
+			Expect(stderr).To(Equal("err\n"))
+		})
+
+		It("panics when the command fails", func() {
+			defer func() {
+				Expect(recover()).To(MatchError("sh -c exit 3 failed with exit code 3"))
+			}()
+			commandOutput("sh", "-c", "exit 3")
+		})
+	})
+
+	Describe("commandOutputIfSuccessful", func() {
+		It("returns stdout without stderr", func() {
+			output, ok := commandOutputIfSuccessful("sh", "-c", "echo out; echo err >&2")
+			Expect([]interface{}{output, ok}).To(Equal([]interface{}{"out\n", true}))
+		})
+
+		It("is not ok when the command fails", func() {
+			_, ok := commandOutputIfSuccessful("sh", "-c", "exit 3")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("runs the command with the injected runner", func() {
+			defer func(old commandRunner) { runner = old }(runner)
+			fake := &fakeRunner{output: "faked\n"}
+			runner = fake
+			content, ok := git{}.fileAt("HEAD", "a.go")
+			Expect([]interface{}{content, ok}).To(Equal([]interface{}{"faked\n", true}))
+			Expect(fake.commands).To(Equal([][]string{{"git", "show", "HEAD:./a.go"}}))
+		})
+	})
+
+	Describe("runner", func() {
+		It("runs commands with the injected runner", func() {
+			defer func(old commandRunner) { runner = old }(runner)
+			fake := &fakeRunner{output: "faked\n", exitCode: 3}
+			runner = fake
+			exitCode, output := runCommandCapturingOutput("go", "test", ".")
+			Expect(exitCode).To(Equal(3))
+			Expect(output).To(Equal("faked\n"))
+			Expect(fake.commands).To(Equal([][]string{{"go", "test", "."}}))
+		})
+
+		It("runs go commands through the go wrapper", func() {
+			fake := &fakeRunner{}
+			wrapped := goWrapperRunner{wrapper: []string{"docker", "run", "golang"}, runner: fake}
+			wrapped.run("", nil, ioutil.Discard, ioutil.Discard, "go", []string{"test", "."})
+			wrapped.run("", nil, ioutil.Discard, ioutil.Discard, "git", []string{"status"})
+			Expect(fake.commands).To(Equal([][]string{{"docker", "run", "golang", "go", "test", "."}, {"git", "status"}}))
+		})
+	})
+
+	Describe("matchesPathGlob", func() {
+		It("matches the full path", func() {
+			Expect(matchesPathGlob("pkg/a.go", "pkg/*.go")).To(BeTrue())
+		})
+
+		It("matches trailing parts of the path", func() {
+			Expect(matchesPathGlob("github.com/foo/bar/pkg/a.go", "pkg/*.go")).To(BeTrue())
+			Expect(matchesPathGlob("github.com/foo/bar/pkg/a.go", "a.go")).To(BeTrue())
+		})
+
+		It("does not match partial directory names", func() {
+			Expect(matchesPathGlob("github.com/foo/bar/xpkg/a.go", "pkg/*.go")).To(BeFalse())
+		})
+
+		It("matches everything below a directory with a trailing /**", func() {
+			Expect(matchesPathGlob("internal/legacy/a.go", "internal/legacy/**")).To(BeTrue())
+			Expect(matchesPathGlob("github.com/foo/internal/legacy/b/c.go", "internal/legacy/**")).To(BeTrue())
+			Expect(matchesPathGlob("internal/legacy.go", "internal/legacy/**")).To(BeFalse())
+		})
+
+		It("matches any number of directories with **", func() {
+			Expect(matchesPathGlob("github.com/foo/pkg/a_gen.go", "**/*_gen.go")).To(BeTrue())
+			Expect(matchesPathGlob("a_gen.go", "**/*_gen.go")).To(BeTrue())
+			Expect(matchesPathGlob("pkg/a/b/mock.go", "pkg/**/mock.go")).To(BeTrue())
+			Expect(matchesPathGlob("pkg/mock.go", "pkg/**/mock.go")).To(BeTrue())
+			Expect(matchesPathGlob("pkg/a/b/real.go", "pkg/**/mock.go")).To(BeFalse())
+		})
+	})
+
+	Describe("matchesAnyPathGlob", func() {
+		It("matches when any glob matches", func() {
+			Expect(matchesAnyPathGlob("pkg/a.go", []string{"nope", "*/a.go"})).To(BeTrue())
+			Expect(matchesAnyPathGlob("pkg/a.go", []string{"nope"})).To(BeFalse())
+		})
+	})
+	Describe("removeString", func() {
+		It("removes all occurrences", func() {
+			Expect(removeString([]string{"a", "b", "a"}, "a")).To(Equal([]string{"b"}))
+		})
+	})
 })
+
+// records the commands it was asked to run and writes the same output for each
+type fakeRunner struct {
+	commands [][]string
+	output   string
+	exitCode int
+}
+
+func (r *fakeRunner) run(directory string, environment []string, stdout io.Writer, stderr io.Writer, name string, args []string) (exitCode int) {
+	r.commands = append(r.commands, append([]string{name}, args...))
+	_, _ = io.WriteString(stdout, r.output)
+	return r.exitCode
+}
@@ -0,0 +1 @@
+../vcs.go
\ No newline at end of file
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("vcs", func() {
+	Describe("detectVersionControl", func() {
+		It("uses the configured version control", func() {
+			inTempDir(func() {
+				vcs, found := detectVersionControl("hg")
+				Expect(found).To(BeTrue())
+				Expect(vcs).To(Equal(mercurial{}))
+			})
+		})
+
+		It("finds git in a parent directory", func() {
+			inTempDir(func() {
+				noError(os.MkdirAll(".git", 0700))
+				noError(os.MkdirAll("nested", 0700))
+				chDir("nested", func() {
+					vcs, found := detectVersionControl("")
+					Expect(found).To(BeTrue())
+					Expect(vcs).To(Equal(git{}))
+				})
+			})
+		})
+
+		It("finds mercurial", func() {
+			inTempDir(func() {
+				noError(os.MkdirAll(".hg", 0700))
+				vcs, found := detectVersionControl("")
+				Expect(found).To(BeTrue())
+				Expect(vcs).To(Equal(mercurial{}))
+			})
+		})
+	})
+
+	Describe("git", func() {
+		It("lists changed files relative to the current directory", func() {
+			inTempDir(func() {
+				gitCommand("init", "-q")
+				noError(os.MkdirAll("nested", 0700))
+				writeFile("nested/changed.go", "")
+				writeFile("nested/unchanged.go", "")
+				writeFile("outside.go", "")
+				gitCommand("add", ".")
+				gitCommand("commit", "-q", "-m", "initial")
+				writeFile("nested/changed.go", "changed")
+				writeFile("outside.go", "changed")
+				chDir("nested", func() {
+					Expect(git{}.changedFiles("HEAD")).To(Equal([]string{"changed.go"}))
+				})
+			})
+		})
+
+		It("lists untracked files that are not ignored", func() {
+			inTempDir(func() {
+				gitCommand("init", "-q")
+				noError(os.MkdirAll("nested", 0700))
+				writeFile(".gitignore", "ignored.go\n")
+				gitCommand("add", ".")
+				gitCommand("commit", "-q", "-m", "initial")
+				writeFile("nested/new.go", "")
+				writeFile("nested/ignored.go", "")
+				writeFile("outside.go", "")
+				chDir("nested", func() {
+					Expect(git{}.changedFiles("HEAD")).To(Equal([]string{"new.go"}))
+				})
+			})
+		})
+
+		It("reads files at a revision relative to the current directory", func() {
+			inTempDir(func() {
+				gitCommand("init", "-q")
+				noError(os.MkdirAll("nested", 0700))
+				writeFile("nested/a.go", "before")
+				gitCommand("add", ".")
+				gitCommand("commit", "-q", "-m", "initial")
+				writeFile("nested/a.go", "after")
+				chDir("nested", func() {
+					content, ok := git{}.fileAt("HEAD", "a.go")
+					Expect([]interface{}{content, ok}).To(Equal([]interface{}{"before", true}))
+					_, ok = git{}.fileAt("HEAD", "new.go")
+					Expect(ok).To(BeFalse())
+				})
+			})
+		})
+
+		It("lists ancestors newest first", func() {
+			inTempDir(func() {
+				gitCommand("init", "-q")
+				gitCommand("commit", "-q", "--allow-empty", "-m", "first")
+				gitCommand("commit", "-q", "--allow-empty", "-m", "second")
+				gitCommand("commit", "-q", "--allow-empty", "-m", "third")
+				Expect(git{}.ancestors("HEAD~1", 5)).To(Equal([]string{
+					commandOutput("git", "rev-parse", "HEAD~1")[:40], commandOutput("git", "rev-parse", "HEAD~2")[:40],
+				}))
+				Expect(git{}.ancestors("HEAD", 1)).To(HaveLen(1))
+			})
+		})
+
+		It("finds when a line was committed via git blame", func() {
+			inTempDir(func() {
+				gitCommand("init", "-q")
+				writeFile("foo.go", "package foo\n//testcov:experimental\n")
+				gitCommand("add", "foo.go")
+				withEnv("GIT_AUTHOR_DATE", "2020-01-02T03:04:05Z", func() {
+					gitCommand("commit", "-q", "-m", "initial")
+				})
+				added, found := git{}.lineAddedAt("foo.go", 2)
+				Expect(found).To(BeTrue())
+				Expect(added.UTC()).To(Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)))
+			})
+		})
+
+		It("treats untracked files as added now", func() {
+			inTempDir(func() {
+				gitCommand("init", "-q")
+				writeFile("foo.go", "package foo\n//testcov:experimental\n")
+				withFakeClock(func() {
+					added, found := git{}.lineAddedAt("foo.go", 2)
+					Expect(found).To(BeTrue())
+					Expect(added).To(Equal(time.Unix(1, 0)))
+				})
+			})
+		})
+
+		It("does not find anything outside of repositories", func() {
+			inTempDir(func() {
+				writeFile("foo.go", "package foo\n//testcov:experimental\n")
+				_, found := git{}.lineAddedAt("foo.go", 2)
+				Expect(found).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("mercurial", func() {
+		It("lists changed and untracked files via hg status", func() {
+			withFakeCommand("hg", "echo \"$@\"", func() {
+				Expect(mercurial{}.changedFiles("default")).To(Equal(
+					[]string{"status --no-status --modified --added --unknown --rev default ."},
+				))
+			})
+		})
+
+		It("lists ancestors via hg log", func() {
+			withFakeCommand("hg", "printf '%s|' \"$@\"", func() { // echo would turn the template's \n into a newline
+				Expect(mercurial{}.ancestors("default", 3)).To(Equal(
+					[]string{"log|--template|{node}\\n|--limit|3|--rev|reverse(ancestors(default))|"},
+				))
+			})
+		})
+
+		It("reads files at a revision via hg cat", func() {
+			withFakeCommand("hg", "echo \"$@\"", func() {
+				content, ok := mercurial{}.fileAt("default", "a.go")
+				Expect([]interface{}{content, ok}).To(Equal([]interface{}{"cat --rev default a.go\n", true}))
+			})
+			withFakeCommand("hg", "exit 1", func() {
+				_, ok := mercurial{}.fileAt("default", "a.go")
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		It("finds when a line was committed via hg annotate", func() {
+			withFakeCommand("hg", "printf '1 1577934245 0\\n2147483647 1600000000 0\\n'", func() {
+				added, found := mercurial{}.lineAddedAt("foo.go", 1)
+				Expect(found).To(BeTrue())
+				Expect(added.UTC()).To(Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+				withFakeClock(func() {
+					added, found = mercurial{}.lineAddedAt("foo.go", 2) // not committed yet
+					Expect([]interface{}{added, found}).To(Equal([]interface{}{time.Unix(1, 0), true}))
+				})
+			})
+		})
+
+		It("treats files hg can not annotate as added now inside of repositories", func() {
+			withFakeCommand("hg", "[ \"$3\" = root ]", func() {
+				withFakeClock(func() {
+					added, found := mercurial{}.lineAddedAt("foo.go", 1)
+					Expect([]interface{}{added, found}).To(Equal([]interface{}{time.Unix(1, 0), true}))
+				})
+			})
+			withFakeCommand("hg", "exit 255", func() {
+				_, found := mercurial{}.lineAddedAt("foo.go", 1)
+				Expect(found).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("changedFilesSince", func() {
+		It("expands paths", func() {
+			withFakeCommand("hg", "echo a.go; echo b/c.go", func() {
+				Expect(changedFilesSince("default", mercurial{}, "/foo")).To(Equal(
+					map[string]bool{"/foo/a.go": true, "/foo/b/c.go": true},
+				))
+			})
+		})
+	})
+})
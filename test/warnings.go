@@ -0,0 +1 @@
+../warnings.go
\ No newline at end of file
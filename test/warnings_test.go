@@ -0,0 +1,37 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("warnings", func() {
+	Describe("warningCollector", func() {
+		warnAll := func(collector *warningCollector) {
+			collector.warn("b.go", "b.go has less untested sections\nconfigured on: b.go:1\n")
+			collector.warn("a.go", "a.go is stale\n")
+			collector.warn("b.go", "b.go has less untested sections\nconfigured on: b.go:1\n")
+			collector.print()
+		}
+
+		It("prints warnings once in a block grouped by path with counts", func() {
+			Expect(captureStderr(func() { warnAll(newWarningCollector("summary")) })).To(Equal(
+				"warnings (3):\na.go is stale\nb.go has less untested sections configured on: b.go:1 (2 times)\n",
+			))
+		})
+
+		It("prints warnings where they happen with full", func() {
+			Expect(captureStderr(func() { warnAll(newWarningCollector("full")) })).To(Equal(
+				"b.go has less untested sections\nconfigured on: b.go:1\na.go is stale\nb.go has less untested sections\nconfigured on: b.go:1\n",
+			))
+		})
+
+		It("prints nothing with off", func() {
+			Expect(captureStderr(func() { warnAll(newWarningCollector("off")) })).To(Equal(""))
+		})
+
+		It("prints nothing without warnings", func() {
+			Expect(captureStderr(func() { newWarningCollector("summary").print() })).To(Equal(""))
+		})
+	})
+})
@@ -0,0 +1 @@
+../worktree.go
\ No newline at end of file
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("worktree", func() {
+	Describe("addWorktree", func() {
+		It("checks out the ref into a temporary worktree and removes it", func() {
+			inTempDir(func() {
+				gitCommand("init", "-q")
+				writeFile("foo.go", "package foo\n")
+				gitCommand("add", "foo.go")
+				gitCommand("commit", "-q", "-m", "base")
+				writeFile("foo.go", "package foo\n// changed\n")
+
+				tree, remove := addWorktree("HEAD", "base")
+				Expect(readFile(filepath.Join(tree.path, "foo.go"))).To(Equal("package foo\n"))
+				Expect(tree.environment).To(ContainElement("GOCACHE=" + worktreeBuildCache("base")))
+				Expect(strings.Count(commandOutput("git", "worktree", "list"), "\n")).To(Equal(2))
+
+				remove()
+				_, err := os.Stat(tree.path)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+				Expect(strings.Count(commandOutput("git", "worktree", "list"), "\n")).To(Equal(1))
+				Expect(readFile("foo.go")).To(Equal("package foo\n// changed\n"))
+			})
+		})
+	})
+
+	Describe("worktreeBuildCache", func() {
+		It("uses a build cache per slot", func() {
+			Expect(worktreeBuildCache("base")).To(HaveSuffix(filepath.Join("go-testcov", "worktrees", "base", "go-build")))
+			Expect(worktreeBuildCache("base")).ToNot(Equal(worktreeBuildCache("head")))
+		})
+	})
+})
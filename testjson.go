@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// package name of the events go-testcov adds to a `go test -json` stream, so consumers never confuse them with a real package
+var testEventPackage = "go-testcov"
+
+// event like `go test -json` prints them, see `go doc test2json`
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string  `json:",omitempty"`
+	Elapsed float64 `json:",omitempty"`
+	Output  string  `json:",omitempty"`
+}
+
+// events that report the coverage verdict as a "Coverage" test of the go-testcov package, appended to the `go test -json` output
+// so gotestsum and ci parsers show coverage failures like test failures, with the failing sections as test output
+func coverageTestEvents(result runResult, precision int) string {
+	action := "pass"
+	if result.exitCode != 0 {
+		action = "fail"
+	}
+	elapsed := 0.0
+	for _, phase := range result.phases {
+		elapsed += phase.Seconds
+	}
+
+	now := timeNow().UTC()
+	events := []testEvent{{Time: now, Action: "run", Package: testEventPackage, Test: "Coverage"}}
+	for _, finding := range result.findings {
+		if finding.Severity != "error" {
+			continue
+		}
+		output := finding.Message
+		if finding.Path != "" {
+			output = fmt.Sprintf("%v:%v: %v", finding.Path, finding.Line, finding.Message)
+		}
+		events = append(events, testEvent{Time: now, Action: "output", Package: testEventPackage, Test: "Coverage", Output: output + "\n"})
+	}
+	events = append(events,
+		testEvent{Time: now, Action: "output", Package: testEventPackage, Test: "Coverage", Output: summaryLine(result, precision) + "\n"},
+		testEvent{Time: now, Action: action, Package: testEventPackage, Test: "Coverage", Elapsed: elapsed},
+		testEvent{Time: now, Action: action, Package: testEventPackage, Elapsed: elapsed},
+	)
+
+	lines := []string{}
+	for _, event := range events {
+		content, err := json.Marshal(event)
+		check(err)
+		lines = append(lines, string(content))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// test injection point to make timings predictable
+var timeNow = time.Now
+
+// how long a phase of go-testcov took
+type timing struct {
+	Phase   string  `json:"phase"`
+	Seconds float64 `json:"seconds"`
+}
+
+// phases in the order they ran, so users can see when go-testcov itself is the bottleneck and not go test
+type timings []timing
+
+// record a phase that started at start and ends now
+func (t *timings) measure(phase string, start time.Time) {
+	*t = append(*t, timing{Phase: phase, Seconds: timeNow().Sub(start).Seconds()})
+}
+
+func printTimings(phases timings) {
+	parts := []string{}
+	for _, phase := range phases {
+		parts = append(parts, fmt.Sprintf("%v %.2fs", phase.Phase, phase.Seconds))
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "timings: %v\n", strings.Join(parts, ", "))
+}
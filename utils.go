@@ -1,13 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	pathpkg "path"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 )
 
@@ -23,15 +28,128 @@ func splitWithoutEmpty(string string, delimiter rune) []string {
 	return strings.FieldsFunc(string, func(c rune) bool { return c == delimiter })
 }
 
+// runs the commands go-testcov shells out to, tests inject fakes and --go-wrapper runs go inside of bazel or docker
+type commandRunner interface {
+	// run name with args in the directory and environment (current ones when empty) and return its exit code
+	run(directory string, environment []string, stdout io.Writer, stderr io.Writer, name string, args []string) (exitCode int)
+}
+
+// runs commands on this machine
+type execRunner struct{}
+
+func (execRunner) run(directory string, environment []string, stdout io.Writer, stderr io.Writer, name string, args []string) (exitCode int) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = directory
+	cmd.Env = environment
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return exitCodeOf(cmd, cmd.Run(), name, args)
+}
+
+// runs go commands through a wrapper like "docker run --rm -v $PWD:$PWD -w $PWD golang" by putting them after it
+type goWrapperRunner struct {
+	wrapper []string
+	runner  commandRunner
+}
+
+func (r goWrapperRunner) run(directory string, environment []string, stdout io.Writer, stderr io.Writer, name string, args []string) (exitCode int) {
+	if name == "go" {
+		args = append(append(append([]string{}, r.wrapper[1:]...), name), args...)
+		name = r.wrapper[0]
+	}
+	return r.runner.run(directory, environment, stdout, stderr, name, args)
+}
+
+// runner of all commands that stream or capture output, useOptions wraps it for one run like it configures the directive syntax
+var runner commandRunner = execRunner{}
+
 // Run a command and stream output to stdout/err, but return an exit code
 // https://stackoverflow.com/questions/10385551/get-exit-code-go
 func runCommand(name string, args ...string) (exitCode int) {
-	cmd := exec.Command(name, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	return runCommandIn("", nil, name, args...)
+}
+
+// Run a command in the given directory and environment, the current directory and environment when empty
+func runCommandIn(directory string, environment []string, name string, args ...string) (exitCode int) {
+	return runner.run(directory, environment, os.Stdout, os.Stderr, name, args)
+}
+
+// Run a command and return its combined stdout and stderr instead of streaming it
+func runCommandCapturingOutput(name string, args ...string) (exitCode int, output string) {
+	var buffer bytes.Buffer
+	exitCode = runner.run("", nil, &buffer, &buffer, name, args)
+	return exitCode, buffer.String()
+}
+
+// a command to run with runCommandsConcurrently, its output lines are prefixed to tell them apart
+type command struct {
+	prefix      string
+	directory   string
+	environment []string
+	name        string
+	args        []string
+}
 
-	err := cmd.Run()
+// Run commands at the same time, stream their output line by line with each command's prefix so lines never mix,
+// and return the exit code of each command
+func runCommandsConcurrently(commands []command) (exitCodes []int) {
+	var lock sync.Mutex // shared by all writers so only whole lines are written
+	exitCodes = make([]int, len(commands))
+	var wait sync.WaitGroup
+	for i, c := range commands {
+		wait.Add(1)
+		go func(i int, c command) {
+			defer wait.Done()
+			stdout := &prefixWriter{prefix: c.prefix, out: os.Stdout, lock: &lock}
+			stderr := &prefixWriter{prefix: c.prefix, out: os.Stderr, lock: &lock}
+			exitCodes[i] = runner.run(c.directory, c.environment, stdout, stderr, c.name, c.args)
+			stdout.flush()
+			stderr.flush()
+		}(i, c)
+	}
+	wait.Wait()
+	return exitCodes
+}
+
+// writer that prefixes each line and only writes complete lines, so concurrent commands do not interleave
+type prefixWriter struct {
+	prefix  string
+	out     io.Writer
+	lock    *sync.Mutex
+	pending []byte // incomplete last line
+}
+
+func (w *prefixWriter) Write(data []byte) (int, error) {
+	w.pending = append(w.pending, data...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end == -1 {
+			return len(data), nil
+		}
+		if err := w.writeLine(w.pending[:end+1]); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[end+1:]
+	}
+}
+
+// write what is left when the command did not end its output with a newline
+func (w *prefixWriter) flush() {
+	if len(w.pending) > 0 {
+		_ = w.writeLine(append(w.pending, '\n'))
+		w.pending = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	_, err := w.out.Write(append([]byte(w.prefix), line...))
+	return err
+}
 
+// exit code of a command that ran
+func exitCodeOf(cmd *exec.Cmd, err error, name string, args []string) (exitCode int) {
 	if err != nil {
 		// try to get the exit code
 		if exitError, ok := err.(*exec.ExitError); ok {
@@ -51,6 +169,27 @@ func runCommand(name string, args ...string) (exitCode int) {
 	return
 }
 
+// Run a command and return its stdout, stderr is streamed so users see why it failed
+func commandOutput(name string, args ...string) string {
+	var output bytes.Buffer
+	if exitCode := runner.run("", nil, &output, os.Stderr, name, args); exitCode != 0 {
+		check(fmt.Errorf("%v %v failed with exit code %v", name, strings.Join(args, " "), exitCode))
+	}
+	return output.String()
+}
+
+// Run a command and return its stdout, not ok when it failed, for lookups that may find nothing like a file at a revision
+func commandOutputIfSuccessful(name string, args ...string) (output string, ok bool) {
+	var buffer bytes.Buffer
+	exitCode := runner.run("", nil, &buffer, ioutil.Discard, name, args)
+	return buffer.String(), exitCode == 0
+}
+
+// Run a command without showing its output, for cleanup that is allowed to fail
+func runQuietly(name string, args ...string) error {
+	return exec.Command(name, args...).Run()
+}
+
 // read a file into a string
 func readFile(path string) (content string) {
 	data, err := ioutil.ReadFile(path)
@@ -76,6 +215,55 @@ func joinPath(parts ...string) string {
 	return strings.Join(parts, string(os.PathSeparator))
 }
 
+// expand a path relative to the working directory
+func absolutePath(path string, workingDirectory string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(workingDirectory, path)
+}
+
+// does the glob match the path or any of its trailing parts, so "pkg/*.go" matches "github.com/foo/bar/pkg/a.go",
+// "**" matches any number of directories, so "internal/legacy/**" matches "internal/legacy/a/b.go" and "**/*_gen.go" any "x_gen.go"
+func matchesPathGlob(path string, glob string) bool {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	globParts := strings.Split(glob, "/")
+	for i := range parts {
+		if matchesPathParts(parts[i:], globParts) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesPathParts(parts []string, globParts []string) bool {
+	if len(globParts) == 0 {
+		return len(parts) == 0
+	}
+	if globParts[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchesPathParts(parts[i:], globParts[1:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	matched, _ := pathpkg.Match(globParts[0], parts[0])
+	return matched && matchesPathParts(parts[1:], globParts[1:])
+}
+
+func matchesAnyPathGlob(path string, globs []string) bool {
+	for _, glob := range globs {
+		if matchesPathGlob(path, glob) {
+			return true
+		}
+	}
+	return false
+}
+
 func stringToInt(string string) int {
 	converted, err := strconv.Atoi(string)
 	check(err)
@@ -90,3 +278,13 @@ func containsString(s []string, e string) bool {
 	}
 	return false
 }
+
+func removeString(s []string, e string) (removed []string) {
+	removed = []string{}
+	for _, a := range s {
+		if a != e {
+			removed = append(removed, a)
+		}
+	}
+	return
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// version control system used to find what changed, so diff mode works for git and mercurial repos
+type versionControl interface {
+	// files changed since the given revision, relative to the current directory
+	changedFiles(revision string) []string
+
+	// revision ids of the revision and its ancestors, newest first
+	ancestors(revision string, limit int) []string
+
+	// content of a file relative to the current directory at the revision, not ok when it did not exist
+	fileAt(revision string, path string) (content string, ok bool)
+
+	// when a line of a file was last changed, lines that are not committed yet count as changed now,
+	// not found when the file is not in a repository
+	lineAddedAt(path string, line int) (added time.Time, found bool)
+}
+
+type git struct{}
+
+// new files that were not added yet are changed too, so they are checked before the first commit
+func (git) changedFiles(revision string) []string {
+	changed := splitWithoutEmpty(commandOutput("git", "diff", "--name-only", "--relative", revision), '\n')
+	return append(changed, splitWithoutEmpty(commandOutput("git", "ls-files", "--others", "--exclude-standard"), '\n')...)
+}
+
+func (git) ancestors(revision string, limit int) []string {
+	return splitWithoutEmpty(commandOutput("git", "rev-list", fmt.Sprintf("--max-count=%v", limit), revision), '\n')
+}
+
+func (git) fileAt(revision string, path string) (content string, ok bool) {
+	return commandOutputIfSuccessful("git", "show", revision+":./"+filepath.ToSlash(path))
+}
+
+func (git) lineAddedAt(path string, line int) (added time.Time, found bool) {
+	exitCode, output := runCommandCapturingOutput(
+		"git", "-C", filepath.Dir(path), "blame", "--porcelain", "-L", fmt.Sprintf("%v,%v", line, line), "--", filepath.Base(path),
+	)
+	if exitCode != 0 {
+		// files that are not tracked yet are new, files outside of repositories have no history
+		insideRepository, _ := runCommandCapturingOutput("git", "-C", filepath.Dir(path), "rev-parse", "--is-inside-work-tree")
+		return timeNow(), insideRepository == 0
+	}
+	for _, blameLine := range strings.Split(output, "\n") {
+		if strings.HasPrefix(blameLine, "author-time ") {
+			seconds, err := strconv.ParseInt(strings.TrimPrefix(blameLine, "author-time "), 10, 64)
+			check(err)
+			return time.Unix(seconds, 0), true
+		}
+	}
+	return // untested section
+}
+
+type mercurial struct{}
+
+// hg prints paths relative to the current directory when given a pattern,
+// new files that were not added yet are changed too, so they are checked before the first commit
+func (mercurial) changedFiles(revision string) []string {
+	return splitWithoutEmpty(commandOutput("hg", "status", "--no-status", "--modified", "--added", "--unknown", "--rev", revision, "."), '\n')
+}
+
+func (mercurial) ancestors(revision string, limit int) []string {
+	return splitWithoutEmpty(commandOutput(
+		"hg", "log", "--template", "{node}\\n", "--limit", fmt.Sprint(limit), "--rev", "reverse(ancestors("+revision+"))",
+	), '\n')
+}
+
+func (mercurial) fileAt(revision string, path string) (content string, ok bool) {
+	return commandOutputIfSuccessful("hg", "cat", "--rev", revision, path)
+}
+
+// hg annotates the working directory with the revision number 2147483647 for lines that are not committed yet
+const mercurialWorkingDirectoryRevision = "2147483647"
+
+func (mercurial) lineAddedAt(path string, line int) (added time.Time, found bool) {
+	exitCode, output := runCommandCapturingOutput(
+		"hg", "--cwd", filepath.Dir(path), "annotate", "--rev", "wdir()", "--template", "{lines % \"{rev} {date|hgdate}\\n\"}", "--", filepath.Base(path),
+	)
+	if exitCode != 0 {
+		// files that are not tracked yet are new, files outside of repositories have no history
+		insideRepository, _ := runCommandCapturingOutput("hg", "--cwd", filepath.Dir(path), "root")
+		return timeNow(), insideRepository == 0
+	}
+	lines := splitWithoutEmpty(output, '\n')
+	if line > len(lines) {
+		return
+	}
+	fields := strings.Fields(lines[line-1]) // revision, seconds and timezone offset
+	if len(fields) < 2 {
+		return
+	}
+	if fields[0] == mercurialWorkingDirectoryRevision {
+		return timeNow(), true
+	}
+	seconds, err := strconv.ParseInt(fields[1], 10, 64)
+	check(err)
+	return time.Unix(seconds, 0), true
+}
+
+var versionControls = map[string]versionControl{"git": git{}, "hg": mercurial{}}
+
+// marker directories that tell us which version control system a repo uses, in order of preference
+var versionControlMarkers = [][2]string{{".git", "git"}, {".hg", "hg"}}
+
+func versionControlNames() (names []string) {
+	for name := range versionControls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// use the configured version control or find it by looking for .git / .hg in the current directory or its parents
+func detectVersionControl(configured string) (found versionControl, ok bool) {
+	if configured != "" {
+		return versionControls[configured], true
+	}
+	dir, err := os.Getwd()
+	check(err)
+	for {
+		for _, marker := range versionControlMarkers {
+			if _, err := os.Stat(filepath.Join(dir, marker[0])); err == nil {
+				return versionControls[marker[1]], true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, false
+		}
+		dir = parent
+	}
+}
+
+// absolute paths of all files changed since the revision
+func changedFilesSince(revision string, vcs versionControl, workingDirectory string) (changed map[string]bool) {
+	changed = map[string]bool{}
+	for _, path := range vcs.changedFiles(revision) {
+		changed[filepath.Join(workingDirectory, path)] = true
+	}
+	return
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+var warningModes = []string{"summary", "full", "off"}
+
+// notices that do not fail the run, like budgets that could be decremented, with --warnings=summary they are collected
+// and printed once after the untested sections instead of between them, with full they are printed where they happen
+type warningCollector struct {
+	mode     string
+	warnings map[string][]string // messages by the path they are about
+}
+
+func newWarningCollector(mode string) *warningCollector {
+	return &warningCollector{mode: mode, warnings: map[string][]string{}}
+}
+
+func (c *warningCollector) warn(path string, message string) {
+	switch c.mode {
+	case "full":
+		_, _ = fmt.Fprint(os.Stderr, message)
+	case "off":
+	default:
+		c.warnings[path] = append(c.warnings[path], strings.Replace(strings.TrimSuffix(message, "\n"), "\n", " ", -1))
+	}
+}
+
+// one line per distinct warning grouped by path, repeated warnings are counted instead of printed again
+func (c *warningCollector) print() {
+	paths := []string{}
+	total := 0
+	for path, messages := range c.warnings {
+		paths = append(paths, path)
+		total += len(messages)
+	}
+	if total == 0 {
+		return
+	}
+	sort.Strings(paths)
+
+	_, _ = fmt.Fprintf(os.Stderr, "warnings (%v):\n", total)
+	for _, path := range paths {
+		counts := map[string]int{}
+		messages := []string{}
+		for _, message := range c.warnings[path] {
+			if counts[message] == 0 {
+				messages = append(messages, message)
+			}
+			counts[message]++
+		}
+		for _, message := range messages {
+			if counts[message] > 1 {
+				message = fmt.Sprintf("%v (%v times)", message, counts[message])
+			}
+			_, _ = fmt.Fprintln(os.Stderr, message)
+		}
+	}
+}
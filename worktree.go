@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// temporary git worktree, so comparison runs do not disturb the developers checkout
+type worktree struct {
+	path string
+
+	// environment for commands run in the worktree, with a build cache per slot so runs do not
+	// fill or lock the developers build cache but are still fast when comparing again
+	environment []string
+}
+
+// check out the ref into a temporary worktree, remove needs to be called when done
+// the worktree is also removed when the user interrupts, so no stale worktrees are left behind
+func addWorktree(ref string, slot string) (tree worktree, remove func()) {
+	tempDir, err := ioutil.TempDir("", "go-testcov-worktree")
+	check(err)
+
+	commandOutput("git", "worktree", "prune") // clean up after runs that were killed
+	tree.path = filepath.Join(tempDir, "worktree")
+	commandOutput("git", "worktree", "add", "--quiet", "--detach", tree.path, ref)
+	tree.environment = append(os.Environ(), "GOCACHE="+worktreeBuildCache(slot))
+
+	var once sync.Once
+	cleanup := func() {
+		once.Do(func() {
+			_ = runQuietly("git", "worktree", "remove", "--force", tree.path)
+			_ = os.RemoveAll(tempDir)
+		})
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	done := make(chan bool)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-interrupted:
+			cleanup()
+			exitFunction(130)
+		case <-done:
+		}
+	}()
+
+	return tree, func() {
+		signal.Stop(interrupted)
+		close(done)
+		cleanup()
+	}
+}
+
+// build cache for worktrees of a slot like "base" or "head", kept between runs
+func worktreeBuildCache(slot string) string {
+	return cacheDirectory("worktrees", slot, "go-build")
+}